@@ -0,0 +1,251 @@
+package algorithms
+
+import (
+	"encoding/binary"
+	"math"
+	model "recommender/models"
+	"sort"
+)
+
+type FrequentItemset struct {
+	Items []int
+	Count int
+}
+
+/*
+https://en.wikipedia.org/wiki/Apriori_algorithm
+Returns every itemset of up to $maxLength items that appears in at least $minSupport
+(fraction) of the transactions. Items of each returned itemset are sorted.
+*/
+func FrequentItemsets(transactions [][]int, minSupport float64, maxLength int) []FrequentItemset {
+	minCount := int(math.Ceil(minSupport * float64(len(transactions))))
+	if minCount < 1 {
+		minCount = 1
+	}
+	// Count single items and keep the frequent ones
+	itemCounts := make(map[int]int)
+	for _, transaction := range transactions {
+		for _, item := range transaction {
+			itemCounts[item]++
+		}
+	}
+	frequentItemsets := make([]FrequentItemset, 0)
+	currentLevel := make([]FrequentItemset, 0)
+	for item, count := range itemCounts {
+		if count >= minCount {
+			currentLevel = append(currentLevel, FrequentItemset{Items: []int{item}, Count: count})
+		}
+	}
+	sortItemsets(currentLevel)
+	// Infrequent items can't be part of any frequent itemset, so drop them from the transactions
+	prunedTransactions := make([][]int, 0, len(transactions))
+	for _, transaction := range transactions {
+		pruned := make([]int, 0, len(transaction))
+		for _, item := range transaction {
+			if itemCounts[item] >= minCount {
+				pruned = append(pruned, item)
+			}
+		}
+		sort.Ints(pruned)
+		if len(pruned) > 1 {
+			prunedTransactions = append(prunedTransactions, pruned)
+		}
+	}
+	for length := 2; len(currentLevel) > 0; length++ {
+		frequentItemsets = append(frequentItemsets, currentLevel...)
+		if maxLength > 0 && length > maxLength {
+			break
+		}
+		candidates := generateCandidates(currentLevel)
+		if len(candidates) == 0 {
+			break
+		}
+		// Count every candidate contained in each transaction. Short transactions enumerate their
+		// own subsets, while long ones check every candidate to avoid a combinatorial explosion.
+		candidateCounts := make(map[string]int, len(candidates))
+		for _, transaction := range prunedTransactions {
+			if len(transaction) < length {
+				continue
+			}
+			if combinationsWithin(len(transaction), length, len(candidates)) {
+				forEachCombination(transaction, length, func(subset []int) {
+					key := itemsetKey(subset)
+					if _, exists := candidates[key]; exists {
+						candidateCounts[key]++
+					}
+				})
+				continue
+			}
+			for key, candidate := range candidates {
+				if isSortedSubset(candidate, transaction) {
+					candidateCounts[key]++
+				}
+			}
+		}
+		currentLevel = make([]FrequentItemset, 0)
+		for key, count := range candidateCounts {
+			if count >= minCount {
+				currentLevel = append(currentLevel, FrequentItemset{Items: candidates[key], Count: count})
+			}
+		}
+		sortItemsets(currentLevel)
+	}
+	return frequentItemsets
+}
+
+/*
+Generates every rule X => y from $itemsets where X U {y} is a frequent itemset and
+confidence(X => y) >= $minConfidence. $itemsets must be downward closed, as returned
+by FrequentItemsets, so that the count of every antecedent is known.
+*/
+func AssociationRules(itemsets []FrequentItemset, numTransactions int, minConfidence float64) []model.AssociationRule {
+	counts := make(map[string]int, len(itemsets))
+	for _, itemset := range itemsets {
+		counts[itemsetKey(itemset.Items)] = itemset.Count
+	}
+	rules := make([]model.AssociationRule, 0)
+	if numTransactions == 0 {
+		return rules
+	}
+	for _, itemset := range itemsets {
+		if len(itemset.Items) < 2 {
+			continue
+		}
+		for idx, consequent := range itemset.Items {
+			antecedent := make([]int, 0, len(itemset.Items)-1)
+			antecedent = append(antecedent, itemset.Items[:idx]...)
+			antecedent = append(antecedent, itemset.Items[idx+1:]...)
+			antecedentCount, exists := counts[itemsetKey(antecedent)]
+			if !exists || antecedentCount == 0 {
+				continue
+			}
+			confidence := float64(itemset.Count) / float64(antecedentCount)
+			if confidence < minConfidence {
+				continue
+			}
+			consequentSupport := float64(counts[itemsetKey([]int{consequent})]) / float64(numTransactions)
+			rules = append(rules, model.AssociationRule{
+				Antecedent: antecedent,
+				Consequent: consequent,
+				Support:    float64(itemset.Count) / float64(numTransactions),
+				Confidence: confidence,
+				Lift:       confidence / consequentSupport,
+			})
+		}
+	}
+	return rules
+}
+
+// Joins frequent (k-1)-itemsets sharing their first k-2 items and keeps the candidates whose subsets are all frequent
+func generateCandidates(level []FrequentItemset) map[string][]int {
+	frequent := make(map[string]struct{}, len(level))
+	for _, itemset := range level {
+		frequent[itemsetKey(itemset.Items)] = struct{}{}
+	}
+	candidates := make(map[string][]int)
+	for i := 0; i < len(level); i++ {
+		for j := i + 1; j < len(level); j++ {
+			itemsA, itemsB := level[i].Items, level[j].Items
+			prefixLength := len(itemsA) - 1
+			if !equalItems(itemsA[:prefixLength], itemsB[:prefixLength]) {
+				// Level is sorted, so no later itemset shares the prefix of itemsA
+				break
+			}
+			candidate := make([]int, 0, len(itemsA)+1)
+			candidate = append(candidate, itemsA...)
+			candidate = append(candidate, itemsB[prefixLength])
+			if hasInfrequentSubset(candidate, frequent) {
+				continue
+			}
+			candidates[itemsetKey(candidate)] = candidate
+		}
+	}
+	return candidates
+}
+
+func hasInfrequentSubset(candidate []int, frequent map[string]struct{}) bool {
+	subset := make([]int, 0, len(candidate)-1)
+	for idx := range candidate {
+		subset = append(subset[:0], candidate[:idx]...)
+		subset = append(subset, candidate[idx+1:]...)
+		if _, exists := frequent[itemsetKey(subset)]; !exists {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if every item of the sorted $subset exists in the sorted $set
+func isSortedSubset(subset []int, set []int) bool {
+	idx := 0
+	for _, item := range set {
+		if idx == len(subset) {
+			break
+		}
+		if item == subset[idx] {
+			idx++
+		} else if item > subset[idx] {
+			return false
+		}
+	}
+	return idx == len(subset)
+}
+
+// Returns true if n choose k is at most $limit
+func combinationsWithin(n int, k int, limit int) bool {
+	combinations := 1
+	for i := 1; i <= k; i++ {
+		combinations = combinations * (n - k + i) / i
+		if combinations > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// Calls $visit with every sorted subset of $items of size $k. The subset slice is reused between calls.
+func forEachCombination(items []int, k int, visit func([]int)) {
+	subset := make([]int, k)
+	var combine func(start int, depth int)
+	combine = func(start int, depth int) {
+		if depth == k {
+			visit(subset)
+			return
+		}
+		for idx := start; idx <= len(items)-(k-depth); idx++ {
+			subset[depth] = items[idx]
+			combine(idx+1, depth+1)
+		}
+	}
+	combine(0, 0)
+}
+
+func sortItemsets(itemsets []FrequentItemset) {
+	sort.Slice(itemsets, func(i, j int) bool {
+		itemsA, itemsB := itemsets[i].Items, itemsets[j].Items
+		for idx := range itemsA {
+			if itemsA[idx] != itemsB[idx] {
+				return itemsA[idx] < itemsB[idx]
+			}
+		}
+		return false
+	})
+}
+
+func equalItems(itemsA []int, itemsB []int) bool {
+	for idx := range itemsA {
+		if itemsA[idx] != itemsB[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// Encodes $items into a compact map key
+func itemsetKey(items []int) string {
+	key := make([]byte, 0, 8*len(items))
+	for _, item := range items {
+		key = binary.LittleEndian.AppendUint64(key, uint64(item))
+	}
+	return string(key)
+}
@@ -0,0 +1,14 @@
+package algorithms
+
+/*
+https://en.wikipedia.org/wiki/Bayesian_average
+Weighted rating as used by IMDb: (v/(v+m))*R + (m/(v+m))*C where R is the mean rating
+of an item with v votes, C is the mean rating over all items and m is the weight of the prior.
+*/
+func BayesianAverage(mean float64, votes int, globalMean float64, prior float64) float64 {
+	if float64(votes)+prior == 0 {
+		return 0.0
+	}
+	v := float64(votes)
+	return (v/(v+prior))*mean + (prior/(v+prior))*globalMean
+}
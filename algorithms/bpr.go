@@ -0,0 +1,196 @@
+package algorithms
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+type BPRParams struct {
+	Factors        int
+	Epochs         int
+	LearningRate   float64
+	Regularization float64
+	Seed           int64
+}
+
+// Latent factors learned by Bayesian Personalized Ranking. Users and items are
+// mapped to dense indexes to keep the factor matrices compact.
+type BPRModel struct {
+	UserIndex   map[int]int
+	ItemIndex   map[int]int
+	ItemIDs     []int
+	UserFactors [][]float64
+	ItemFactors [][]float64
+	ItemBias    []float64
+}
+
+func DefaultBPRParams() BPRParams {
+	return BPRParams{Factors: 32, Epochs: 20, LearningRate: 0.05, Regularization: 0.0025, Seed: 42}
+}
+
+/*
+https://arxiv.org/abs/1205.2618
+Trains a BPR matrix factorization model on a map of {userID:[positive itemIDs]}.
+Each epoch draws as many (user, positive item, negative item) triples as there are
+positive interactions, where the negative item is sampled uniformly among the items
+the user has not interacted with.
+*/
+func TrainBPR(interactions map[int][]int, params BPRParams) *BPRModel {
+	model := &BPRModel{UserIndex: make(map[int]int), ItemIndex: make(map[int]int)}
+	// Sort user & item IDs so that the same input always yields the same model
+	userIDs := make([]int, 0, len(interactions))
+	for userID := range interactions {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+	for _, userID := range userIDs {
+		for _, itemID := range interactions[userID] {
+			if _, exists := model.ItemIndex[itemID]; !exists {
+				model.ItemIndex[itemID] = -1
+				model.ItemIDs = append(model.ItemIDs, itemID)
+			}
+		}
+	}
+	sort.Ints(model.ItemIDs)
+	for idx, itemID := range model.ItemIDs {
+		model.ItemIndex[itemID] = idx
+	}
+	// Flatten interactions into (user, item) pairs and per-user positive sets
+	pairs := make([][2]int, 0)
+	positives := make([]map[int]struct{}, len(userIDs))
+	for userIdx, userID := range userIDs {
+		model.UserIndex[userID] = userIdx
+		positives[userIdx] = make(map[int]struct{}, len(interactions[userID]))
+		for _, itemID := range interactions[userID] {
+			itemIdx := model.ItemIndex[itemID]
+			if _, exists := positives[userIdx][itemIdx]; exists {
+				continue
+			}
+			positives[userIdx][itemIdx] = struct{}{}
+			pairs = append(pairs, [2]int{userIdx, itemIdx})
+		}
+	}
+	rng := rand.New(rand.NewSource(params.Seed))
+	model.UserFactors = initFactors(len(userIDs), params.Factors, rng)
+	model.ItemFactors = initFactors(len(model.ItemIDs), params.Factors, rng)
+	model.ItemBias = make([]float64, len(model.ItemIDs))
+	numItems := len(model.ItemIDs)
+	if len(pairs) == 0 || numItems < 2 {
+		return model
+	}
+	lr, reg := params.LearningRate, params.Regularization
+	for epoch := 0; epoch < params.Epochs; epoch++ {
+		for sample := 0; sample < len(pairs); sample++ {
+			pair := pairs[rng.Intn(len(pairs))]
+			userIdx, posIdx := pair[0], pair[1]
+			// Users who interacted with every item have no negatives to sample from
+			if len(positives[userIdx]) == numItems {
+				continue
+			}
+			negIdx := rng.Intn(numItems)
+			for {
+				if _, isPositive := positives[userIdx][negIdx]; !isPositive {
+					break
+				}
+				negIdx = rng.Intn(numItems)
+			}
+			userVec, posVec, negVec := model.UserFactors[userIdx], model.ItemFactors[posIdx], model.ItemFactors[negIdx]
+			x := model.ItemBias[posIdx] - model.ItemBias[negIdx]
+			for f := range userVec {
+				x += userVec[f] * (posVec[f] - negVec[f])
+			}
+			// Gradient of ln(sigmoid(x)) with respect to x
+			sig := 1 / (1 + math.Exp(x))
+			for f := range userVec {
+				u, p, n := userVec[f], posVec[f], negVec[f]
+				userVec[f] += lr * (sig*(p-n) - reg*u)
+				posVec[f] += lr * (sig*u - reg*p)
+				negVec[f] += lr * (-sig*u - reg*n)
+			}
+			model.ItemBias[posIdx] += lr * (sig - reg*model.ItemBias[posIdx])
+			model.ItemBias[negIdx] += lr * (-sig - reg*model.ItemBias[negIdx])
+		}
+	}
+	return model
+}
+
+// Returns the preference score of userID for itemID and false if either is unknown to the model
+func (model *BPRModel) Score(userID int, itemID int) (float64, bool) {
+	userIdx, exists := model.UserIndex[userID]
+	if !exists {
+		return 0.0, false
+	}
+	return model.ScoreFactors(model.UserFactors[userIdx], itemID)
+}
+
+// Returns the preference score for itemID of the user with $userFactors and false if the item is unknown to the model
+func (model *BPRModel) ScoreFactors(userFactors []float64, itemID int) (float64, bool) {
+	itemIdx, exists := model.ItemIndex[itemID]
+	if !exists {
+		return 0.0, false
+	}
+	score := model.ItemBias[itemIdx]
+	for f, value := range userFactors {
+		score += value * model.ItemFactors[itemIdx][f]
+	}
+	return score, true
+}
+
+/*
+Learns the factors of a user outside the model, eg. an anonymous profile, from the sorted items they interacted with.
+Only the user factors are updated, the same way as in TrainBPR, while the item factors and biases stay fixed so that
+the model can be shared. Items unknown to the model are ignored.
+*/
+func (model *BPRModel) FoldIn(items []int, params BPRParams) []float64 {
+	rng := rand.New(rand.NewSource(params.Seed))
+	userVec := initFactors(1, params.Factors, rng)[0]
+	positives := make(map[int]struct{}, len(items))
+	posIdxs := make([]int, 0, len(items))
+	for _, itemID := range items {
+		itemIdx, exists := model.ItemIndex[itemID]
+		if _, isPositive := positives[itemIdx]; !exists || isPositive {
+			continue
+		}
+		positives[itemIdx] = struct{}{}
+		posIdxs = append(posIdxs, itemIdx)
+	}
+	numItems := len(model.ItemIDs)
+	// Users who interacted with every item have no negatives to sample from
+	if len(posIdxs) == 0 || len(posIdxs) == numItems {
+		return userVec
+	}
+	lr, reg := params.LearningRate, params.Regularization
+	// Every epoch draws as many samples as the user has positive interactions, like a trained user on average
+	for sample := 0; sample < params.Epochs*len(posIdxs); sample++ {
+		posIdx := posIdxs[rng.Intn(len(posIdxs))]
+		negIdx := rng.Intn(numItems)
+		for {
+			if _, isPositive := positives[negIdx]; !isPositive {
+				break
+			}
+			negIdx = rng.Intn(numItems)
+		}
+		posVec, negVec := model.ItemFactors[posIdx], model.ItemFactors[negIdx]
+		x := model.ItemBias[posIdx] - model.ItemBias[negIdx]
+		for f := range userVec {
+			x += userVec[f] * (posVec[f] - negVec[f])
+		}
+		sig := 1 / (1 + math.Exp(x))
+		for f := range userVec {
+			userVec[f] += lr * (sig*(posVec[f]-negVec[f]) - reg*userVec[f])
+		}
+	}
+	return userVec
+}
+
+func initFactors(rows int, factors int, rng *rand.Rand) [][]float64 {
+	matrix := make([][]float64, rows)
+	for i := range matrix {
+		matrix[i] = make([]float64, factors)
+		for f := range matrix[i] {
+			matrix[i][f] = rng.NormFloat64() * 0.1
+		}
+	}
+	return matrix
+}
@@ -0,0 +1,61 @@
+package algorithms
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+/*
+https://en.wikipedia.org/wiki/Locality-sensitive_hashing
+Banded LSH index over MinHash signatures. Each signature is split into $bands bands of $rows
+rows, and two sets become candidates when all rows of at least one band are equal. The
+probability of that is 1-(1-s^rows)^bands for sets of Jaccard similarity s.
+*/
+type LSHIndex struct {
+	Bands   int
+	Rows    int
+	buckets []map[uint64][]int
+}
+
+func NewLSHIndex(bands int, rows int) *LSHIndex {
+	index := &LSHIndex{Bands: bands, Rows: rows, buckets: make([]map[uint64][]int, bands)}
+	for band := range index.buckets {
+		index.buckets[band] = make(map[uint64][]int)
+	}
+	return index
+}
+
+// Adds $id to the bucket of each band of $signature. Not safe for concurrent use.
+func (index *LSHIndex) Add(id int, signature []uint64) {
+	for band := 0; band < index.Bands; band++ {
+		key := index.bandKey(signature, band)
+		index.buckets[band][key] = append(index.buckets[band][key], id)
+	}
+}
+
+// Returns the sorted IDs sharing at least one band bucket with $signature
+func (index *LSHIndex) Candidates(signature []uint64) []int {
+	seen := make(map[int]struct{})
+	for band := 0; band < index.Bands; band++ {
+		for _, id := range index.buckets[band][index.bandKey(signature, band)] {
+			seen[id] = struct{}{}
+		}
+	}
+	candidates := make([]int, 0, len(seen))
+	for id := range seen {
+		candidates = append(candidates, id)
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+func (index *LSHIndex) bandKey(signature []uint64, band int) uint64 {
+	hash := fnv.New64a()
+	buffer := make([]byte, 8)
+	for row := band * index.Rows; row < (band+1)*index.Rows && row < len(signature); row++ {
+		binary.LittleEndian.PutUint64(buffer, signature[row])
+		hash.Write(buffer)
+	}
+	return hash.Sum64()
+}
@@ -0,0 +1,97 @@
+package algorithms
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"math/rand"
+)
+
+// Mersenne prime 2^61-1 used as the modulus of the universal hash functions
+const mersennePrime61 = (1 << 61) - 1
+
+// Family of universal hash functions h(x) = (a*x + b) mod p used to build MinHash signatures
+type MinHasher struct {
+	coefficientsA []uint64
+	coefficientsB []uint64
+}
+
+func NewMinHasher(numHashes int, seed int64) *MinHasher {
+	rng := rand.New(rand.NewSource(seed))
+	hasher := &MinHasher{
+		coefficientsA: make([]uint64, numHashes),
+		coefficientsB: make([]uint64, numHashes),
+	}
+	for i := 0; i < numHashes; i++ {
+		hasher.coefficientsA[i] = uint64(rng.Int63n(mersennePrime61-1)) + 1
+		hasher.coefficientsB[i] = uint64(rng.Int63n(mersennePrime61))
+	}
+	return hasher
+}
+
+// Returns the length of the signatures produced by the hasher
+func (hasher *MinHasher) NumHashes() int {
+	return len(hasher.coefficientsA)
+}
+
+/*
+https://en.wikipedia.org/wiki/MinHash
+Returns the MinHash signature of a set, given the hashes of its elements (see HashInt, HashString).
+The fraction of equal positions between two signatures estimates the Jaccard similarity of the sets.
+*/
+func (hasher *MinHasher) Signature(elementHashes []uint64) []uint64 {
+	signature := make([]uint64, len(hasher.coefficientsA))
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+	for _, element := range elementHashes {
+		element %= mersennePrime61
+		for i := range signature {
+			if value := universalHash(hasher.coefficientsA[i], hasher.coefficientsB[i], element); value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+	return signature
+}
+
+// Estimates the Jaccard similarity of two sets from their MinHash signatures
+func EstimateJaccard(signature1 []uint64, signature2 []uint64) float64 {
+	if len(signature1) != len(signature2) || len(signature1) == 0 {
+		return 0.0
+	}
+	equal := 0
+	for i := range signature1 {
+		if signature1[i] == signature2[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(signature1))
+}
+
+// Hashes an int set element with the splitmix64 finalizer so that consecutive IDs spread evenly
+func HashInt(value int) uint64 {
+	x := uint64(value) + 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+func HashString(value string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	return hash.Sum64()
+}
+
+// Computes (a*x + b) mod 2^61-1 without overflowing
+func universalHash(a uint64, b uint64, x uint64) uint64 {
+	hi, lo := bits.Mul64(a, x)
+	// a*x = hi*2^64 + lo, and 2^61 = 1 (mod p)
+	value := (hi << 3) | (lo >> 61)
+	value += lo & mersennePrime61
+	value += b
+	for value >= mersennePrime61 {
+		value -= mersennePrime61
+	}
+	return value
+}
@@ -1,16 +1,9 @@
 package algorithms
 
-import (
-	"fmt"
-	"log"
-	"math"
-	"reflect"
-)
-
-var floatType = reflect.TypeOf(float64(0))
+import "math"
 
 // https://en.wikipedia.org/wiki/Pearson_correlation_coefficient#For_a_sample
-func PearsonSimilarity[T comparable](vector1 []T, vector2 []T) float64 {
+func PearsonSimilarity[T Number](vector1 []T, vector2 []T) float64 {
 	if len(vector1) != len(vector2) {
 		return 0.0
 	}
@@ -18,16 +11,7 @@ func PearsonSimilarity[T comparable](vector1 []T, vector2 []T) float64 {
 	sumX, sumY, sumXY, sumXsq, sumYsq := 0.0, 0.0, 0.0, 0.0, 0.0
 
 	for i := 0; i < len(vector1); i++ {
-		x, err := toFloat64(vector1[i])
-		if err != nil {
-			log.Fatalf("Error: %v", err)
-			return float64(math.NaN())
-		}
-		y, err := toFloat64(vector2[i])
-		if err != nil {
-			log.Fatalf("Error: %v", err)
-			return float64(math.NaN())
-		}
+		x, y := float64(vector1[i]), float64(vector2[i])
 		sumX += x
 		sumY += y
 		sumXY += x * y
@@ -45,24 +29,3 @@ func PearsonSimilarity[T comparable](vector1 []T, vector2 []T) float64 {
 
 	return numerator / denominator
 }
-
-func toFloat64(value interface{}) (float64, error) {
-	v := reflect.ValueOf(value)
-	v = reflect.Indirect(v)
-
-	switch v.Kind() {
-	case reflect.Float32, reflect.Float64:
-		return v.Float(), nil
-	case reflect.Bool:
-		if v.Bool() {
-			return 1.0, nil
-		}
-		return 0.0, nil
-	default:
-		if !v.Type().ConvertibleTo(floatType) {
-			return math.NaN(), fmt.Errorf("Cannot convert %v to float64", v.Type())
-		}
-		fv := v.Convert(floatType)
-		return fv.Float(), nil
-	}
-}
@@ -0,0 +1,67 @@
+package algorithms
+
+import (
+	"math"
+	model "recommender/models"
+	"slices"
+)
+
+// Max distance, as a fraction of a step, of a rating from its nearest step to be considered part of a scale
+const scaleTolerance = 1e-3
+
+// Differences of ratings below this fraction of their range are rounding errors of float32 ratings
+const differenceTolerance = 1e-5
+
+/*
+Returns the coarsest rating scale of at most 256 levels that contains every one of $ratings,
+with the greatest common divisor of their differences as its step, or false if there is none.
+*/
+func DetectRatingScale(ratings []float32) (model.RatingScale, bool) {
+	distinct := make([]float64, 0)
+	seen := make(map[float32]bool)
+	for _, rating := range ratings {
+		if !seen[rating] {
+			seen[rating] = true
+			distinct = append(distinct, float64(rating))
+		}
+	}
+	if len(distinct) == 0 {
+		return model.RatingScale{}, false
+	}
+	slices.Sort(distinct)
+	scale := model.RatingScale{Min: distinct[0], Step: 1, Levels: 1}
+	if len(distinct) == 1 {
+		return scale, true
+	}
+	tolerance := differenceTolerance * (distinct[len(distinct)-1] - distinct[0])
+	scale.Step = distinct[1] - distinct[0]
+	for _, rating := range distinct[2:] {
+		scale.Step = floatGCD(scale.Step, rating-scale.Min, tolerance)
+	}
+	// Spread the rounding errors of float32 ratings over the whole range, eg. 1.2000000477 / 12 instead of 0.0999999
+	ratingRange := distinct[len(distinct)-1] - scale.Min
+	scale.Levels = int(math.Round(ratingRange/scale.Step)) + 1
+	if scale.Levels > math.MaxUint8+1 {
+		return model.RatingScale{}, false
+	}
+	scale.Step = ratingRange / float64(scale.Levels-1)
+	for _, rating := range distinct {
+		steps := (rating - scale.Min) / scale.Step
+		if math.Abs(steps-math.Round(steps)) > scaleTolerance {
+			return model.RatingScale{}, false
+		}
+	}
+	return scale, true
+}
+
+// Greatest common divisor of two positive numbers with Euclid's algorithm, where remainders below $tolerance count as 0
+func floatGCD(a float64, b float64, tolerance float64) float64 {
+	for b > tolerance {
+		a, b = b, math.Mod(a, b)
+		// A remainder close to the divisor is a rounding error of an exact division
+		if a-b <= tolerance {
+			b = 0
+		}
+	}
+	return a
+}
@@ -0,0 +1,23 @@
+package algorithms
+
+import "math"
+
+/*
+https://dl.acm.org/doi/10.1145/3109859.3109873
+Probability of moving from a node with $degree edges to any of its neighbours in a
+bipartite graph, raised to $alpha as in P3alpha. An alpha of 1 yields a plain random walk.
+*/
+func TransitionProbability(degree int, alpha float64) float64 {
+	if degree == 0 {
+		return 0.0
+	}
+	return math.Pow(1/float64(degree), alpha)
+}
+
+// Divides $score by $degree^$beta to penalize popular items as in RP3beta
+func PopularityPenalty(score float64, degree int, beta float64) float64 {
+	if degree == 0 {
+		return 0.0
+	}
+	return score / math.Pow(float64(degree), beta)
+}
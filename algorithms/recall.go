@@ -0,0 +1,19 @@
+package algorithms
+
+// Returns the fraction of the $exact IDs that were also retrieved by an approximate search
+func Recall(approximate []int, exact []int) float64 {
+	if len(exact) == 0 {
+		return 1.0
+	}
+	retrieved := make(map[int]bool, len(approximate))
+	for _, id := range approximate {
+		retrieved[id] = true
+	}
+	hits := 0
+	for _, id := range exact {
+		if retrieved[id] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(exact))
+}
@@ -0,0 +1,57 @@
+package algorithms
+
+// Deviations of every item from a pivot item, along with the number of users who rated both
+type SlopeOneRow struct {
+	Deviations map[int]float64
+	Counts     map[int]int
+}
+
+/*
+https://en.wikipedia.org/wiki/Slope_One
+Computes dev(j, pivot) = avg(r_uj - r_u,pivot) for every item j, over all users u who
+rated both j and the pivot item. $pivotRatings holds the ratings of the pivot item keyed by
+userID and $userRatings returns the ratings of a user keyed by itemID.
+*/
+func SlopeOneDeviations(pivotItemID int, pivotRatings SparseVector[int, float32], userRatings func(int) SparseVector[int, float32]) SlopeOneRow {
+	row := SlopeOneRow{Deviations: make(map[int]float64), Counts: make(map[int]int)}
+	for p, userID := range pivotRatings.Keys {
+		pivotRating := float32(pivotRatings.Value(p))
+		ratings := userRatings(userID)
+		for i, itemID := range ratings.Keys {
+			if itemID == pivotItemID {
+				continue
+			}
+			row.Deviations[itemID] += float64(float32(ratings.Value(i)) - pivotRating)
+			row.Counts[itemID]++
+		}
+	}
+	for itemID, count := range row.Counts {
+		row.Deviations[itemID] /= float64(count)
+	}
+	return row
+}
+
+/*
+Predicts the rating of $itemID for a user with $ratings using the Weighted Slope One scheme.
+$rows must contain the SlopeOneRow of every item in $ratings. Returns false if no rated item
+shares a user with $itemID.
+*/
+func WeightedSlopeOne(ratings map[int]float32, rows map[int]SlopeOneRow, itemID int) (float64, bool) {
+	numerator, denominator := 0.0, 0
+	for ratedItemID, rating := range ratings {
+		row, exists := rows[ratedItemID]
+		if !exists {
+			continue
+		}
+		count := row.Counts[itemID]
+		if count == 0 {
+			continue
+		}
+		numerator += (float64(rating) + row.Deviations[itemID]) * float64(count)
+		denominator += count
+	}
+	if denominator == 0 {
+		return 0.0, false
+	}
+	return numerator / float64(denominator), true
+}
@@ -0,0 +1,318 @@
+package algorithms
+
+import (
+	"cmp"
+	"math"
+	model "recommender/models"
+	"slices"
+)
+
+// Numeric types that can be stored in vectors
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+/*
+Sparse vector whose Keys are sorted in ascending order, where Value(i) is the value of Keys[i].
+Vectors must be built with NewSparseVector, which also computes their sum and norm once, or with
+NewQuantizedVector, which stores ratings as uint8 steps of a scale instead of Values.
+*/
+type SparseVector[K cmp.Ordered, V Number] struct {
+	Keys   []K
+	Values []V
+	// Steps of the rating scale of quantized vectors, whose Values are nil
+	steps       []uint8
+	scale       model.RatingScale
+	sum         float64
+	squaredNorm float64
+}
+
+// Returns the sparse vector of a {key:value} map
+func NewSparseVector[K cmp.Ordered, V Number](values map[K]V) SparseVector[K, V] {
+	keys := make([]K, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	vector := SparseVector[K, V]{Keys: keys, Values: make([]V, len(keys))}
+	for i, key := range keys {
+		value := values[key]
+		vector.Values[i] = value
+		vector.sum += float64(value)
+		vector.squaredNorm += float64(value) * float64(value)
+	}
+	return vector
+}
+
+// Returns the sparse vector of $keys sorted in ascending order and their $values, which are used in place instead of being copied
+func NewSortedSparseVector[K cmp.Ordered, V Number](keys []K, values []V) SparseVector[K, V] {
+	vector := SparseVector[K, V]{Keys: keys, Values: values}
+	for _, value := range values {
+		vector.sum += float64(value)
+		vector.squaredNorm += float64(value) * float64(value)
+	}
+	return vector
+}
+
+/*
+Returns the sparse vector of $keys sorted in ascending order and their $values, which are used in place, with the
+$stats of the values computed beforehand, eg. once per row of a table whose vectors are viewed many times
+*/
+func NewSparseVectorWithStats[K cmp.Ordered, V Number](keys []K, values []V, stats VectorStats) SparseVector[K, V] {
+	return SparseVector[K, V]{Keys: keys, Values: values, sum: stats.Sum, squaredNorm: stats.SquaredNorm}
+}
+
+// Returns the sparse vector of {key:rating} $ratings, storing each rating as its step in $scale
+func NewQuantizedVector[K cmp.Ordered](ratings map[K]float32, scale model.RatingScale) SparseVector[K, float32] {
+	keys := make([]K, 0, len(ratings))
+	for key := range ratings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	vector := SparseVector[K, float32]{Keys: keys, steps: make([]uint8, len(keys)), scale: scale}
+	for i, key := range keys {
+		vector.steps[i] = scale.Encode(ratings[key])
+		value := float64(scale.Decode(vector.steps[i]))
+		vector.sum += value
+		vector.squaredNorm += value * value
+	}
+	return vector
+}
+
+// Returns the quantized vector of $keys sorted in ascending order and their $steps in $scale, which are used in place instead of being copied
+func NewSortedQuantizedVector[K cmp.Ordered](keys []K, steps []uint8, scale model.RatingScale) SparseVector[K, float32] {
+	vector := SparseVector[K, float32]{Keys: keys, steps: steps, scale: scale}
+	for _, step := range steps {
+		value := float64(scale.Decode(step))
+		vector.sum += value
+		vector.squaredNorm += value * value
+	}
+	return vector
+}
+
+// Same as NewSparseVectorWithStats for the $steps in $scale of a quantized vector
+func NewQuantizedVectorWithStats[K cmp.Ordered](keys []K, steps []uint8, scale model.RatingScale, stats VectorStats) SparseVector[K, float32] {
+	return SparseVector[K, float32]{Keys: keys, steps: steps, scale: scale, sum: stats.Sum, squaredNorm: stats.SquaredNorm}
+}
+
+// Sum and squared norm of the values of a sparse vector
+type VectorStats struct {
+	Sum         float64
+	SquaredNorm float64
+}
+
+// Returns the sum and squared norm of the values of the vector
+func (vector SparseVector[K, V]) Stats() VectorStats {
+	return VectorStats{Sum: vector.sum, SquaredNorm: vector.squaredNorm}
+}
+
+// Returns the value of Keys[i], decoded from its step for quantized vectors
+func (vector SparseVector[K, V]) Value(i int) float64 {
+	if vector.steps != nil {
+		return float64(vector.scale.Decode(vector.steps[i]))
+	}
+	return float64(vector.Values[i])
+}
+
+// Returns whether the vector stores its values as steps of a rating scale
+func (vector SparseVector[K, V]) Quantized() bool {
+	return vector.steps != nil
+}
+
+func (vector SparseVector[K, V]) Len() int {
+	return len(vector.Keys)
+}
+
+func (vector SparseVector[K, V]) Sum() float64 {
+	return vector.sum
+}
+
+// Returns the mean of the stored values
+func (vector SparseVector[K, V]) Mean() float64 {
+	if len(vector.Keys) == 0 {
+		return 0.0
+	}
+	return vector.sum / float64(len(vector.Keys))
+}
+
+// Returns the squared Euclidean norm of the vector
+func (vector SparseVector[K, V]) SquaredNorm() float64 {
+	return vector.squaredNorm
+}
+
+// Returns the Euclidean norm of the vector
+func (vector SparseVector[K, V]) Norm() float64 {
+	return math.Sqrt(vector.squaredNorm)
+}
+
+// Returns whether two sorted sets have at least one common element, stopping at the first one
+func HasIntersection[K cmp.Ordered](set1 []K, set2 []K) bool {
+	i, j := 0, 0
+	for i < len(set1) && j < len(set2) {
+		switch {
+		case set1[i] < set2[j]:
+			i++
+		case set1[i] > set2[j]:
+			j++
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the number of common elements of two sorted sets with a merge-join
+func IntersectionSize[K cmp.Ordered](set1 []K, set2 []K) int {
+	size, i, j := 0, 0, 0
+	for i < len(set1) && j < len(set2) {
+		switch {
+		case set1[i] < set2[j]:
+			i++
+		case set1[i] > set2[j]:
+			j++
+		default:
+			size++
+			i++
+			j++
+		}
+	}
+	return size
+}
+
+// Returns the number of distinct elements of two sorted sets
+func UnionSize[K cmp.Ordered](set1 []K, set2 []K) int {
+	return len(set1) + len(set2) - IntersectionSize(set1, set2)
+}
+
+// Jaccard similarity of two sorted sets, without allocating
+func JaccardSimilaritySorted[K cmp.Ordered](set1 []K, set2 []K) float64 {
+	return JaccardSimilarityCounts(IntersectionSize(set1, set2), len(set1), len(set2))
+}
+
+// Dice similarity of two sorted sets, without allocating
+func DiceSimilaritySorted[K cmp.Ordered](set1 []K, set2 []K) float64 {
+	return DiceSimilarityCounts(IntersectionSize(set1, set2), len(set1), len(set2))
+}
+
+// Jaccard similarity of two sets of $size1 and $size2 elements with $intersection common elements
+func JaccardSimilarityCounts(intersection int, size1 int, size2 int) float64 {
+	union := size1 + size2 - intersection
+	if union == 0 {
+		return 0.0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// Dice similarity of two sets of $size1 and $size2 elements with $intersection common elements
+func DiceSimilarityCounts(intersection int, size1 int, size2 int) float64 {
+	if size1+size2 == 0 {
+		return 0.0
+	}
+	return float64(2*intersection) / float64(size1+size2)
+}
+
+/*
+Number of elements of the multiset $vector2, whose values are the occurrences of its keys, that are also in $vector1.
+Like Intersection, every occurrence of a common element in the second multiset is counted.
+*/
+func MultisetIntersectionSize[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) float64 {
+	size, i, j := 0.0, 0, 0
+	for i < len(vector1.Keys) && j < len(vector2.Keys) {
+		switch {
+		case vector1.Keys[i] < vector2.Keys[j]:
+			i++
+		case vector1.Keys[i] > vector2.Keys[j]:
+			j++
+		default:
+			size += vector2.Value(j)
+			i++
+			j++
+		}
+	}
+	return size
+}
+
+// Jaccard similarity of two multisets of occurrences, the same as JaccardSimilarity over the lists of their elements
+func MultisetJaccardSimilarity[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) float64 {
+	intersection := MultisetIntersectionSize(vector1, vector2)
+	union := vector1.Sum() + vector2.Sum() - intersection
+	if union == 0 {
+		return 0.0
+	}
+	return intersection / union
+}
+
+// Dice similarity of two multisets of occurrences, the same as DiceSimilarity over the lists of their elements
+func MultisetDiceSimilarity[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) float64 {
+	if vector1.Sum()+vector2.Sum() == 0 {
+		return 0.0
+	}
+	return 2 * MultisetIntersectionSize(vector1, vector2) / (vector1.Sum() + vector2.Sum())
+}
+
+// Returns the dot product of two sparse vectors
+func DotProduct[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) float64 {
+	dotProduct, _, _ := overlap(vector1, vector2)
+	return dotProduct
+}
+
+/*
+Cosine similarity of two sparse vectors over the keys of $vector1, ie. keys only present
+in $vector2 are ignored. This is the same as CosineSimilarity over the aligned vectors
+built by util.GetMovieRatingVectors and its siblings, but without allocating them.
+*/
+func CosineSimilaritySparse[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) float64 {
+	dotProduct, _, squaredNorm2 := overlap(vector1, vector2)
+	return CosineSimilarityOverlap(dotProduct, vector1.SquaredNorm(), squaredNorm2)
+}
+
+// Cosine similarity from the $dotProduct of two vectors and their squared norms
+func CosineSimilarityOverlap(dotProduct float64, squaredNorm1 float64, squaredNorm2 float64) float64 {
+	if squaredNorm1 == 0 || squaredNorm2 == 0 {
+		return 0.0
+	}
+	return dotProduct / (math.Sqrt(squaredNorm1) * math.Sqrt(squaredNorm2))
+}
+
+/*
+Pearson correlation of two sparse vectors over the keys of $vector1, where missing values
+of $vector2 count as 0. This is the same as PearsonSimilarity over the aligned vectors
+built by util.GetMovieRatingVectors and its siblings, but without allocating them.
+*/
+func PearsonSimilaritySparse[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) float64 {
+	sumXY, sumY, sumYsq := overlap(vector1, vector2)
+	return PearsonSimilarityOverlap(float64(vector1.Len()), vector1.Sum(), vector1.SquaredNorm(), sumXY, sumY, sumYsq)
+}
+
+// Pearson correlation from the sums of $n pairs of values (x, y), ie. of x, x², xy, y and y²
+func PearsonSimilarityOverlap(n float64, sumX float64, sumXsq float64, sumXY float64, sumY float64, sumYsq float64) float64 {
+	denominator := math.Sqrt((n*sumXsq - sumX*sumX) * (n*sumYsq - sumY*sumY))
+	if denominator == 0.0 {
+		return 0.0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// Returns the dot product of two sparse vectors and the sum and squared sum of $vector2 over their common keys
+func overlap[K cmp.Ordered, V Number](vector1 SparseVector[K, V], vector2 SparseVector[K, V]) (float64, float64, float64) {
+	dotProduct, sum2, squaredSum2 := 0.0, 0.0, 0.0
+	i, j := 0, 0
+	for i < len(vector1.Keys) && j < len(vector2.Keys) {
+		switch {
+		case vector1.Keys[i] < vector2.Keys[j]:
+			i++
+		case vector1.Keys[i] > vector2.Keys[j]:
+			j++
+		default:
+			value1, value2 := vector1.Value(i), vector2.Value(j)
+			dotProduct += value1 * value2
+			sum2 += value2
+			squaredSum2 += value2 * value2
+			i++
+			j++
+		}
+	}
+	return dotProduct, sum2, squaredSum2
+}
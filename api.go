@@ -0,0 +1,334 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"recommender/config"
+	"strings"
+	"time"
+)
+
+// Hand-maintained OpenAPI 3 document of the Web-Server endpoints
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Max size of a request body
+const maxBodySize = 1 << 20
+
+// Types of the scores of the recommendations, depending on the algorithm
+const (
+	PredictedRating = "predictedRating"
+	Similarity      = "similarity"
+	Probability     = "probability"
+	Preference      = "preference"
+	RatingCount     = "ratingCount"
+	Confidence      = "confidence"
+)
+
+/*
+Body of POST /api/v1/recommendations. Optional fields default to the CLI defaults.
+  - Metric: similarity metric of the algorithms that use one (the -s flag)
+  - K: number of neighbors of user and item (up to 10000)
+  - N: number of recommendations (up to 10000)
+  - Filters: restrictions of the dataset and the interactions the recommendations are based on
+  - Seeds, NegativeSeeds, SeedWeights, Aggregation: movies (and their {movieId:weight}) that the results of tag, title and hybrid must be similar or dissimilar to
+  - Profile: ratings of an anonymous user to recommend movies to instead of the Input user, never added to the dataset
+*/
+type RecommendationRequest struct {
+	Algorithm     string          `json:"algorithm"`
+	Metric        string          `json:"metric"`
+	K             int             `json:"k"`
+	N             int             `json:"n"`
+	Input         int             `json:"input"`
+	InputType     string          `json:"inputType"`
+	Seeds         []int           `json:"seeds"`
+	NegativeSeeds []int           `json:"negativeSeeds"`
+	SeedWeights   map[int]float64 `json:"seedWeights"`
+	Aggregation   string          `json:"aggregation"`
+	Profile       []ProfileRating `json:"profile"`
+	Filters       RequestFilters  `json:"filters"`
+	Prior         float64         `json:"prior"`
+	Window        int             `json:"window"`
+	Alpha         float64         `json:"alpha"`
+	Beta          float64         `json:"beta"`
+	MinSupport    float64         `json:"minSupport"`
+	MinConfidence float64         `json:"minConfidence"`
+	Approximate   bool            `json:"approximate"`
+	Bands         int             `json:"bands"`
+	Rows          int             `json:"rows"`
+}
+
+/*
+Restrictions of the data a recommendation is based on and of the recommended movies:
+  - MaxRecords: only use the records with the lowest IDs of the dataset of the algorithm (-1 for all of them)
+  - Implicit: treat every rating >= Threshold (any rating if Threshold <= 0) as a positive interaction
+  - The other fields are the result filters of config.ResultFilters
+*/
+type RequestFilters struct {
+	MaxRecords    int      `json:"maxRecords"`
+	Implicit      bool     `json:"implicit"`
+	Threshold     float64  `json:"threshold"`
+	MinRatings    int      `json:"minRatings"`
+	Allow         []int    `json:"allow"`
+	Deny          []int    `json:"deny"`
+	MinYear       int      `json:"minYear"`
+	MaxYear       int      `json:"maxYear"`
+	IncludeTags   []string `json:"includeTags"`
+	ExcludeTags   []string `json:"excludeTags"`
+	IncludeGenres []string `json:"includeGenres"`
+	ExcludeGenres []string `json:"excludeGenres"`
+}
+
+type ProfileRating struct {
+	MovieID int     `json:"movieId"`
+	Rating  float32 `json:"rating"`
+}
+
+type RecommendationsResponse struct {
+	Algorithm string `json:"algorithm"`
+	// Non-personalized algorithm used instead of the requested one, when its input wasn't found
+	Fallback  string                 `json:"fallback,omitempty"`
+	ScoreType string                 `json:"scoreType"`
+	Results   []RecommendationResult `json:"results"`
+}
+
+type RecommendationResult struct {
+	MovieID int     `json:"movieId"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	// Association rule of the recommendation, only set by the assoc algorithm
+	Antecedent []int   `json:"antecedent,omitempty"`
+	Support    float64 `json:"support,omitempty"`
+	Lift       float64 `json:"lift,omitempty"`
+}
+
+// Names of the body fields of the configuration fields reported by config.Validate, where they differ
+var requestFields = map[string]string{
+	"recommendations": "n",
+	"similarity":      "metric",
+	"maxRecords":      "filters.maxRecords",
+	"implicit":        "filters.implicit",
+	"threshold":       "filters.threshold",
+	"minRatings":      "filters.minRatings",
+	"allow":           "filters.allow",
+	"minYear":         "filters.minYear",
+	"maxYear":         "filters.maxYear",
+}
+
+// Registers the handlers of the versioned API
+func registerAPI() {
+	http.HandleFunc("/api/v1/recommendations", handleRecommendations)
+	http.HandleFunc("/api/v1/openapi.json", handleOpenAPI)
+	http.HandleFunc("/api/v1/movies/", handleMovies)
+	http.HandleFunc("/api/v1/users/", handleUsers)
+}
+
+// Handler of POST /api/v1/recommendations
+func handleRecommendations(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now()
+	defer recoverRequest(w)
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	request := newRecommendationRequest()
+	if fieldErr := decodeBody(w, r, &request); fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	cfg := request.Config()
+	if fieldErrors := cfg.Validate(); len(fieldErrors) > 0 {
+		for i, err := range fieldErrors {
+			if field, exists := requestFields[err.Field]; exists {
+				fieldErrors[i].Field = field
+			}
+		}
+		writeErrors(w, validationStatus(fieldErrors), fieldErrors...)
+		return
+	}
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	results := serveRecommendation(cfg)
+	setStaleDataHeader(w, &results.cfg)
+	response := RecommendationsResponse{
+		Algorithm: results.cfg.Algorithm,
+		Fallback:  results.fallback,
+		ScoreType: scoreType(results),
+		Results:   make([]RecommendationResult, 0),
+	}
+	for _, rule := range results.rules {
+		response.Results = append(response.Results, RecommendationResult{
+			MovieID:    rule.Consequent,
+			Title:      results.data.MovieTitles.Title(rule.Consequent),
+			Score:      rule.Confidence,
+			Antecedent: rule.Antecedent,
+			Support:    rule.Support,
+			Lift:       rule.Lift,
+		})
+	}
+	for _, movieRating := range results.ratingForecasts {
+		response.Results = append(response.Results, RecommendationResult{
+			MovieID: movieRating.MovieID,
+			Title:   results.data.MovieTitles.Title(movieRating.MovieID),
+			Score:   float64(movieRating.Rating),
+		})
+	}
+	for _, relevantMovie := range results.relevantMovies {
+		response.Results = append(response.Results, RecommendationResult{
+			MovieID: relevantMovie.MovieID,
+			Title:   results.data.MovieTitles.Title(relevantMovie.MovieID),
+			Score:   relevantMovie.Similarity,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+	fmt.Printf("Reponse sent in: %s\n", time.Since(startTime))
+}
+
+// Handler of GET /api/v1/openapi.json
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// Returns a request with the defaults of the CLI flags
+func newRecommendationRequest() RecommendationRequest {
+	return RecommendationRequest{
+		K:           k,
+		InputType:   "user",
+		Aggregation: "mean",
+		Filters:     RequestFilters{MaxRecords: -1},
+		Prior:       10.0,
+		Window:      30,
+		Alpha:       1.0,
+		Beta:        0.5,
+		Bands:       defaultBands,
+		Rows:        defaultRows,
+	}
+}
+
+// Returns the configuration of the request, to be validated before use
+func (request *RecommendationRequest) Config() config.Config {
+	cfg := config.Config{
+		Recommendations: request.N,
+		Similarity:      request.Metric,
+		Algorithm:       request.Algorithm,
+		Input:           request.Input,
+		MaxRecords:      request.Filters.MaxRecords,
+		K:               request.K,
+		NumThreads:      numThreads,
+		Implicit:        request.Filters.Implicit,
+		Threshold:       request.Filters.Threshold,
+		Prior:           request.Prior,
+		Window:          request.Window,
+		InputType:       request.InputType,
+		Alpha:           request.Alpha,
+		Beta:            request.Beta,
+		Seeds:           request.Seeds,
+		NegativeSeeds:   request.NegativeSeeds,
+		SeedWeights:     request.SeedWeights,
+		Aggregation:     request.Aggregation,
+		MinSupport:      request.MinSupport,
+		MinConfidence:   request.MinConfidence,
+		Approximate:     request.Approximate,
+		Bands:           request.Bands,
+		Rows:            request.Rows,
+		Filters: config.ResultFilters{
+			MinRatings:    request.Filters.MinRatings,
+			Allow:         request.Filters.Allow,
+			Deny:          request.Filters.Deny,
+			MinYear:       request.Filters.MinYear,
+			MaxYear:       request.Filters.MaxYear,
+			IncludeTags:   request.Filters.IncludeTags,
+			ExcludeTags:   request.Filters.ExcludeTags,
+			IncludeGenres: request.Filters.IncludeGenres,
+			ExcludeGenres: request.Filters.ExcludeGenres,
+		},
+	}
+	if request.Profile != nil {
+		// Later ratings of the same movie replace the earlier ones, like in a profile file
+		cfg.Profile = make(map[int]float32, len(request.Profile))
+		for _, rating := range request.Profile {
+			cfg.Profile[rating.MovieID] = rating.Rating
+		}
+	}
+	cfg.DefaultSeeds()
+	return cfg
+}
+
+// Returns the type of the scores of $results
+func scoreType(results recommendationResults) string {
+	if len(results.rules) != 0 {
+		return Confidence
+	}
+	if len(results.relevantMovies) != 0 {
+		return Similarity
+	}
+	switch results.cfg.Algorithm {
+	case "p3alpha", "rp3beta":
+		return Probability
+	case "bpr":
+		return Preference
+	case "popular", "trending":
+		return RatingCount
+	case "assoc":
+		return Confidence
+	case "tag", "title", "hybrid":
+		return Similarity
+	}
+	if results.cfg.Implicit && results.cfg.Algorithm == "item" {
+		// Implicit item-item forecasts are average similarities to the movies the user interacted with
+		return Similarity
+	}
+	return PredictedRating
+}
+
+// Sends a 405 error response and returns false if the method of $r is not one of $methods
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeErrors(w, http.StatusMethodNotAllowed, config.FieldError{
+		Code: "method_not_allowed", Message: fmt.Sprintf("Method %s is not allowed, use %s", r.Method, strings.Join(methods, " or ")),
+	})
+	return false
+}
+
+// Decodes the JSON body of $r into $value, rejecting unknown fields. Returns the error of a malformed body
+func decodeBody(w http.ResponseWriter, r *http.Request, value interface{}) *config.FieldError {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(value)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON object")
+	}
+	if err == nil {
+		return nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &config.FieldError{
+			Code: config.InvalidParameter, Field: typeErr.Field, Message: fmt.Sprintf("'%s' must be of type %s", typeErr.Field, typeErr.Type),
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = errors.New("request body is empty")
+	}
+	return &config.FieldError{Code: "invalid_body", Message: fmt.Sprintf("Invalid JSON body: %v", err)}
+}
+
+// Sends $value as a JSON response with the $status HTTP status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
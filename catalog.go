@@ -0,0 +1,288 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"recommender/config"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Number of most frequent tags of a movie returned with its details
+const topMovieTags = 10
+
+// Default and max number of ratings per page of a user's ratings
+const (
+	defaultPageSize = 20
+	maxPageSize     = 500
+)
+
+// Fields the ratings of a user can be sorted by
+var ratingSortFields = []string{"movieId", "title", "rating", "time"}
+
+type MovieResponse struct {
+	MovieID     int            `json:"movieId"`
+	Title       string         `json:"title"`
+	Genres      []string       `json:"genres"`
+	RatingCount int            `json:"ratingCount"`
+	MeanRating  float64        `json:"meanRating"`
+	Histogram   []RatingBucket `json:"histogram"`
+	TopTags     []TagCount     `json:"topTags"`
+}
+
+// Number of ratings of a movie with the same value
+type RatingBucket struct {
+	Rating float32 `json:"rating"`
+	Count  int     `json:"count"`
+}
+
+// Number of times a tag was given to a movie
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type MovieTagsResponse struct {
+	MovieID int        `json:"movieId"`
+	Tags    []TagCount `json:"tags"`
+}
+
+type UserRatingsResponse struct {
+	UserID   int          `json:"userId"`
+	Total    int          `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"pageSize"`
+	Ratings  []UserRating `json:"ratings"`
+}
+
+type UserRating struct {
+	MovieID int     `json:"movieId"`
+	Title   string  `json:"title"`
+	Rating  float32 `json:"rating"`
+	// Unix timestamp of the rating, if the dataset has one
+	Time int64 `json:"time,omitempty"`
+}
+
+/*
+Handler of the movie endpoints:
+  - GET /api/v1/movies/{id}: title, genres, rating statistics and top tags of a movie
+  - GET /api/v1/movies/{id}/tags: every tag of a movie with its number of occurrences
+  - POST /api/v1/movies/{id}/tags: see handleTagMutation
+  - GET /api/v1/movies/{id}/similarity/{otherId}: see handlePairRequest
+*/
+func handleMovies(w http.ResponseWriter, r *http.Request) {
+	defer recoverRequest(w)
+	segments := pathSegments(r, "/api/v1/movies/")
+	if len(segments) == 3 && segments[1] == "similarity" {
+		handlePairRequest(w, r, "movie", segments)
+		return
+	}
+	if len(segments) == 0 || len(segments) > 2 || (len(segments) == 2 && segments[1] != "tags") {
+		handleNotFound(w, r)
+		return
+	}
+	methods := []string{http.MethodGet}
+	if len(segments) == 2 {
+		methods = append(methods, http.MethodPost)
+	}
+	if !allowMethods(w, r, methods...) {
+		return
+	}
+	movieID, fieldErr := parsePathID(segments[0], "id")
+	if fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	if r.Method == http.MethodPost {
+		handleTagMutation(w, r, movieID)
+		return
+	}
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	movieTitle, titleExists := data.MovieTitles.Get(movieID)
+	ratings := data.Movies.Vector(movieID)
+	if !titleExists && !data.Movies.Has(movieID) {
+		writeErrors(w, http.StatusNotFound, config.FieldError{Code: "not_found", Field: "id", Message: fmt.Sprintf("Movie %d was not found", movieID)})
+		return
+	}
+	tagCounts := countMovieTags(movieID)
+	if len(segments) == 2 {
+		writeJSON(w, http.StatusOK, MovieTagsResponse{MovieID: movieID, Tags: tagCounts})
+		return
+	}
+	response := MovieResponse{
+		MovieID:     movieID,
+		Title:       movieTitle.Title,
+		Genres:      movieTitle.Genres,
+		RatingCount: ratings.Len(),
+		Histogram:   make([]RatingBucket, 0),
+		TopTags:     tagCounts[:min(topMovieTags, len(tagCounts))],
+	}
+	if response.Genres == nil {
+		response.Genres = []string{}
+	}
+	counts := make(map[float32]int)
+	for i := range ratings.Keys {
+		counts[float32(ratings.Value(i))]++
+	}
+	response.MeanRating = ratings.Mean()
+	for rating, count := range counts {
+		response.Histogram = append(response.Histogram, RatingBucket{Rating: rating, Count: count})
+	}
+	slices.SortFunc(response.Histogram, func(a, b RatingBucket) int { return cmp.Compare(a.Rating, b.Rating) })
+	writeJSON(w, http.StatusOK, response)
+}
+
+/*
+Handler of the user endpoints:
+  - GET /api/v1/users/{id}/ratings: a page of the ratings of a user, see listUserRatings
+  - PUT, DELETE /api/v1/users/{id}/ratings/{movieId}: see handleRatingMutation
+  - GET /api/v1/users/{id}/predictions/{movieId}, GET /api/v1/users/{id}/similarity/{otherId}: see handlePairRequest
+*/
+func handleUsers(w http.ResponseWriter, r *http.Request) {
+	defer recoverRequest(w)
+	segments := pathSegments(r, "/api/v1/users/")
+	if len(segments) == 3 && (segments[1] == "predictions" || segments[1] == "similarity") {
+		handlePairRequest(w, r, "user", segments)
+		return
+	}
+	if len(segments) < 2 || len(segments) > 3 || segments[1] != "ratings" {
+		handleNotFound(w, r)
+		return
+	}
+	methods := []string{http.MethodGet}
+	if len(segments) == 3 {
+		methods = []string{http.MethodPut, http.MethodDelete}
+	}
+	if !allowMethods(w, r, methods...) {
+		return
+	}
+	userID, fieldErr := parsePathID(segments[0], "id")
+	if fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	if len(segments) == 2 {
+		listUserRatings(w, r, userID)
+		return
+	}
+	movieID, fieldErr := parsePathID(segments[2], "movieId")
+	if fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	handleRatingMutation(w, r, userID, movieID)
+}
+
+/*
+Sends a page of the ratings of $userID. Query parameters:
+  - page, pageSize: 1-based page number and number of ratings per page (20 by default, up to 500)
+  - sort, order: one of movieId (default), title, rating or time, in asc (default) or desc order
+*/
+func listUserRatings(w http.ResponseWriter, r *http.Request, userID int) {
+	parser := queryParser{values: r.URL.Query()}
+	page, pageSize := parser.Int("page", 1), parser.Int("pageSize", defaultPageSize)
+	sortField, order := parser.String("sort", "movieId"), parser.String("order", "asc")
+	if len(parser.errors) > 0 {
+		writeErrors(w, http.StatusBadRequest, parser.errors...)
+		return
+	}
+	fieldErrors := make([]config.FieldError, 0)
+	if page < 1 {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.InvalidValue, Field: "page", Message: "Page must be greater than 0"})
+	}
+	if pageSize < 1 || pageSize > maxPageSize {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.InvalidValue, Field: "pageSize", Message: fmt.Sprintf("Page size must be between 1 and %d", maxPageSize)})
+	}
+	if !slices.Contains(ratingSortFields, sortField) {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.InvalidValue, Field: "sort", Message: "Allowed sort fields: 'movieId', 'title', 'rating', 'time'"})
+	}
+	if order != "asc" && order != "desc" {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.InvalidValue, Field: "order", Message: "Allowed orders: 'asc', 'desc'"})
+	}
+	if len(fieldErrors) > 0 {
+		writeErrors(w, http.StatusUnprocessableEntity, fieldErrors...)
+		return
+	}
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	if !data.Users.Has(userID) {
+		writeErrors(w, http.StatusNotFound, config.FieldError{Code: "not_found", Field: "id", Message: fmt.Sprintf("User %d was not found", userID)})
+		return
+	}
+	userRatings := data.Users.Vector(userID)
+	ratings := make([]UserRating, 0, userRatings.Len())
+	for i, movieID := range userRatings.Keys {
+		ratings = append(ratings, UserRating{
+			MovieID: movieID,
+			Title:   data.MovieTitles.Title(movieID),
+			Rating:  float32(userRatings.Value(i)),
+			Time:    data.Movies.Time(movieID, userID),
+		})
+	}
+	slices.SortFunc(ratings, func(a, b UserRating) int {
+		var comparison int
+		switch sortField {
+		case "movieId":
+			comparison = cmp.Compare(a.MovieID, b.MovieID)
+		case "title":
+			comparison = strings.Compare(a.Title, b.Title)
+		case "rating":
+			comparison = cmp.Compare(a.Rating, b.Rating)
+		case "time":
+			comparison = cmp.Compare(a.Time, b.Time)
+		}
+		if order == "desc" {
+			comparison = -comparison
+		}
+		// Ties are in ascending movieID order, so that pages are stable
+		if comparison == 0 {
+			comparison = cmp.Compare(a.MovieID, b.MovieID)
+		}
+		return comparison
+	})
+	start := min((page-1)*pageSize, len(ratings))
+	end := min(start+pageSize, len(ratings))
+	writeJSON(w, http.StatusOK, UserRatingsResponse{
+		UserID:   userID,
+		Total:    len(ratings),
+		Page:     page,
+		PageSize: pageSize,
+		Ratings:  ratings[start:end],
+	})
+}
+
+// Returns the tags of $movieID with their number of occurrences, most frequent first
+func countMovieTags(movieID int) []TagCount {
+	tagCounts := make([]TagCount, 0)
+	for tag, count := range data.MovieTags.Occurrences(movieID) {
+		tagCounts = append(tagCounts, TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(tagCounts, func(a, b TagCount) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return tagCounts
+}
+
+// Returns the segments of the path of $r after $prefix, eg. ["1", "tags"] for /api/v1/movies/1/tags
+func pathSegments(r *http.Request, prefix string) []string {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Parses the ID of a path segment, returning an InvalidParameter error for $field if it's not an integer
+func parsePathID(segment string, field string) (int, *config.FieldError) {
+	id, err := strconv.Atoi(segment)
+	if err != nil {
+		return 0, &config.FieldError{Code: config.InvalidParameter, Field: field, Message: fmt.Sprintf("'%s' must be an integer, got '%s'", field, segment)}
+	}
+	return id, nil
+}
@@ -7,13 +7,31 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	util "recommender/utils"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 /*
 Accepted values:
-  - Algorithm: user, item, tag, hybrid
-  - Similarity: jaccard, dice, cosine, pearson
-  - Input: user_id, movie_id
+  - Algorithm: user, item, tag, title, hybrid, bpr, slopeone, popular, top-rated, trending, p3alpha, rp3beta, assoc
+  - Similarity: jaccard, dice, cosine, pearson (ignored by bpr, slopeone, popular, top-rated, trending, p3alpha, rp3beta, assoc)
+  - Input: user_id, movie_id (optional user_id for popular, top-rated, trending)
+  - Seeds: movie_ids (assoc uses [Input] if empty). tag, title and hybrid rank the movies similar to all of them
+  - NegativeSeeds: movie_ids whose similar movies are ranked lower by tag, title and hybrid
+  - SeedWeights: {movie_id:weight} of the seeds and negative seeds (1 if missing)
+  - Aggregation: mean, max, sum, rrf (strategy to combine the similarities to the seeds of tag, title and hybrid)
+  - Profile: {movie_id:rating} ratings of an anonymous user, used instead of an Input user (see ProfileAlgorithms)
+  - InputType: user, movie (only used by p3alpha, rp3beta and to compare two users or two movies)
+  - Implicit: treat every rating >= Threshold (any rating if Threshold <= 0) as a positive interaction
+  - Approximate: only score the LSH candidates (Bands x Rows MinHash signatures) of user, item, hybrid, tag
+  - Quantize: load the quantized rating snapshots (if any) and keep ratings as uint8 steps of their scale
+  - Compact: fold the mutation log of the Web-Server into new snapshots instead of recommending
+  - Filters: restrictions of the movies every algorithm can recommend (see ResultFilters)
+  - Import: Letterboxd or IMDb ratings export to convert into the ImportOutput profile file instead of recommending
+  - Predict: movie_id whose rating by the Input user to forecast with user or item instead of recommending
+  - CompareTo: user_id or movie_id (see InputType) to compare with the Input by every metric instead of recommending
 */
 type Config struct {
 	DataDir         string
@@ -29,10 +47,405 @@ type Config struct {
 	WebServer       bool
 	K               int
 	NumThreads      int
+	Implicit        bool
+	Threshold       float64
+	Prior           float64
+	Window          int
+	InputType       string
+	Alpha           float64
+	Beta            float64
+	Seeds           []int
+	NegativeSeeds   []int
+	SeedWeights     map[int]float64
+	Aggregation     string
+	Profile         map[int]float32
+	MinSupport      float64
+	MinConfidence   float64
+	Approximate     bool
+	Recall          bool
+	Bands           int
+	Rows            int
+	Quantize        bool
+	Compact         bool
+	Filters         ResultFilters
+	Import          string
+	ImportOutput    string
+	Predict         int
+	CompareTo       int
+}
+
+/*
+Restrictions of the recommended movies, applied to the ranked results of any algorithm before keeping the top ones:
+  - MinRatings: min number of ratings of a movie
+  - Allow: only these movie IDs, unless nil. Deny: never these movie IDs
+  - MinYear, MaxYear: range of release years (0 for no bound). Movies without a year are dropped when one is set
+  - IncludeTags, IncludeGenres: movies with at least one of these tags and genres, unless empty
+  - ExcludeTags, ExcludeGenres: movies with none of these tags and genres
+*/
+type ResultFilters struct {
+	MinRatings    int
+	Allow         []int
+	Deny          []int
+	MinYear       int
+	MaxYear       int
+	IncludeTags   []string
+	ExcludeTags   []string
+	IncludeGenres []string
+	ExcludeGenres []string
+}
+
+// Returns true if any of the filters restricts the results
+func (filters *ResultFilters) Active() bool {
+	return filters.MinRatings > 0 || filters.Allow != nil || len(filters.Deny) > 0 || filters.MinYear > 0 || filters.MaxYear > 0 ||
+		len(filters.IncludeTags) > 0 || len(filters.ExcludeTags) > 0 || len(filters.IncludeGenres) > 0 || len(filters.ExcludeGenres) > 0
+}
+
+// Similarity metrics for which preprocess stores item-item neighbor snapshots
+var SimilarityMetrics = []string{"jaccard", "dice", "cosine", "pearson"}
+
+// Algorithms that rank movies without using a similarity metric
+var similarityFreeAlgorithms = map[string]bool{
+	"bpr": true, "slopeone": true, "popular": true, "top-rated": true, "trending": true, "p3alpha": true, "rp3beta": true, "assoc": true,
+}
+
+// Algorithms that accept an anonymous profile instead of an input user. tag ranks the movies similar to the ones it liked
+var ProfileAlgorithms = []string{"user", "item", "bpr", "slopeone", "p3alpha", "rp3beta", "tag", "popular", "top-rated", "trending"}
+
+// Algorithms that rank the movies similar to several seed movies, and the strategies to combine the similarities to every seed
+var SeedAlgorithms = []string{"tag", "title", "hybrid"}
+var Aggregations = []string{"mean", "max", "sum", "rrf"}
+
+// Algorithms that accept implicit feedback. bpr and the random walks always treat the ratings as implicit feedback
+var ImplicitAlgorithms = []string{"user", "item", "bpr", "p3alpha", "rp3beta"}
+
+// Algorithms that forecast the rating of a single movie
+var PredictAlgorithms = []string{"user", "item"}
+
+// Non-personalized algorithms that don't need an input
+var inputFreeAlgorithms = map[string]bool{"popular": true, "top-rated": true, "trending": true}
+
+// Returns true if $algorithm requires a similarity metric
+func UsesSimilarity(algorithm string) bool {
+	return !similarityFreeAlgorithms[algorithm]
+}
+
+// Returns true if $algorithm requires a user or movie ID as input
+func UsesInput(algorithm string) bool {
+	return !inputFreeAlgorithms[algorithm]
+}
+
+// Returns true if the input of the configured algorithm is a movie ID instead of a user ID
+func (cfg *Config) InputIsMovie() bool {
+	switch cfg.Algorithm {
+	case "tag", "title", "hybrid", "assoc":
+		return true
+	case "p3alpha", "rp3beta":
+		return cfg.InputType == "movie"
+	}
+	return false
+}
+
+/*
+Returns true if the configured algorithm ranks the movies similar to several seeds, or to seeds with weights, instead of
+the ones similar to the Input movie. Seeds is [Input] when no seeds are given, see DefaultSeeds.
+*/
+func (cfg *Config) UsesSeeds() bool {
+	if !slices.Contains(SeedAlgorithms, cfg.Algorithm) {
+		return false
+	}
+	return len(cfg.Seeds) > 1 || len(cfg.NegativeSeeds) > 0 || len(cfg.SeedWeights) > 0 || (len(cfg.Seeds) == 1 && cfg.Seeds[0] != cfg.Input)
+}
+
+// Sets Seeds to [Input] when no seeds are given, so that an Input movie is the single seed of the seed algorithms
+func (cfg *Config) DefaultSeeds() {
+	if len(cfg.Seeds) == 0 && cfg.Input != 0 {
+		cfg.Seeds = []int{cfg.Input}
+	}
+}
+
+// Returns true if $cfg forecasts the rating of a single movie or compares two users or movies instead of recommending
+func (cfg *Config) Pairwise() bool {
+	return cfg.Predict != 0 || cfg.CompareTo != 0
+}
+
+/*
+Parses a comma separated list of IDs with optional weights, eg. "1:2,260,1196:0.5". Returns the IDs and the
+weights of the ones that have one
+*/
+func ParseWeightedIDList(list string) ([]int, map[int]float64, error) {
+	ids := make([]int, 0)
+	var weights map[int]float64
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		idField, weightField, weighted := strings.Cut(field, ":")
+		id, err := strconv.Atoi(strings.TrimSpace(idField))
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid ID '%s' in list '%s'", idField, list)
+		}
+		ids = append(ids, id)
+		if !weighted {
+			continue
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightField), 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid weight '%s' in list '%s'", weightField, list)
+		}
+		if weights == nil {
+			weights = make(map[int]float64)
+		}
+		weights[id] = weight
+	}
+	return ids, weights, nil
+}
+
+// Parses a comma separated list of names, eg. "dark comedy, noir", dropping the empty ones
+func ParseList(list string) []string {
+	names := make([]string, 0)
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			names = append(names, field)
+		}
+	}
+	return names
+}
+
+// Parses a comma separated list of IDs, eg. "1,2,3"
+func ParseIDList(list string) ([]int, error) {
+	ids := make([]int, 0)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ID '%s' in list '%s'", field, list)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Codes of the validation errors of the recommendation parameters
+const (
+	MissingParameter = "missing_parameter"
+	InvalidParameter = "invalid_parameter"
+	InvalidValue     = "invalid_value"
+)
+
+// Upper bounds of the request sizes, since they size the result buffers, the neighborhoods and the MinHash signatures
+const (
+	maxRecommendations = 10000
+	maxNeighbors       = 10000
+	maxLSHHashes       = 500
+)
+
+// Validation error of a single recommendation parameter, named after its Web-Server query parameter
+type FieldError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (err FieldError) Error() string {
+	return err.Message
+}
+
+// Accepted values of the algorithm and input type parameters
+var Algorithms = []string{"user", "item", "tag", "title", "hybrid", "bpr", "slopeone", "popular", "top-rated", "trending", "p3alpha", "rp3beta", "assoc"}
+var InputTypes = []string{"user", "movie"}
+
+/*
+Checks the recommendation parameters of $cfg with the rules shared by the CLI and the Web-Server. Missing
+parameters are reported with the MissingParameter code and the ones with unaccepted values with InvalidValue.
+*/
+func (cfg *Config) Validate() []FieldError {
+	validationErrors := make([]FieldError, 0)
+	missing := func(field string, message string) {
+		validationErrors = append(validationErrors, FieldError{Code: MissingParameter, Field: field, Message: message})
+	}
+	invalid := func(field string, message string) {
+		validationErrors = append(validationErrors, FieldError{Code: InvalidValue, Field: field, Message: message})
+	}
+	// Comparing two users or movies needs neither an algorithm nor a metric, which only ranks them
+	comparing := cfg.CompareTo != 0
+	// Check if required parameters are provided
+	if cfg.Recommendations == 0 && !cfg.Pairwise() {
+		missing("recommendations", "Number of recommendations is required")
+	}
+	if cfg.Algorithm == "" && !comparing {
+		missing("algorithm", "Algorithm is required")
+	}
+	if cfg.Similarity == "" && UsesSimilarity(cfg.Algorithm) && !comparing {
+		missing("similarity", "Similarity metric is required")
+	}
+	if cfg.Input == 0 && len(cfg.Seeds) == 0 && cfg.Profile == nil && (UsesInput(cfg.Algorithm) || comparing) {
+		missing("input", "Input is required")
+	}
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	// Validate that the number of recommendations is positive and bounded
+	if cfg.Recommendations < 0 || cfg.Recommendations > maxRecommendations {
+		invalid("recommendations", fmt.Sprintf("Number of recommendations must be between 1 and %d", maxRecommendations))
+	}
+
+	// Validate that the number of neighbors is positive and bounded
+	if cfg.K <= 0 || cfg.K > maxNeighbors {
+		invalid("k", fmt.Sprintf("Number of neighbors must be between 1 and %d", maxNeighbors))
+	}
+
+	// Validate that provided similarity metric is accepted
+	if ((UsesSimilarity(cfg.Algorithm) && !comparing) || (comparing && cfg.Similarity != "")) && !slices.Contains(SimilarityMetrics, cfg.Similarity) {
+		invalid("similarity", "Allowed similarity metrics: 'jaccard', 'dice', 'cosine', 'pearson'")
+	}
+
+	// Validate that provided algorithm is accepted
+	if !slices.Contains(Algorithms, cfg.Algorithm) && !comparing {
+		invalid("algorithm", "Allowed algorithms: 'user', 'item', 'tag', 'title', 'hybrid', 'bpr', 'slopeone', 'popular', 'top-rated', 'trending', 'p3alpha', 'rp3beta', 'assoc'")
+	}
+
+	// Validate the input type and the random walk exponents
+	if !slices.Contains(InputTypes, cfg.InputType) {
+		invalid("inputType", "Allowed input types: 'user', 'movie'")
+	}
+	if cfg.Alpha <= 0 {
+		invalid("alpha", "Alpha must be greater than 0")
+	}
+	if cfg.Beta < 0 {
+		invalid("beta", "Beta must be greater than or equal to 0")
+	}
+
+	// Validate that the prior weight and the trending window are not negative
+	if cfg.Prior < 0 {
+		invalid("prior", "Prior weight must be greater than or equal to 0")
+	}
+	if cfg.Window <= 0 {
+		invalid("window", "Trending window must be greater than 0 days")
+	}
+
+	// Validate that the profile replaces the input user of an algorithm that accepts one
+	if cfg.Profile != nil {
+		if !slices.Contains(ProfileAlgorithms, cfg.Algorithm) {
+			invalid("profile", "Profiles are accepted by: 'user', 'item', 'bpr', 'slopeone', 'p3alpha', 'rp3beta', 'tag', 'popular', 'top-rated', 'trending'")
+		} else if cfg.Input != 0 || (cfg.InputIsMovie() && cfg.Algorithm != "tag") {
+			invalid("profile", "A profile replaces the input user and can't be combined with an input")
+		}
+		if len(cfg.Profile) == 0 {
+			invalid("profile", "Profile must contain at least one rating")
+		}
+		for movieID := range cfg.Profile {
+			if movieID <= 0 {
+				invalid("profile", "Movie IDs of the profile must be greater than 0")
+				break
+			}
+		}
+	}
+
+	// Validate that negative seeds and weights are only given to the algorithms that combine several seeds
+	if len(cfg.NegativeSeeds) > 0 && !slices.Contains(SeedAlgorithms, cfg.Algorithm) {
+		invalid("negativeSeeds", "Negative seeds are accepted by: 'tag', 'title', 'hybrid'")
+	}
+	if len(cfg.SeedWeights) > 0 && !slices.Contains(SeedAlgorithms, cfg.Algorithm) {
+		invalid("seedWeights", "Seed weights are accepted by: 'tag', 'title', 'hybrid'")
+	}
+	for _, movieID := range cfg.NegativeSeeds {
+		if slices.Contains(cfg.Seeds, movieID) {
+			invalid("negativeSeeds", fmt.Sprintf("Movie %d can't be both a seed and a negative seed", movieID))
+			break
+		}
+	}
+	if len(cfg.NegativeSeeds) > 0 && len(cfg.Seeds) == 0 {
+		invalid("seeds", "Negative seeds require at least one seed")
+	}
+	for movieID, weight := range cfg.SeedWeights {
+		if !slices.Contains(cfg.Seeds, movieID) && !slices.Contains(cfg.NegativeSeeds, movieID) {
+			invalid("seedWeights", fmt.Sprintf("Movie %d has a weight but is not a seed", movieID))
+			break
+		}
+		if weight <= 0 {
+			invalid("seedWeights", "Seed weights must be greater than 0")
+			break
+		}
+	}
+	if cfg.Aggregation != "" && !slices.Contains(Aggregations, cfg.Aggregation) {
+		invalid("aggregation", "Allowed aggregations: 'mean', 'max', 'sum', 'rrf'")
+	}
+
+	// Validate that implicit feedback is given to an algorithm that uses it, with a metric defined over 1.0 ratings
+	if cfg.Implicit && !comparing && !slices.Contains(ImplicitAlgorithms, cfg.Algorithm) {
+		invalid("implicit", "Implicit feedback is accepted by: 'user', 'item', 'bpr', 'p3alpha', 'rp3beta'")
+	}
+	if cfg.Implicit && cfg.Similarity == "pearson" && (UsesSimilarity(cfg.Algorithm) || comparing) {
+		invalid("similarity", "Pearson correlation is undefined for implicit feedback, whose ratings are all 1.0")
+	}
+
+	// Validate the movie to forecast the rating of and the user or movie to compare with
+	if cfg.Predict < 0 {
+		invalid("predict", "Movie ID must be greater than 0")
+	}
+	if cfg.Predict != 0 && !slices.Contains(PredictAlgorithms, cfg.Algorithm) {
+		invalid("algorithm", "Ratings are predicted by: 'user', 'item'")
+	}
+	if cfg.CompareTo < 0 {
+		invalid("compareTo", "ID to compare with must be greater than 0")
+	} else if comparing && cfg.Predict != 0 {
+		invalid("compareTo", "A comparison can't be combined with a prediction")
+	}
+
+	// Validate the result filters
+	if cfg.Filters.MinRatings < 0 {
+		invalid("minRatings", "Min number of ratings must be greater than or equal to 0")
+	}
+	if cfg.Filters.Allow != nil && len(cfg.Filters.Allow) == 0 {
+		invalid("allow", "Allowlist must contain at least one movie ID")
+	}
+	if cfg.Filters.MinYear < 0 {
+		invalid("minYear", "Min year must be greater than or equal to 0")
+	}
+	if cfg.Filters.MaxYear < 0 || (cfg.Filters.MaxYear > 0 && cfg.Filters.MaxYear < cfg.Filters.MinYear) {
+		invalid("maxYear", "Max year must be greater than or equal to 0 and to the min year")
+	}
+
+	// Validate the LSH parameters
+	if cfg.Bands <= 0 {
+		invalid("bands", "LSH bands must be greater than 0")
+	}
+	if cfg.Rows <= 0 {
+		invalid("rows", "LSH rows must be greater than 0")
+	}
+	if cfg.Bands > 0 && cfg.Rows > 0 && cfg.Bands*cfg.Rows > maxLSHHashes {
+		invalid("bands", fmt.Sprintf("LSH bands times rows must be at most %d", maxLSHHashes))
+	}
+
+	// Validate that maxRecords is greater than 0 or -1 (default)
+	if cfg.MaxRecords <= 0 && cfg.MaxRecords != -1 {
+		invalid("maxRecords", "Max records to load must be greater than 0 or -1")
+	}
+	return validationErrors
 }
 
 type PreprocessConfig struct {
 	DataDir string
+	// Association rule mining over each user's set of highly rated movies
+	MinRating     float64
+	MinSupport    float64
+	MinConfidence float64
+	MaxItemset    int
+	// Number of most similar movies stored per movie for each of the NeighborsMetrics (none disables the neighbor snapshots)
+	Neighbors        int
+	NeighborsMetrics []string
+	// All-pairs movie similarities of the PairsMetric (empty disables them) of at least MinSimilarity, written as csv or bin
+	PairsMetric   string
+	MinSimilarity float64
+	PairsFormat   string
+	NumThreads    int
+	// Also write the ratings as uint8 steps of their scale to users.q.gob and movies.q.gob
+	Quantize bool
 }
 
 func InitRecommender() (Config, error) {
@@ -43,13 +456,66 @@ func InitRecommender() (Config, error) {
 	input := flag.Int("i", 0, "Input")
 	maxRecords := flag.Int("r", -1, "Max records to load")
 	enableUI := flag.Bool("u", false, "Enable UI webserver")
+	implicit := flag.Bool("implicit", false, "Treat ratings as implicit feedback")
+	threshold := flag.Float64("t", 0, "Min rating of a positive interaction in implicit mode")
+	prior := flag.Float64("prior", 10, "Weight of the prior (in votes) for top-rated movies")
+	window := flag.Int("w", 30, "Window in days for trending movies")
+	inputType := flag.String("type", "user", "Input type of random walk algorithms and -compare (user or movie)")
+	alpha := flag.Float64("alpha", 1.0, "Exponent of the random walk transition probabilities")
+	beta := flag.Float64("beta", 0.5, "Exponent of the popularity penalty of rp3beta")
+	seedList := flag.String("seeds", "", "Comma separated list of seed movie IDs, with optional weights (eg. 1:2,260)")
+	negativeSeedList := flag.String("negseeds", "", "Comma separated list of negative seed movie IDs of tag, title and hybrid, with optional weights")
+	aggregation := flag.String("agg", "mean", "Aggregation of the similarities to the seeds of tag, title and hybrid (mean, max, sum or rrf)")
+	minSupport := flag.Float64("minsup", 0, "Min support of association rules")
+	minConfidence := flag.Float64("minconf", 0, "Min confidence of association rules")
+	approximate := flag.Bool("approx", false, "Only score the LSH candidates of the input")
+	recall := flag.Bool("recall", false, "Report the recall of approximate against exact search")
+	bands := flag.Int("bands", 50, "Number of LSH bands")
+	rows := flag.Int("rows", 2, "Number of MinHash rows per LSH band")
+	quantize := flag.Bool("quantize", false, "Store ratings as uint8 steps of the dataset rating scale")
+	compact := flag.Bool("compact", false, "Fold the mutation log into new snapshots and exit")
+	profileFile := flag.String("profile", "", "CSV file with the movieId and rating columns of an anonymous user")
+	minRatings := flag.Int("minratings", 0, "Min number of ratings of the recommended movies")
+	allowList := flag.String("allow", "", "Comma separated list of the only movie IDs that can be recommended")
+	allowFile := flag.String("allowfile", "", "File with the only movie IDs that can be recommended, one per line or in a movieId column")
+	denyList := flag.String("deny", "", "Comma separated list of movie IDs that are never recommended")
+	minYear := flag.Int("minyear", 0, "Min release year of the recommended movies")
+	maxYear := flag.Int("maxyear", 0, "Max release year of the recommended movies")
+	includeTags := flag.String("tags", "", "Comma separated list of tags, one of which the recommended movies must have")
+	excludeTags := flag.String("notags", "", "Comma separated list of tags the recommended movies must not have")
+	includeGenres := flag.String("genres", "", "Comma separated list of genres, one of which the recommended movies must have")
+	excludeGenres := flag.String("nogenres", "", "Comma separated list of genres the recommended movies must not have")
+	importFile := flag.String("import", "", "Letterboxd or IMDb ratings export to convert into a profile file and exit")
+	importOutput := flag.String("o", "profile.csv", "Profile file written by -import")
+	predict := flag.Int("predict", 0, "Movie ID whose rating by the input user to forecast with user or item")
+	compareTo := flag.Int("compare", 0, "User or movie ID (see -type) to compare with the input by every similarity metric")
 	flag.Parse()
 
 	var validationErrors []error
 	usageMsg := fmt.Sprintln("\nUsage:\n" +
-		"recommender -n number_of_recommendations -s similarity_metric -a algorithm -i input (-r maxRecordsToRead)\n" +
+		"recommender -n number_of_recommendations -s similarity_metric -a algorithm -i input (-r maxRecordsToRead) (-implicit -t threshold)\n" +
+		"OR\n" +
+		"recommender -n number_of_recommendations -a popular|top-rated|trending (-i user_id) (-prior votes) (-w days)\n" +
+		"OR\n" +
+		"recommender -n number_of_recommendations -a p3alpha|rp3beta -i input (-type user|movie) (-alpha alpha) (-beta beta)\n" +
+		"OR\n" +
+		"recommender -n number_of_recommendations -a assoc -i movie_id|-seeds movie_id,movie_id,... (-minsup support) (-minconf confidence)\n" +
+		"OR\n" +
+		"recommender -n number_of_recommendations -s similarity_metric -a tag|title|hybrid -seeds movie_id(:weight),... (-negseeds movie_id(:weight),...) (-agg mean|max|sum|rrf)\n" +
+		"OR\n" +
+		"recommender -n number_of_recommendations (-s similarity_metric) -a algorithm -profile /path/to/ratings.csv\n" +
+		"Filters of any algorithm: (-minratings count) (-allow|-deny movie_id,...) (-allowfile /path/to/ids.txt) (-minyear year) (-maxyear year)\n" +
+		"(-tags|-notags tag,...) (-genres|-nogenres genre,...)\n" +
+		"OR\n" +
+		"recommender -predict movie_id -s similarity_metric -a user|item -i user_id|-profile /path/to/ratings.csv\n" +
+		"OR\n" +
+		"recommender -compare user_id|movie_id -i user_id|movie_id (-type user|movie) (-s similarity_metric)\n" +
 		"OR\n" +
-		"recommender -u",
+		"recommender -u\n" +
+		"OR\n" +
+		"recommender -compact\n" +
+		"OR\n" +
+		"recommender -import /path/to/export.csv (-o /path/to/profile.csv)",
 	)
 	dirNotFoundMsg := fmt.Sprintf("Please execute the preprocess binary before recommender.\n"+
 		"This binary will generate the preprocessed files in '%s' directory.\n"+
@@ -82,31 +548,50 @@ func InitRecommender() (Config, error) {
 		validationErrors = append(validationErrors, errors.New(fmt.Sprintf("'%s' was not found.", tagsFile)))
 	}
 
-	if !*enableUI {
-		// Check if required flags are provided.
-		if *numRecommendations == 0 || *similarityMetric == "" || *algorithm == "" || *input == 0 {
-			return Config{}, errors.New(usageMsg)
-		}
-
-		// Validate that provided similarity metric is accepted
-		if *similarityMetric != "jaccard" && *similarityMetric != "dice" && *similarityMetric != "cosine" && *similarityMetric != "pearson" {
-			validationErrors = append(validationErrors, errors.New("Allowed similarity metrics: 'jaccard', 'dice', 'cosine', 'pearson'"))
+	seeds, seedWeights, err := ParseWeightedIDList(*seedList)
+	if err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	negativeSeeds, negativeSeedWeights, err := ParseWeightedIDList(*negativeSeedList)
+	if err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	for movieID, weight := range negativeSeedWeights {
+		if seedWeights == nil {
+			seedWeights = make(map[int]float64)
 		}
-
-		// Validate that provided algorithm is accepted
-		if *algorithm != "user" && *algorithm != "item" && *algorithm != "tag" && *algorithm != "title" && *algorithm != "hybrid" {
-			validationErrors = append(validationErrors, errors.New("Allowed similarity metrics: 'user', 'item', 'tag', 'title', 'hybrid'"))
+		seedWeights[movieID] = weight
+	}
+	filters := ResultFilters{
+		MinRatings:    *minRatings,
+		MinYear:       *minYear,
+		MaxYear:       *maxYear,
+		IncludeTags:   ParseList(*includeTags),
+		ExcludeTags:   ParseList(*excludeTags),
+		IncludeGenres: ParseList(*includeGenres),
+		ExcludeGenres: ParseList(*excludeGenres),
+	}
+	if filters.Deny, err = ParseIDList(*denyList); err != nil {
+		validationErrors = append(validationErrors, err)
+	}
+	if *allowList != "" {
+		if filters.Allow, err = ParseIDList(*allowList); err != nil {
+			validationErrors = append(validationErrors, err)
 		}
-
-		// Validate that maxRecords is greater than 0 or -1 (default)
-		if *maxRecords < 0 && *maxRecords != -1 {
-			validationErrors = append(validationErrors, errors.New("Max records to load must be greater than 0 or -1"))
+	}
+	if *allowFile != "" {
+		allowedIDs, err := util.LoadIDList(*allowFile)
+		if err != nil {
+			return Config{}, errors.New(fmt.Sprintf("Failed to load allowlist '%s': %s", *allowFile, err))
 		}
+		// An empty file is an empty allowlist, rejected by the validation, rather than no allowlist
+		filters.Allow = append(append(make([]int, 0, len(filters.Allow)+len(allowedIDs)), filters.Allow...), allowedIDs...)
 	}
-
-	// Check if any validation failed
-	if len(validationErrors) > 0 {
-		return Config{}, addToErrorList(validationErrors)
+	var profile map[int]float32
+	if *profileFile != "" {
+		if profile, err = util.LoadProfile(*profileFile); err != nil {
+			return Config{}, errors.New(fmt.Sprintf("Failed to load profile '%s': %s", *profileFile, err))
+		}
 	}
 
 	cfg := Config{
@@ -123,17 +608,76 @@ func InitRecommender() (Config, error) {
 		WebServer:       *enableUI,
 		K:               128,
 		NumThreads:      8,
+		Implicit:        *implicit,
+		Threshold:       *threshold,
+		Prior:           *prior,
+		Window:          *window,
+		InputType:       *inputType,
+		Alpha:           *alpha,
+		Beta:            *beta,
+		Seeds:           seeds,
+		NegativeSeeds:   negativeSeeds,
+		SeedWeights:     seedWeights,
+		Aggregation:     *aggregation,
+		Profile:         profile,
+		MinSupport:      *minSupport,
+		MinConfidence:   *minConfidence,
+		Approximate:     *approximate,
+		Recall:          *recall,
+		Bands:           *bands,
+		Rows:            *rows,
+		Quantize:        *quantize,
+		Compact:         *compact,
+		Filters:         filters,
+		Import:          *importFile,
+		ImportOutput:    *importOutput,
+		Predict:         *predict,
+		CompareTo:       *compareTo,
 	}
 
+	if !*enableUI && !*compact && *importFile == "" {
+		fieldErrors := cfg.Validate()
+		// Print the usage if required flags are not provided
+		for _, err := range fieldErrors {
+			if err.Code == MissingParameter {
+				return Config{}, errors.New(usageMsg)
+			}
+		}
+
+		// Association rules are an optional preprocessing artifact
+		rulesFile := filepath.Join(dataDir, "rules.gob")
+		if _, err := os.Stat(rulesFile); *algorithm == "assoc" && os.IsNotExist(err) {
+			validationErrors = append(validationErrors, errors.New(fmt.Sprintf("'%s' was not found. Please re-run preprocess.", rulesFile)))
+		}
+
+		// Validate the values of the recommendation flags
+		for _, err := range fieldErrors {
+			validationErrors = append(validationErrors, err)
+		}
+	}
+
+	// Check if any validation failed
+	if len(validationErrors) > 0 {
+		return Config{}, addToErrorList(validationErrors)
+	}
+
+	cfg.DefaultSeeds()
+
 	switch *algorithm {
-	case "user":
+	case "user", "bpr", "slopeone":
 		cfg.MaxUsers = *maxRecords
-	case "item", "hybrid":
+	case "item", "hybrid", "popular", "top-rated", "trending":
 		cfg.MaxMovies = *maxRecords
 	case "tag":
 		cfg.MaxTags = *maxRecords
 	case "title":
 		cfg.MaxTitles = *maxRecords
+	case "p3alpha", "rp3beta":
+		if cfg.InputIsMovie() {
+			cfg.MaxMovies = *maxRecords
+		} else {
+			cfg.MaxUsers = *maxRecords
+		}
 	}
 
 	return cfg, nil
@@ -141,10 +685,21 @@ func InitRecommender() (Config, error) {
 
 func InitPreprocess() (PreprocessConfig, error) {
 	dataDir := flag.String("d", "", "Original data directory (CSVs)")
+	minRating := flag.Float64("minrating", 4.0, "Min rating of a movie to be part of a user's transaction")
+	minSupport := flag.Float64("minsup", 0.01, "Min support of mined association rules")
+	minConfidence := flag.Float64("minconf", 0.1, "Min confidence of mined association rules")
+	maxItemset := flag.Int("maxlen", 2, "Max number of movies in a mined itemset")
+	neighbors := flag.Int("k", 128, "Number of most similar movies to store per movie")
+	neighborsMetrics := flag.String("neighbors", strings.Join(SimilarityMetrics, ","), "Comma separated similarity metrics of the stored neighbors (none to skip)")
+	pairsMetric := flag.String("pairs", "", "Similarity metric of the all-pairs movie similarities (empty to skip)")
+	minSimilarity := flag.Float64("minsim", 0.5, "Min similarity of a stored all-pairs edge")
+	pairsFormat := flag.String("format", "csv", "Format of the all-pairs edge list (csv or bin)")
+	quantize := flag.Bool("quantize", false, "Also write quantized rating snapshots")
+	numThreads := flag.Int("threads", 8, "Number of routines used to compute neighbors and all-pairs similarities")
 	flag.Parse()
 
 	var validationErrors []error
-	usageMsg := fmt.Sprintln("Usage: preprocess -d /path/to/csv/dataset")
+	usageMsg := fmt.Sprintln("Usage: preprocess -d /path/to/csv/dataset (-minrating rating) (-minsup support) (-minconf confidence) (-maxlen length) (-neighbors similarity_metric,...|none -k neighbors) (-pairs similarity_metric -minsim similarity -format csv|bin) (-threads threads) (-quantize)")
 
 	// Check if required flags are provided.
 	if *dataDir == "" {
@@ -173,12 +728,55 @@ func InitPreprocess() (PreprocessConfig, error) {
 		validationErrors = append(validationErrors, errors.New(fmt.Sprintf("'%s' was not found.", tagsFile)))
 	}
 
+	// Validate association rule thresholds
+	if *minSupport <= 0 || *minSupport > 1 || *minConfidence < 0 || *minConfidence > 1 {
+		validationErrors = append(validationErrors, errors.New("Min support must be in (0, 1] and min confidence in [0, 1]"))
+	}
+	if *maxItemset < 2 {
+		validationErrors = append(validationErrors, errors.New("Max itemset length must be at least 2"))
+	}
+	if *neighbors <= 0 {
+		validationErrors = append(validationErrors, errors.New("Number of neighbors must be greater than 0"))
+	}
+	metrics := make([]string, 0)
+	if *neighborsMetrics != "none" {
+		metrics = strings.Split(*neighborsMetrics, ",")
+	}
+	for _, metric := range metrics {
+		if !slices.Contains(SimilarityMetrics, metric) {
+			validationErrors = append(validationErrors, errors.New("Allowed neighbors similarity metrics: 'jaccard', 'dice', 'cosine', 'pearson'"))
+			break
+		}
+	}
+	if *pairsMetric != "" && *pairsMetric != "jaccard" && *pairsMetric != "dice" && *pairsMetric != "cosine" && *pairsMetric != "pearson" {
+		validationErrors = append(validationErrors, errors.New("Allowed all-pairs similarity metrics: 'jaccard', 'dice', 'cosine', 'pearson'"))
+	}
+	if *pairsFormat != "csv" && *pairsFormat != "bin" {
+		validationErrors = append(validationErrors, errors.New("Allowed all-pairs formats: 'csv', 'bin'"))
+	}
+	if *numThreads <= 0 {
+		validationErrors = append(validationErrors, errors.New("Number of threads must be greater than 0"))
+	}
+
 	// Check if any validation failed
 	if len(validationErrors) > 0 {
 		return PreprocessConfig{}, addToErrorList(validationErrors)
 	}
 
-	return PreprocessConfig{DataDir: *dataDir}, nil
+	return PreprocessConfig{
+		DataDir:          *dataDir,
+		MinRating:        *minRating,
+		MinSupport:       *minSupport,
+		MinConfidence:    *minConfidence,
+		MaxItemset:       *maxItemset,
+		Neighbors:        *neighbors,
+		NeighborsMetrics: metrics,
+		PairsMetric:      *pairsMetric,
+		MinSimilarity:    *minSimilarity,
+		PairsFormat:      *pairsFormat,
+		NumThreads:       *numThreads,
+		Quantize:         *quantize,
+	}, nil
 }
 
 func addToErrorList(errs []error) error {
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	util "recommender/utils"
+	"strings"
+)
+
+/*
+Converts the Letterboxd or IMDb ratings export $exportFile into a profile file for -profile, matching its rows to the
+movies of the dataset by title and year. Rows that match no movie are listed so that they can be fixed by hand.
+*/
+func importProfile(dataDir string, exportFile string, profileFile string) error {
+	loadMovieTitles(dataDir, -1)
+	source, ratings, err := util.ReadRatingsExport(exportFile)
+	if err != nil {
+		return err
+	}
+	profile, unmatched := util.MatchImportedRatings(ratings, util.NewTitleMatcher(&data.MovieTitles))
+	fmt.Printf("Matched %d of the %d %s ratings of %s to %d movies.\n", len(ratings)-len(unmatched), len(ratings), source, exportFile, len(profile))
+	if len(unmatched) > 0 {
+		fmt.Println("Ratings not found in the dataset:")
+		for _, rating := range unmatched {
+			fmt.Printf("Row %d: %s (%d) => %g\n", rating.Row, strings.Join(rating.Titles, " / "), rating.Year, rating.Rating)
+		}
+	}
+	if len(profile) == 0 {
+		return fmt.Errorf("no rating of %s matches a movie of the dataset", exportFile)
+	}
+	if err := util.WriteProfile(profileFile, profile, &data.MovieTitles); err != nil {
+		return err
+	}
+	fmt.Printf("Profile written to file: %s. Use it with -profile %s\n", profileFile, profileFile)
+	return nil
+}
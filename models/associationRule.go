@@ -0,0 +1,10 @@
+package models
+
+type AssociationRule struct {
+	// Sorted movieIDs that imply the consequent movie
+	Antecedent []int   `json:"antecedent"`
+	Consequent int     `json:"consequent"`
+	Support    float64 `json:"support"`
+	Confidence float64 `json:"confidence"`
+	Lift       float64 `json:"lift"`
+}
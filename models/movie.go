@@ -2,6 +2,8 @@ package models
 
 type Movie struct {
 	UserRatings map[int]float32 `json:"userRatings"`
+	// Unix timestamp of each rating, keyed by userID. Empty for datasets without timestamps
+	RatingTimes map[int]int64 `json:"ratingTimes"`
 }
 
 type SimilarMovie struct {
@@ -0,0 +1,8 @@
+package models
+
+// Similarity of MovieB to MovieA, as produced by the all-pairs similarity engine
+type MovieEdge struct {
+	MovieA     int
+	MovieB     int
+	Similarity float64
+}
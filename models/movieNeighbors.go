@@ -0,0 +1,4 @@
+package models
+
+// Precomputed top-K most similar movies of every movie for one similarity metric, sorted by similarity in descending order
+type MovieNeighbors map[int][]SimilarMovie
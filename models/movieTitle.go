@@ -2,4 +2,6 @@ package models
 
 type MovieTitle struct {
 	Title string `json:"title"`
+	// Genres of the movie, empty if the dataset lists none
+	Genres []string `json:"genres"`
 }
@@ -0,0 +1,21 @@
+package models
+
+// Operations of a Mutation
+const (
+	RateMovie   = "rate"
+	UnrateMovie = "unrate"
+	TagMovie    = "tag"
+)
+
+// Change of the dataset made through the Web-Server, as recorded in its mutation log
+type Mutation struct {
+	Op      string `json:"op"`
+	UserID  int    `json:"userId"`
+	MovieID int    `json:"movieId"`
+	// Set by rate only
+	Rating float32 `json:"rating,omitempty"`
+	// Set by tag only
+	Tags []string `json:"tags,omitempty"`
+	// Unix timestamp of the mutation
+	Time int64 `json:"time"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// Neighbour a rating forecast is based on: a similar user who rated the movie, or a similar movie the user rated
+type PredictionNeighbor struct {
+	ID         int     `json:"id"`
+	Similarity float64 `json:"similarity"`
+	// Rating of the movie by the neighbour user, or of the neighbour movie by the user
+	Rating float32 `json:"rating"`
+	// Number of movies rated by both users, or of users who rated both movies
+	Overlap int `json:"overlap"`
+}
+
+// Forecast rating of a movie by a user, with the neighbours it is based on sorted by similarity in descending order
+type RatingPrediction struct {
+	UserID  int `json:"userId"`
+	MovieID int `json:"movieId"`
+	// Forecast rating, only set if Predicted, ie. if at least one neighbour rated the movie
+	Rating    float64 `json:"rating"`
+	Predicted bool    `json:"predicted"`
+	// Rating the user already gave to the movie, 0 if none
+	Actual    float32              `json:"actual,omitempty"`
+	Neighbors []PredictionNeighbor `json:"neighbors"`
+}
+
+// Similarity of two users or two movies by their ratings under every metric, with its supporting evidence
+type PairSimilarity struct {
+	ID      int `json:"id"`
+	OtherID int `json:"otherId"`
+	// Number of ratings of each of them, and of movies rated by both users or users who rated both movies
+	Ratings      int `json:"ratings"`
+	OtherRatings int `json:"otherRatings"`
+	Overlap      int `json:"overlap"`
+	// Similarity under every metric, except pearson for implicit feedback
+	Metrics map[string]float64 `json:"metrics"`
+	// Number of users or movies sharing at least one rating with ID, and rank of OtherID among them by Metric (0 if
+	// it isn't one of them). Only set if a metric is given
+	Metric    string `json:"metric,omitempty"`
+	Neighbors int    `json:"neighbors,omitempty"`
+	Rank      int    `json:"rank,omitempty"`
+}
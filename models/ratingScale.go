@@ -0,0 +1,40 @@
+package models
+
+import "math"
+
+/*
+Descriptor of a rating scale whose ratings are Min + step * Step, for step in [0, Levels), eg:
+  - MovieLens half stars: {Min: 0.5, Step: 0.5, Levels: 10}
+  - 1-10 scale:           {Min: 1, Step: 1, Levels: 10}
+  - binary feedback:      {Min: 0, Step: 1, Levels: 2}
+*/
+type RatingScale struct {
+	Min    float64
+	Step   float64
+	Levels int
+}
+
+// Returns the step of the nearest rating of the scale to $rating
+func (scale RatingScale) Encode(rating float32) uint8 {
+	step := math.Round((float64(rating) - scale.Min) / scale.Step)
+	return uint8(math.Max(0, math.Min(step, float64(scale.Levels-1))))
+}
+
+// Returns the rating of $step, rounded to float32 so that it equals the rating it was encoded from
+func (scale RatingScale) Decode(step uint8) float32 {
+	return float32(scale.Min + float64(step)*scale.Step)
+}
+
+// Ratings of every entity (user or movie) stored as steps of Scale, as written by preprocess -quantize
+type QuantizedRatings struct {
+	Scale    RatingScale
+	Entities map[int]QuantizedEntity
+}
+
+// Keys (movieIDs of a user or userIDs of a movie) sorted in ascending order, with the rating steps and times of every key
+type QuantizedEntity struct {
+	Keys  []int32
+	Steps []uint8
+	// Unix timestamp of each rating. Empty for users and datasets without timestamps
+	Times []int64
+}
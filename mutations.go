@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"recommender/config"
+	"recommender/helpers"
+	model "recommender/models"
+	"recommender/recommenders"
+	util "recommender/utils"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Name of the log of the mutations made through the Web-Server, in the data directory
+const mutationLogFile = "mutations.log"
+
+// Name of the list of the movies whose mutated ratings were compacted, until preprocess accounts for them
+const mutatedMoviesFile = "mutated-movies.txt"
+
+// Header of the responses based on precomputed data that doesn't account for the mutations since preprocess
+const staleDataHeader = "X-Stale-Data"
+
+var (
+	// Guards the shared dataset: requests read it under the read lock and mutations modify it under the write lock
+	dataMu sync.RWMutex
+	// Log of the Web-Server every mutation is appended to before being applied, or nil to apply them only in memory
+	mutationLog *util.MutationLog
+	// Scale of the ratings of the dataset when it wasn't quantized, ie. MovieLens half stars
+	defaultRatingScale = model.RatingScale{Min: 0.5, Step: 0.5, Levels: 10}
+)
+
+// Body of PUT /api/v1/users/{id}/ratings/{movieId}
+type RatingRequest struct {
+	Rating *float32 `json:"rating"`
+}
+
+type RatingResponse struct {
+	UserID  int     `json:"userId"`
+	MovieID int     `json:"movieId"`
+	Rating  float32 `json:"rating"`
+	// Unix timestamp of the rating
+	Time int64 `json:"time"`
+}
+
+// Body of POST /api/v1/movies/{id}/tags
+type TagsRequest struct {
+	UserID int      `json:"userId"`
+	Tags   []string `json:"tags"`
+}
+
+/*
+Handler of the rating mutations of a user:
+  - PUT /api/v1/users/{id}/ratings/{movieId}: sets the rating of the user to the movie, creating the user if needed
+  - DELETE /api/v1/users/{id}/ratings/{movieId}: removes the rating, along with the user if it has no ratings left
+*/
+func handleRatingMutation(w http.ResponseWriter, r *http.Request, userID int, movieID int) {
+	if userID <= 0 {
+		writeErrors(w, http.StatusUnprocessableEntity, config.FieldError{Code: config.InvalidValue, Field: "id", Message: "User ID must be greater than 0"})
+		return
+	}
+	mutation := model.Mutation{Op: model.UnrateMovie, UserID: userID, MovieID: movieID, Time: time.Now().Unix()}
+	if r.Method == http.MethodPut {
+		var request RatingRequest
+		if fieldErr := decodeBody(w, r, &request); fieldErr != nil {
+			writeErrors(w, http.StatusBadRequest, *fieldErr)
+			return
+		}
+		if request.Rating == nil {
+			writeErrors(w, http.StatusBadRequest, config.FieldError{Code: config.MissingParameter, Field: "rating", Message: "Rating is required"})
+			return
+		}
+		mutation.Op, mutation.Rating = model.RateMovie, *request.Rating
+	}
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if !data.MovieTitles.Has(movieID) {
+		writeErrors(w, http.StatusNotFound, config.FieldError{Code: "not_found", Field: "movieId", Message: fmt.Sprintf("Movie %d was not found", movieID)})
+		return
+	}
+	_, rated := data.Users.Rating(userID, movieID)
+	if mutation.Op == model.UnrateMovie {
+		if !rated {
+			writeErrors(w, http.StatusNotFound, config.FieldError{
+				Code: "not_found", Field: "movieId", Message: fmt.Sprintf("User %d has not rated movie %d", userID, movieID),
+			})
+			return
+		}
+		if recordMutation(w, mutation) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+	// Ratings must fit the index, which stores them as steps of the scale in quantized mode
+	scale := writableRatingScale()
+	if mutation.Rating < scale.Decode(0) || mutation.Rating > scale.Decode(uint8(scale.Levels-1)) || scale.Decode(scale.Encode(mutation.Rating)) != mutation.Rating {
+		writeErrors(w, http.StatusUnprocessableEntity, config.FieldError{
+			Code: config.InvalidValue, Field: "rating", Message: fmt.Sprintf("Rating must be between %g and %g in steps of %g", scale.Min, scale.Decode(uint8(scale.Levels-1)), scale.Step),
+		})
+		return
+	}
+	if !recordMutation(w, mutation) {
+		return
+	}
+	status := http.StatusOK
+	if !rated {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, RatingResponse{UserID: userID, MovieID: movieID, Rating: mutation.Rating, Time: mutation.Time})
+}
+
+// Handler of POST /api/v1/movies/{id}/tags, which adds the tags of a user to a movie and returns every tag of the movie
+func handleTagMutation(w http.ResponseWriter, r *http.Request, movieID int) {
+	var request TagsRequest
+	if fieldErr := decodeBody(w, r, &request); fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	fieldErrors := make([]config.FieldError, 0)
+	if request.UserID == 0 {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.MissingParameter, Field: "userId", Message: "User ID is required"})
+	}
+	if len(request.Tags) == 0 {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.MissingParameter, Field: "tags", Message: "At least one tag is required"})
+	}
+	if len(fieldErrors) > 0 {
+		writeErrors(w, http.StatusBadRequest, fieldErrors...)
+		return
+	}
+	if request.UserID < 0 {
+		fieldErrors = append(fieldErrors, config.FieldError{Code: config.InvalidValue, Field: "userId", Message: "User ID must be greater than 0"})
+	}
+	// Tags are normalized the same way as the ones of the CSV dataset
+	tags := make([]string, 0, len(request.Tags))
+	for _, tag := range request.Tags {
+		tag = strings.Join(helpers.ExtractTokensFromStr(tag), " ")
+		if tag == "" {
+			fieldErrors = append(fieldErrors, config.FieldError{Code: config.InvalidValue, Field: "tags", Message: "Tags must contain at least one word"})
+			break
+		}
+		tags = append(tags, tag)
+	}
+	if len(fieldErrors) > 0 {
+		writeErrors(w, http.StatusUnprocessableEntity, fieldErrors...)
+		return
+	}
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if !data.MovieTitles.Has(movieID) {
+		writeErrors(w, http.StatusNotFound, config.FieldError{Code: "not_found", Field: "id", Message: fmt.Sprintf("Movie %d was not found", movieID)})
+		return
+	}
+	mutation := model.Mutation{Op: model.TagMovie, UserID: request.UserID, MovieID: movieID, Tags: tags, Time: time.Now().Unix()}
+	if recordMutation(w, mutation) {
+		writeJSON(w, http.StatusCreated, MovieTagsResponse{MovieID: movieID, Tags: countMovieTags(movieID)})
+	}
+}
+
+// Appends $mutation to the log and applies it, or sends a 500 error response and returns false if it couldn't be logged
+func recordMutation(w http.ResponseWriter, mutation model.Mutation) bool {
+	if mutationLog != nil {
+		if err := mutationLog.Append(mutation); err != nil {
+			fmt.Printf("Failed to log mutation: %s\n", err)
+			writeErrors(w, http.StatusInternalServerError, config.FieldError{Code: "internal_error", Message: "The mutation could not be saved"})
+			return false
+		}
+	}
+	applyMutation(mutation)
+	return true
+}
+
+/*
+Applies $mutation to the shared dataset and its index. Must be called under the write lock of the dataset.
+Models and tables derived from the dataset are dropped, but precomputed neighbors and association rules are only
+refreshed by preprocess.
+*/
+func applyMutation(mutation model.Mutation) {
+	util.ApplyMutation(mutation, &data.Users, &data.Movies, &data.MovieTags)
+	if mutation.Op == model.RateMovie || mutation.Op == model.UnrateMovie {
+		markMutatedMovie(mutation.MovieID)
+	}
+	if data.Index != nil {
+		switch mutation.Op {
+		case model.RateMovie, model.UnrateMovie:
+			data.Index.UpdateRating(mutation.UserID, mutation.MovieID, &data.Users)
+		case model.TagMovie:
+			data.Index.UpdateMovieTags(mutation.MovieID, &data.MovieTags)
+		}
+	}
+	recommenders.ResetCaches()
+}
+
+// Records that the ratings of $movieID changed since preprocess, so that its neighbors are computed on the fly from now on
+func markMutatedMovie(movieID int) {
+	for _, neighbors := range data.Neighbors {
+		delete(neighbors, movieID)
+	}
+	data.MutatedMovies[movieID] = true
+}
+
+/*
+Sets the staleDataHeader of a response computed with $cfg if it was based on precomputed data that doesn't account for
+the rating mutations since preprocess: "rules" for the association rules, or "neighbors" for the neighbor snapshots,
+whose similarities to the mutated movies are out of date. The mutated movies' own neighbors are always computed again.
+*/
+func setStaleDataHeader(w http.ResponseWriter, cfg *config.Config) {
+	if len(data.MutatedMovies) == 0 {
+		return
+	}
+	switch {
+	case cfg.Algorithm == "assoc":
+		w.Header().Set(staleDataHeader, "rules")
+	case cfg.Algorithm == "item" && !cfg.Implicit && !cfg.Approximate && getNeighbors(cfg, &data) != nil:
+		w.Header().Set(staleDataHeader, "neighbors")
+	}
+}
+
+// Returns the scale new ratings must be on: the one of the quantized snapshots or of quantized mode, if any
+func writableRatingScale() model.RatingScale {
+	if data.Scale != nil {
+		return *data.Scale
+	}
+	return defaultRatingScale
+}
+
+// Applies the mutation log of the data directory, if any, on top of the loaded data
+func replayMutations(dataDir string) {
+	replayMutatedMovies(dataDir)
+	mutations, err := util.ReadMutationLog(dataDir + mutationLogFile)
+	if err != nil {
+		log.Fatalf("Failed to read the mutation log: %v", err)
+	}
+	for _, mutation := range mutations {
+		applyMutation(mutation)
+	}
+	if len(mutations) > 0 {
+		fmt.Printf("Replayed %d mutations of %s.\n", len(mutations), dataDir+mutationLogFile)
+	}
+}
+
+// Marks the movies of the compacted mutations, which are part of the loaded data but not of the precomputed one
+func replayMutatedMovies(dataDir string) {
+	if _, err := os.Stat(dataDir + mutatedMoviesFile); err != nil {
+		return
+	}
+	movieIDs, err := util.LoadIDList(dataDir + mutatedMoviesFile)
+	if err != nil {
+		log.Fatalf("Failed to read the compacted mutated movies: %v", err)
+	}
+	for _, movieID := range movieIDs {
+		markMutatedMovie(movieID)
+	}
+}
+
+// Opens the mutation log of the data directory for the Web-Server to append its mutations to
+func openMutationLog(dataDir string) {
+	opened, err := util.OpenMutationLog(dataDir + mutationLogFile)
+	if err != nil {
+		log.Fatalf("Failed to open the mutation log: %v", err)
+	}
+	mutationLog = opened
+}
+
+/*
+Folds the mutation log into new users, movies and tags files, along with the snapshot and the quantized snapshots
+if preprocess produced them, then removes the log. The Web-Server must not be running, as its mutations would be lost.
+The movies whose ratings were mutated are added to the mutatedMoviesFile, since the association rules and neighbor
+snapshots still don't account for them.
+*/
+func compactData(dataDir string) error {
+	loadUsers(dataDir, -1)
+	loadMovieTitles(dataDir, -1)
+	loadMovies(dataDir, -1)
+	loadMovieTags(dataDir, -1)
+	mutations, err := util.ReadMutationLog(dataDir + mutationLogFile)
+	if err != nil {
+		return err
+	}
+	if len(mutations) == 0 {
+		fmt.Println("The mutation log is empty, there is nothing to compact.")
+		return nil
+	}
+	replayMutatedMovies(dataDir)
+	for _, mutation := range mutations {
+		util.ApplyMutation(mutation, &data.Users, &data.Movies, &data.MovieTags)
+		if mutation.Op == model.RateMovie || mutation.Op == model.UnrateMovie {
+			data.MutatedMovies[mutation.MovieID] = true
+		}
+	}
+	users, movies, movieTags := data.Users.ToUsers(), data.Movies.ToMovies(), data.MovieTags.ToMovieTags()
+	files := map[string]interface{}{"users.gob": users, "movies.gob": movies, "tags.gob": movieTags}
+	if _, err := os.Stat(dataDir + "users.q.gob"); err == nil {
+		// Mutations may have added ratings out of the scale of the previous quantized snapshots
+		if scale, ok := util.DetectRatingScale(&data.Users, &data.Movies); ok {
+			files["users.q.gob"], files["movies.q.gob"] = util.QuantizeUsers(users, scale), util.QuantizeMovies(movies, scale)
+		} else {
+			fmt.Println("Ratings don't fit in a scale of 256 steps anymore, removing the quantized snapshots.")
+			if err := errors.Join(os.Remove(dataDir+"users.q.gob"), os.Remove(dataDir+"movies.q.gob")); err != nil {
+				return err
+			}
+		}
+	}
+	for fileName, fileData := range files {
+		if err := util.WriteGOB(fileData, dataDir+fileName); err != nil {
+			return err
+		}
+		fmt.Printf("Compacted data written to file: %s\n", dataDir+fileName)
+	}
+	if snapshot != nil {
+		err := util.ReplaceFile(dataDir+"snapshot.bin", func(tempPath string) error {
+			return util.WriteSnapshot(tempPath, users, movies, data.MovieTitles.ToMovieTitles(), movieTags)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot written to file: %s\n", dataDir+"snapshot.bin")
+	}
+	mutatedMovies := make([]int, 0, len(data.MutatedMovies))
+	for movieID := range data.MutatedMovies {
+		mutatedMovies = append(mutatedMovies, movieID)
+	}
+	if len(mutatedMovies) > 0 {
+		slices.Sort(mutatedMovies)
+		if err := util.WriteIDList(dataDir+mutatedMoviesFile, mutatedMovies, "Movies whose ratings were compacted after preprocess"); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(dataDir + mutationLogFile); err != nil {
+		return err
+	}
+	fmt.Printf("Compacted %d mutations. Re-run preprocess to refresh the association rules and neighbor snapshots.\n", len(mutations))
+	return nil
+}
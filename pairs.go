@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"recommender/config"
+	model "recommender/models"
+	"recommender/recommenders"
+	"runtime"
+	"time"
+)
+
+// Forecast rating of a movie by a user, as sent by GET /api/v1/users/{id}/predictions/{movieId}
+type PredictionResponse struct {
+	model.RatingPrediction
+	Title     string `json:"title"`
+	Algorithm string `json:"algorithm"`
+	Metric    string `json:"metric"`
+}
+
+// Fields of the configuration errors, renamed to the path and query parameters of the prediction and similarity endpoints
+var pairFields = map[string]string{
+	"input":      "id",
+	"predict":    "movieId",
+	"compareTo":  "otherId",
+	"similarity": "metric",
+}
+
+// Forecasts the rating of the Predict movie by the Input user, or compares the Input with the CompareTo user or movie
+func performPairwise(cfg *config.Config) {
+	var m runtime.MemStats
+	startTime := time.Now()
+	// Load only the files that are necessary for the prediction or the comparison
+	switch {
+	case cfg.Predict != 0:
+		loadMovieTitles(cfg.DataDir, -1)
+		loadUsers(cfg.DataDir, -1)
+		if cfg.Algorithm == "item" {
+			loadMovies(cfg.DataDir, -1)
+			loadNeighbors(cfg.DataDir, cfg.Similarity)
+		}
+	case cfg.InputType == "movie":
+		loadMovieTitles(cfg.DataDir, -1)
+		loadMovies(cfg.DataDir, -1)
+	default:
+		loadUsers(cfg.DataDir, -1)
+	}
+	data.Index = buildIndex(cfg.NumThreads, -1)
+	replayMutations(cfg.DataDir)
+	quantizeRatings()
+	if err := checkPairFeasibility(cfg, &data); err != nil {
+		fmt.Println(err.Message)
+		return
+	}
+	if cfg.Predict != 0 {
+		printPrediction(cfg, recommenders.PredictRating(cfg, cfg.Predict, &data.Users, &data.Movies, getNeighbors(cfg, &data), data.Index))
+	} else {
+		printPairSimilarity(cfg, recommenders.ComparePair(cfg, cfg.CompareTo, &data.Users, &data.Movies, data.Index))
+	}
+	fmt.Printf("Execution Time: %s\n", time.Since(startTime))
+	runtime.ReadMemStats(&m)
+	fmt.Printf("HeapAlloc: %d MiB\n", m.HeapAlloc/(1024*1024))
+}
+
+// Returns a not_found error for the first user or movie of the prediction or comparison of $cfg missing from $data
+func checkPairFeasibility(cfg *config.Config, data *Data) *config.FieldError {
+	notFound := func(field string, kind string, id int) *config.FieldError {
+		return &config.FieldError{Code: "not_found", Field: field, Message: fmt.Sprintf("%s %d was not found", kind, id)}
+	}
+	if cfg.Predict != 0 {
+		// Profiles are supplied by the request, like for recommendations
+		if !data.Users.Has(cfg.Input) && cfg.Profile == nil {
+			return notFound("id", "User", cfg.Input)
+		}
+		if !data.MovieTitles.Has(cfg.Predict) && !data.Movies.Has(cfg.Predict) {
+			return notFound("movieId", "Movie", cfg.Predict)
+		}
+		return nil
+	}
+	for _, pair := range []struct {
+		field string
+		id    int
+	}{{"id", cfg.Input}, {"otherId", cfg.CompareTo}} {
+		if cfg.InputType == "movie" {
+			if !data.Movies.Has(pair.id) {
+				return notFound(pair.field, "Movie", pair.id)
+			}
+		} else if !data.Users.Has(pair.id) {
+			return notFound(pair.field, "User", pair.id)
+		}
+	}
+	return nil
+}
+
+func printPrediction(cfg *config.Config, prediction model.RatingPrediction) {
+	subject := fmt.Sprintf("user %d", cfg.Input)
+	if cfg.Profile != nil {
+		subject = "the profile"
+	}
+	movie := fmt.Sprintf("movie %d (%s)", prediction.MovieID, data.MovieTitles.Title(prediction.MovieID))
+	if !prediction.Predicted {
+		fmt.Printf("No neighbour of %s supports a forecast of %s with %s and %s. Try using the other algorithm.\n", subject, movie, cfg.Algorithm, cfg.Similarity)
+	} else {
+		fmt.Printf("Predicted rating of %s by %s with %s and %s: %.2f\n", movie, subject, cfg.Algorithm, cfg.Similarity, prediction.Rating)
+		if cfg.Algorithm == "user" {
+			fmt.Printf("Based on the %d of the top %d similar users of %s who rated it:\n", len(prediction.Neighbors), cfg.K, subject)
+		} else {
+			fmt.Printf("Based on the %d movies liked by %s whose top %d similar movies include it:\n", len(prediction.Neighbors), subject, cfg.K)
+		}
+		for i, neighbor := range prediction.Neighbors {
+			if cfg.Algorithm == "user" {
+				fmt.Printf("%d: User ID: %d, Similarity: %.5f, Common movies: %d => %.1f\n", i+1, neighbor.ID, neighbor.Similarity, neighbor.Overlap, neighbor.Rating)
+			} else {
+				fmt.Printf("%d: ID: %d, Title: %s, Similarity: %.5f, Common users: %d => %.1f\n",
+					i+1, neighbor.ID, data.MovieTitles.Title(neighbor.ID), neighbor.Similarity, neighbor.Overlap, neighbor.Rating,
+				)
+			}
+		}
+	}
+	if prediction.Actual != 0 {
+		fmt.Printf("The actual rating of %s is %.1f.\n", subject, prediction.Actual)
+	}
+}
+
+func printPairSimilarity(cfg *config.Config, pair model.PairSimilarity) {
+	name := func(id int) string {
+		if cfg.InputType == "movie" {
+			return fmt.Sprintf("movie %d (%s)", id, data.MovieTitles.Title(id))
+		}
+		return fmt.Sprintf("user %d", id)
+	}
+	common := "common movies"
+	if cfg.InputType == "movie" {
+		common = "common users"
+	}
+	fmt.Printf("Similarity of %s and %s:\n", name(pair.ID), name(pair.OtherID))
+	fmt.Printf("Ratings: %d and %d, %s: %d\n", pair.Ratings, pair.OtherRatings, common, pair.Overlap)
+	for _, metric := range config.SimilarityMetrics {
+		fmt.Printf("%s: %.5f\n", metric, pair.Metrics[metric])
+	}
+	if pair.Metric == "" {
+		return
+	}
+	if pair.Rank == 0 {
+		fmt.Printf("%s is not one of the %d neighbours of %s by %s.\n", name(pair.OtherID), pair.Neighbors, name(pair.ID), pair.Metric)
+	} else {
+		fmt.Printf("Rank of %s among the %d neighbours of %s by %s: %d\n", name(pair.OtherID), pair.Neighbors, name(pair.ID), pair.Metric, pair.Rank)
+	}
+}
+
+/*
+Handler of the prediction and similarity endpoints, for the $inputType user or movie of the $segments of the path:
+  - GET /api/v1/users/{id}/predictions/{movieId}: forecast rating of a movie by a user, see recommenders.PredictRating
+  - GET /api/v1/users/{id}/similarity/{otherId}, GET /api/v1/movies/{id}/similarity/{otherId}: similarity of two
+    users or movies by every metric, see recommenders.ComparePair
+*/
+func handlePairRequest(w http.ResponseWriter, r *http.Request, inputType string, segments []string) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	otherField := "otherId"
+	if segments[1] == "predictions" {
+		otherField = "movieId"
+	}
+	id, fieldErr := parsePathID(segments[0], "id")
+	if fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	otherID, fieldErr := parsePathID(segments[2], otherField)
+	if fieldErr != nil {
+		writeErrors(w, http.StatusBadRequest, *fieldErr)
+		return
+	}
+	// An ID of 0 would leave nothing to predict or compare
+	if otherID <= 0 {
+		writeErrors(w, http.StatusUnprocessableEntity, config.FieldError{Code: config.InvalidValue, Field: otherField, Message: fmt.Sprintf("'%s' must be greater than 0", otherField)})
+		return
+	}
+	cfg, fieldErrors := parsePairQuery(r.URL.Query(), segments[1] == "predictions")
+	cfg.Input, cfg.InputType = id, inputType
+	if segments[1] == "predictions" {
+		cfg.Predict = otherID
+	} else {
+		cfg.CompareTo = otherID
+	}
+	if len(fieldErrors) == 0 {
+		fieldErrors = cfg.Validate()
+	}
+	if len(fieldErrors) > 0 {
+		for i, err := range fieldErrors {
+			if field, exists := pairFields[err.Field]; exists {
+				fieldErrors[i].Field = field
+			}
+		}
+		writeErrors(w, validationStatus(fieldErrors), fieldErrors...)
+		return
+	}
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	if err := checkPairFeasibility(&cfg, &data); err != nil {
+		writeErrors(w, http.StatusNotFound, *err)
+		return
+	}
+	if cfg.Predict != 0 {
+		setStaleDataHeader(w, &cfg)
+		writeJSON(w, http.StatusOK, PredictionResponse{
+			RatingPrediction: recommenders.PredictRating(&cfg, cfg.Predict, &data.Users, &data.Movies, getNeighbors(&cfg, &data), data.Index),
+			Title:            data.MovieTitles.Title(cfg.Predict),
+			Algorithm:        cfg.Algorithm,
+			Metric:           cfg.Similarity,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, recommenders.ComparePair(&cfg, cfg.CompareTo, &data.Users, &data.Movies, data.Index))
+}
+
+/*
+Parses the query parameters of the prediction ($predict) or similarity endpoints into a configuration, falling back
+to the CLI defaults for the optional ones: algorithm (user, predictions only), metric (required by predictions),
+k, implicit, threshold, approximate, bands and rows
+*/
+func parsePairQuery(queryParams url.Values, predict bool) (config.Config, []config.FieldError) {
+	parser := queryParser{values: queryParams}
+	cfg := config.Config{
+		Similarity:  parser.String("metric", ""),
+		MaxRecords:  -1,
+		K:           parser.Int("k", k),
+		NumThreads:  numThreads,
+		Implicit:    parser.Bool("implicit", false),
+		Threshold:   parser.Float("threshold", 0.0),
+		Prior:       10.0,
+		Window:      30,
+		Alpha:       1.0,
+		Beta:        0.5,
+		Approximate: parser.Bool("approximate", false),
+		Bands:       parser.Int("bands", defaultBands),
+		Rows:        parser.Int("rows", defaultRows),
+	}
+	if predict {
+		cfg.Algorithm = parser.String("algorithm", "user")
+	}
+	return cfg, parser.errors
+}
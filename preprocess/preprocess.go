@@ -1,12 +1,13 @@
 package main
 
 import (
-	"encoding/gob"
 	"fmt"
 	"log"
 	"os"
+	"recommender/algorithms"
 	"recommender/config"
 	model "recommender/models"
+	"recommender/recommenders"
 	util "recommender/utils"
 	"strings"
 )
@@ -37,22 +38,82 @@ func main() {
 	util.LoadCSVData(&movies, cfg.DataDir+"ratings.csv")
 	writeGOBToFile(movies, preprocessedDataDir+"movies.gob")
 
+	movieTable := util.NewMovieTable(movies)
+	if cfg.Quantize {
+		userTable := util.NewUserTable(users)
+		if scale, ok := util.DetectRatingScale(&userTable, &movieTable); ok {
+			fmt.Printf("Rating scale: min %g, step %g, %d levels.\n", scale.Min, scale.Step, scale.Levels)
+			writeGOBToFile(util.QuantizeUsers(users, scale), preprocessedDataDir+"users.q.gob")
+			writeGOBToFile(util.QuantizeMovies(movies, scale), preprocessedDataDir+"movies.q.gob")
+		} else {
+			fmt.Println("Ratings don't fit in a scale of 256 steps, skipping the quantized snapshots.")
+		}
+	}
+
 	tags := make(map[int]model.MovieTags)
 	util.LoadCSVData(&tags, cfg.DataDir+"tags.csv")
 	writeGOBToFile(tags, preprocessedDataDir+"tags.gob")
+
+	// Memory-mapped snapshot of the above for the recommender to load them without decoding
+	// The previous snapshot is replaced rather than truncated, since running recommenders may have mapped it
+	err = util.ReplaceFile(preprocessedDataDir+"snapshot.bin", func(tempPath string) error {
+		return util.WriteSnapshot(tempPath, users, movies, movieTitles, tags)
+	})
+	if err != nil {
+		fmt.Printf("Failed to write snapshot: %s\n", err)
+	} else {
+		fmt.Printf("Snapshot written to file: %s\n", preprocessedDataDir+"snapshot.bin")
+	}
+
+	// The rules and neighbors below account for the mutations compacted by the recommender
+	os.Remove(preprocessedDataDir + "mutated-movies.txt")
+
+	rules := mineAssociationRules(&cfg, users)
+	writeGOBToFile(rules, preprocessedDataDir+"rules.gob")
+
+	if len(cfg.NeighborsMetrics) > 0 || cfg.PairsMetric != "" {
+		for _, similarity := range cfg.NeighborsMetrics {
+			neighborCfg := config.Config{Similarity: similarity, K: cfg.Neighbors, NumThreads: cfg.NumThreads}
+			neighbors := recommenders.ComputeMovieNeighbors(&neighborCfg, &movieTable)
+			writeGOBToFile(neighbors, preprocessedDataDir+"neighbors-"+similarity+".gob")
+		}
+		if cfg.PairsMetric != "" {
+			pairsCfg := config.Config{Similarity: cfg.PairsMetric, NumThreads: cfg.NumThreads}
+			edges := recommenders.ComputeAllPairs(&pairsCfg, &movieTable, cfg.MinSimilarity)
+			filePath := preprocessedDataDir + "pairs-" + cfg.PairsMetric + "." + cfg.PairsFormat
+			if err := util.WriteMovieEdges(edges, filePath, cfg.PairsFormat); err != nil {
+				fmt.Printf("Failed to write all-pairs similarities: %s\n", err)
+			} else {
+				fmt.Printf("%d all-pairs similarities written to file: %s\n", len(edges), filePath)
+			}
+		}
+	}
+}
+
+// Mines association rules over the set of highly rated movies of every user
+func mineAssociationRules(cfg *config.PreprocessConfig, users map[int]model.User) []model.AssociationRule {
+	transactions := make([][]int, 0, len(users))
+	for _, user := range users {
+		transaction := make([]int, 0)
+		for movieID, rating := range user.MovieRatings {
+			if float64(rating) >= cfg.MinRating {
+				transaction = append(transaction, movieID)
+			}
+		}
+		if len(transaction) > 0 {
+			transactions = append(transactions, transaction)
+		}
+	}
+	itemsets := algorithms.FrequentItemsets(transactions, cfg.MinSupport, cfg.MaxItemset)
+	rules := algorithms.AssociationRules(itemsets, len(transactions), cfg.MinConfidence)
+	fmt.Printf("Mined %d association rules from %d frequent itemsets.\n", len(rules), len(itemsets))
+	return rules
 }
 
 // Stores a data interface into a file using Go Binary format
 func writeGOBToFile(data interface{}, filePath string) {
-	file, err := os.Create(filePath)
-	if err != nil {
-		fmt.Printf("Failed to create file: %s\n", err)
-		return
-	}
-	defer file.Close()
-	encoder := gob.NewEncoder(file)
-	if err := encoder.Encode(data); err != nil {
-		fmt.Printf("Failed to encode GOB: %s\n", err)
+	if err := util.WriteGOB(data, filePath); err != nil {
+		fmt.Printf("Failed to write GOB: %s\n", err)
 		return
 	}
 	pathTokens := strings.Split(filePath, "/")
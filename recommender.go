@@ -6,21 +6,34 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"recommender/algorithms"
 	"recommender/config"
 	model "recommender/models"
 	"recommender/recommenders"
 	util "recommender/utils"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Data struct {
-	Users       map[int]model.User
-	MovieTitles map[int]model.MovieTitle
-	Movies      map[int]model.Movie
-	MovieTags   map[int]model.MovieTags
+	// Read-only tables of the dataset, served in place from the snapshot when there is one
+	Users       util.RatingTable
+	MovieTitles util.TitleTable
+	Movies      util.RatingTable
+	MovieTags   util.TagTable
+	Rules       []model.AssociationRule
+	// Precomputed item-item neighbors keyed by similarity metric
+	Neighbors map[string]model.MovieNeighbors
+	// Movies whose ratings were mutated since preprocess, which the rules and the neighbors of other movies don't account for
+	MutatedMovies map[int]bool
+	// Per-entity statistics of the loaded data, rebuilt whenever the data is reloaded
+	Index *util.DatasetIndex
+	// Rating scale of the quantized snapshots, if they were loaded
+	Scale *model.RatingScale
 }
 
 type ResponseTemplate struct {
@@ -29,12 +42,26 @@ type ResponseTemplate struct {
 	Data       []ResponseData `json:"data"`
 	Message    string         `json:"message"`
 	MetaInfo   string         `json:"metaInfo"`
+	Fallback   string         `json:"fallback"`
+}
+
+// Body of the error responses of every endpoint
+type ErrorResponse struct {
+	Status     string              `json:"status"`
+	StatusCode int                 `json:"statusCode"`
+	Message    string              `json:"message"`
+	Errors     []config.FieldError `json:"errors"`
 }
 
 type ResponseData struct {
 	MovieID    int     `json:"movieID"`
 	MovieTitle string  `json:"movieTitle"`
 	Result     float64 `json:"result"`
+	// Association rule metrics, only set by the assoc algorithm
+	Antecedent []int   `json:"antecedent,omitempty"`
+	Support    float64 `json:"support,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Lift       float64 `json:"lift,omitempty"`
 }
 
 var (
@@ -44,15 +71,20 @@ var (
 	k = 128
 	// Main struct to store data
 	data = Data{
-		Users:       make(map[int]model.User, 0),
-		MovieTitles: make(map[int]model.MovieTitle, 0),
-		Movies:      make(map[int]model.Movie, 0),
-		MovieTags:   make(map[int]model.MovieTags, 0),
-	}
-	// Indicator of whether the webserver is using a portion of the original dataset
-	// This is useful to be able to reset the dataset to the original state after
-	// a resuest has been made through the UI using the maxRecords functionality.
-	limitedDataset = false
+		Rules:     make([]model.AssociationRule, 0),
+		Neighbors: make(map[string]model.MovieNeighbors, 0),
+		// Movies whose ratings were mutated since preprocess
+		MutatedMovies: make(map[int]bool, 0),
+	}
+	// LSH parameters of approximate Web-Server requests, matching the CLI defaults
+	defaultBands = 50
+	defaultRows  = 2
+	// Non-personalized algorithm used by the Web-Server when the requested input doesn't exist
+	fallbackAlgorithm = "top-rated"
+	// Whether ratings are loaded from the quantized snapshots or stored as steps of their detected scale
+	quantize = false
+	// Memory-mapped snapshot of the dataset, or nil to decode the gob files instead
+	snapshot *util.Snapshot
 )
 
 func main() {
@@ -61,8 +93,20 @@ func main() {
 		log.Fatalf("Configuration error: %v", err)
 		return
 	}
-	if cfg.WebServer {
+	quantize = cfg.Quantize
+	openSnapshot(cfg.DataDir)
+	if cfg.Compact {
+		if err := compactData(cfg.DataDir); err != nil {
+			log.Fatalf("Compaction failed: %v", err)
+		}
+	} else if cfg.Import != "" {
+		if err := importProfile(cfg.DataDir, cfg.Import, cfg.ImportOutput); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+	} else if cfg.WebServer {
 		startWebServer(cfg.DataDir)
+	} else if cfg.Pairwise() {
+		performPairwise(&cfg)
 	} else {
 		// CLI mode
 		var m runtime.MemStats
@@ -70,26 +114,56 @@ func main() {
 		// Load only the files that are necessary for the selected algorithm to save time
 		switch cfg.Algorithm {
 		case "user":
-			util.LoadData(&data.MovieTitles, cfg.DataDir+"movieTitles.gob", cfg.MaxTitles)
-			util.LoadData(&data.Users, cfg.DataDir+"users.gob", cfg.MaxUsers)
+			loadMovieTitles(cfg.DataDir, cfg.MaxTitles)
+			loadUsers(cfg.DataDir, cfg.MaxUsers)
+		case "bpr":
+			loadUsers(cfg.DataDir, cfg.MaxUsers)
+		case "slopeone":
+			loadUsers(cfg.DataDir, cfg.MaxUsers)
+			loadMovies(cfg.DataDir, -1)
+		case "popular", "top-rated", "trending":
+			loadMovies(cfg.DataDir, cfg.MaxMovies)
+		case "p3alpha", "rp3beta":
+			loadUsers(cfg.DataDir, cfg.MaxUsers)
+			loadMovies(cfg.DataDir, cfg.MaxMovies)
+		case "assoc":
+			loadMovieTitles(cfg.DataDir, -1)
+			util.LoadData(&data.Rules, cfg.DataDir+"rules.gob")
 		case "item":
-			util.LoadData(&data.Movies, cfg.DataDir+"movies.gob", cfg.MaxMovies)
+			loadMovies(cfg.DataDir, cfg.MaxMovies)
+			loadNeighbors(cfg.DataDir, cfg.Similarity)
 		case "tag":
-			util.LoadData(&data.MovieTags, cfg.DataDir+"tags.gob", cfg.MaxTags)
+			loadMovieTags(cfg.DataDir, cfg.MaxTags)
 		case "title":
-			util.LoadData(&data.MovieTitles, cfg.DataDir+"movieTitles.gob", cfg.MaxTitles)
+			loadMovieTitles(cfg.DataDir, cfg.MaxTitles)
 		case "hybrid":
-			util.LoadData(&data.MovieTitles, cfg.DataDir+"movieTitles.gob", cfg.MaxTitles)
-			util.LoadData(&data.Movies, cfg.DataDir+"movies.gob", cfg.MaxMovies)
-			util.LoadData(&data.MovieTags, cfg.DataDir+"tags.gob", cfg.MaxTags)
+			loadMovieTitles(cfg.DataDir, cfg.MaxTitles)
+			loadMovies(cfg.DataDir, cfg.MaxMovies)
+			loadMovieTags(cfg.DataDir, cfg.MaxTags)
+			loadNeighbors(cfg.DataDir, cfg.Similarity)
+		}
+		// Filters need the ratings, titles and tags of every movie the algorithm may recommend
+		if cfg.Filters.Active() {
+			loadFilterData(cfg.DataDir)
 		}
-		err := checkRequestFeasibility(cfg.Algorithm, cfg.Input)
+		if config.UsesSimilarity(cfg.Algorithm) {
+			data.Index = buildIndex(cfg.NumThreads, cfg.MaxRecords)
+		}
+		// Limited runs only use the records of the preprocessed files, like the limited requests of the Web-Server
+		if cfg.MaxRecords == -1 {
+			replayMutations(cfg.DataDir)
+		}
+		quantizeRatings()
+		err := checkRequestFeasibility(&cfg, &data)
 		if err != "" {
 			fmt.Println(err)
 			return
 		}
-		ratingForecasts, relevantMovies := performRecommendation(&cfg, &data)
-		printRecommendations(&cfg, ratingForecasts, relevantMovies)
+		ratingForecasts, relevantMovies, rules := performRecommendation(&cfg, &data)
+		printRecommendations(&cfg, ratingForecasts, relevantMovies, rules)
+		if cfg.Approximate && cfg.Recall {
+			printRecall(&cfg, ratingForecasts, relevantMovies)
+		}
 		fmt.Printf("Execution Time: %s\n", time.Since(startTime))
 		runtime.ReadMemStats(&m)
 		fmt.Printf("HeapAlloc: %d MiB\n", m.HeapAlloc/(1024*1024))
@@ -98,143 +172,581 @@ func main() {
 
 func startWebServer(dataDir string) {
 	fmt.Println("Starting Web-Server...")
-	util.LoadData(&data.Users, dataDir+"users.gob")
-	util.LoadData(&data.MovieTitles, dataDir+"movieTitles.gob")
-	util.LoadData(&data.Movies, dataDir+"movies.gob")
-	util.LoadData(&data.MovieTags, dataDir+"tags.gob")
+	loadUsers(dataDir, -1)
+	loadMovieTitles(dataDir, -1)
+	loadMovies(dataDir, -1)
+	loadMovieTags(dataDir, -1)
+	// Association rules are optional since older preprocessed datasets don't include them
+	if _, err := os.Stat(dataDir + "rules.gob"); err == nil {
+		util.LoadData(&data.Rules, dataDir+"rules.gob")
+	}
+	for _, similarity := range config.SimilarityMetrics {
+		loadNeighbors(dataDir, similarity)
+	}
+	data.Index = buildIndex(numThreads, -1)
+	replayMutations(dataDir)
+	quantizeRatings()
+	openMutationLog(dataDir)
+	// Build the LSH indexes up front so that approximate requests don't pay for it
+	recommenders.BuildLSHIndexes(&config.Config{MaxRecords: -1, NumThreads: numThreads, Bands: defaultBands, Rows: defaultRows}, &data.Users, &data.Movies, &data.MovieTags)
 	// Register API endpoint handlers
 	http.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.Dir(os.Getenv("PWD")+"/ui"))))
 	http.HandleFunc("/recommend", func(w http.ResponseWriter, r *http.Request) {
 		handleRecommendationRequest(w, r, dataDir)
 	})
+	registerAPI()
+	http.HandleFunc("/", handleNotFound)
 	// Start the Web-Server
 	fmt.Println("Web-Server UI is available on http://localhost:8080/ui/")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
-// Main handler of the Web-Server to
+// Main handler of the Web-Server to serve recommendation requests
 func handleRecommendationRequest(w http.ResponseWriter, r *http.Request, dataDir string) {
 	startTime := time.Now()
+	defer recoverRequest(w)
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
 	// Prepare default response
 	response := ResponseTemplate{
 		Status:     "success",
-		StatusCode: 200,
+		StatusCode: http.StatusOK,
 		Data:       []ResponseData{},
 		Message:    "",
 	}
-	// Retrieve & parse query parameters. Also update the dataset if necessary
-	queryParams := r.URL.Query()
-	recommendations, _ := strconv.Atoi(queryParams["recommendations"][0])
-	similarity := queryParams["similarity"][0]
-	algorithm := queryParams["algorithm"][0]
-	input, _ := strconv.Atoi(queryParams["input"][0])
-	maxRecords := -1
-	if _, exists := queryParams["maxRecords"]; exists {
-		maxRecords, _ = strconv.Atoi(queryParams["maxRecords"][0])
-		reloadData(algorithm, maxRecords, dataDir)
-		limitedDataset = true
-	}
-	// Create a custom configuration object based on query params to perform recommendation
-	cfg := config.Config{
-		Recommendations: recommendations,
-		Similarity:      similarity,
-		Algorithm:       algorithm,
-		Input:           input,
-		MaxRecords:      maxRecords,
-	}
-	fmt.Printf("Received request with parameters: -n=%d -s=%s -a=%s -i=%d -r=%d\n",
-		recommendations, similarity, algorithm, input, maxRecords)
-	err := checkRequestFeasibility(cfg.Algorithm, cfg.Input)
-	if err == "" {
-		// Request is feasible, proceed to recommendation
-		ratingForecasts, relevantMovies := performRecommendation(&cfg, &data)
-		// Fill the response content based on the type of the recommendation results
-		if len(ratingForecasts) != 0 {
-			for _, movieRating := range ratingForecasts {
-				response.Data = append(response.Data, ResponseData{
-					MovieID:    movieRating.MovieID,
-					MovieTitle: data.MovieTitles[movieRating.MovieID].Title,
-					Result:     math.Trunc((float64(movieRating.Rating) * 100)) / 100,
-				})
-			}
-		} else if len(relevantMovies) != 0 {
-			for _, relevantMovie := range relevantMovies {
-				response.Data = append(response.Data, ResponseData{
-					MovieID:    relevantMovie.MovieID,
-					MovieTitle: data.MovieTitles[relevantMovie.MovieID].Title,
-					Result:     math.Trunc((relevantMovie.Similarity * 100000)) / 100000,
-				})
-			}
-			// Additional info for the requested movie
-			response.MetaInfo = data.MovieTitles[cfg.Input].Title
-		} else {
-			response.Message = fmt.Sprintf("No relevant movies found for user %d. Try using another algorithm.", cfg.Input)
+	// Retrieve, parse & validate query parameters
+	cfg, fieldErrors := parseRecommendationQuery(r.URL.Query())
+	if len(fieldErrors) > 0 {
+		writeErrors(w, validationStatus(fieldErrors), fieldErrors...)
+		return
+	}
+	dataMu.RLock()
+	defer dataMu.RUnlock()
+	results := serveRecommendation(cfg)
+	cfg, requestData := results.cfg, results.data
+	setStaleDataHeader(w, &cfg)
+	ratingForecasts, relevantMovies, rules := results.ratingForecasts, results.relevantMovies, results.rules
+	response.Fallback = results.fallback
+	// Fill the response content based on the type of the recommendation results
+	if len(rules) != 0 {
+		for _, rule := range rules {
+			response.Data = append(response.Data, ResponseData{
+				MovieID:    rule.Consequent,
+				MovieTitle: requestData.MovieTitles.Title(rule.Consequent),
+				Result:     math.Trunc((rule.Confidence * 100000)) / 100000,
+				Antecedent: rule.Antecedent,
+				Support:    math.Trunc((rule.Support * 100000)) / 100000,
+				Confidence: math.Trunc((rule.Confidence * 100000)) / 100000,
+				Lift:       math.Trunc((rule.Lift * 100000)) / 100000,
+			})
+		}
+		response.MetaInfo = getSeedTitles(cfg.Seeds, requestData)
+	} else if len(ratingForecasts) != 0 {
+		// Random walk scores are probabilities and need more decimals than ratings
+		precision := 100.0
+		if cfg.Algorithm == "p3alpha" || cfg.Algorithm == "rp3beta" {
+			precision = 100000.0
+		}
+		for _, movieRating := range ratingForecasts {
+			response.Data = append(response.Data, ResponseData{
+				MovieID:    movieRating.MovieID,
+				MovieTitle: requestData.MovieTitles.Title(movieRating.MovieID),
+				Result:     math.Trunc((float64(movieRating.Rating) * precision)) / precision,
+			})
+		}
+	} else if len(relevantMovies) != 0 {
+		for _, relevantMovie := range relevantMovies {
+			response.Data = append(response.Data, ResponseData{
+				MovieID:    relevantMovie.MovieID,
+				MovieTitle: requestData.MovieTitles.Title(relevantMovie.MovieID),
+				Result:     math.Trunc((relevantMovie.Similarity * 100000)) / 100000,
+			})
 		}
+		// Additional info for the requested movie
+		response.MetaInfo = requestData.MovieTitles.Title(cfg.Input)
 	} else {
-		response.Message = err
-		fmt.Println("Request is not feasible:", err)
+		response.Message = fmt.Sprintf("No relevant movies found for user %d. Try using another algorithm.", cfg.Input)
 	}
 	// Send the response
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 	fmt.Printf("Reponse sent in: %s\n", time.Since(startTime))
-	// Reset dataset to the original state
-	if limitedDataset {
-		reloadData(algorithm, -1, dataDir)
-		limitedDataset = false
+}
+
+// Results of a Web-Server recommendation request, shared by every version of the API
+type recommendationResults struct {
+	// Configuration of the results, whose algorithm is the fallback one if the input wasn't found
+	cfg config.Config
+	// Read-only view of the dataset the results were computed on
+	data            *Data
+	fallback        string
+	ratingForecasts []model.Rating
+	relevantMovies  []model.SimilarMovie
+	rules           []model.AssociationRule
+}
+
+// Performs a validated recommendation request of the Web-Server
+func serveRecommendation(cfg config.Config) recommendationResults {
+	// Read-only view of the dataset for this request, so that concurrent requests never modify the shared one
+	results := recommendationResults{data: limitData(cfg.Algorithm, cfg.MaxRecords)}
+	fmt.Printf("Received request with parameters: -n=%d -s=%s -a=%s -i=%d -r=%d -implicit=%t -t=%.1f -approx=%t\n",
+		cfg.Recommendations, cfg.Similarity, cfg.Algorithm, cfg.Input, cfg.MaxRecords, cfg.Implicit, cfg.Threshold, cfg.Approximate)
+	if err := checkRequestFeasibility(&cfg, results.data); err != "" {
+		// Fall back to non-personalized recommendations for unknown inputs
+		fmt.Printf("Request is not feasible: %s Falling back to '%s'.\n", err, fallbackAlgorithm)
+		cfg.Algorithm = fallbackAlgorithm
+		// The unknown input may be a movie, which the fallback would take for the user with the same ID
+		cfg.Input, cfg.InputType, cfg.Seeds, cfg.NegativeSeeds, cfg.SeedWeights = 0, "user", nil, nil, nil
+		results.fallback = fallbackAlgorithm
+	}
+	results.ratingForecasts, results.relevantMovies, results.rules = performRecommendation(&cfg, results.data)
+	results.cfg = cfg
+	return results
+}
+
+/*
+Parses the query parameters of a recommendation request into a configuration, falling back to the
+CLI defaults for the optional ones. Returns an InvalidParameter error for every malformed parameter
+or, if all of them are well-formed, the errors of the configuration validation.
+*/
+func parseRecommendationQuery(queryParams url.Values) (config.Config, []config.FieldError) {
+	parser := queryParser{values: queryParams}
+	cfg := config.Config{
+		Recommendations: parser.Int("recommendations", 0),
+		Similarity:      parser.String("similarity", ""),
+		Algorithm:       parser.String("algorithm", ""),
+		Input:           parser.Int("input", 0),
+		MaxRecords:      parser.Int("maxRecords", -1),
+		K:               k,
+		NumThreads:      numThreads,
+		Implicit:        parser.Bool("implicit", false),
+		Threshold:       parser.Float("threshold", 0.0),
+		Prior:           parser.Float("prior", 10.0),
+		Window:          parser.Int("window", 30),
+		InputType:       parser.String("inputType", "user"),
+		Alpha:           parser.Float("alpha", 1.0),
+		Beta:            parser.Float("beta", 0.5),
+		Seeds:           parser.IDList("seeds", nil),
+		NegativeSeeds:   parser.IDList("negativeSeeds", nil),
+		Aggregation:     parser.String("aggregation", "mean"),
+		Filters: config.ResultFilters{
+			MinRatings:    parser.Int("minRatings", 0),
+			Allow:         parser.IDList("allow", nil),
+			Deny:          parser.IDList("deny", nil),
+			MinYear:       parser.Int("minYear", 0),
+			MaxYear:       parser.Int("maxYear", 0),
+			IncludeTags:   parser.List("includeTags"),
+			ExcludeTags:   parser.List("excludeTags"),
+			IncludeGenres: parser.List("includeGenres"),
+			ExcludeGenres: parser.List("excludeGenres"),
+		},
+		MinSupport:    parser.Float("minSupport", 0.0),
+		MinConfidence: parser.Float("minConfidence", 0.0),
+		Approximate:   parser.Bool("approximate", false),
+		Bands:         parser.Int("bands", defaultBands),
+		Rows:          parser.Int("rows", defaultRows),
+	}
+	if len(parser.errors) > 0 {
+		return cfg, parser.errors
+	}
+	cfg.DefaultSeeds()
+	return cfg, cfg.Validate()
+}
+
+// Parser of query parameters that collects an error for every malformed one instead of stopping at the first
+type queryParser struct {
+	values url.Values
+	errors []config.FieldError
+}
+
+// Returns the value of the $field parameter, or $fallback if it's missing or empty
+func (parser *queryParser) String(field string, fallback string) string {
+	if value := parser.values.Get(field); value != "" {
+		return value
 	}
+	return fallback
+}
+
+func (parser *queryParser) Int(field string, fallback int) int {
+	return parseQueryValue(parser, field, fallback, "an integer", strconv.Atoi)
+}
+
+func (parser *queryParser) Float(field string, fallback float64) float64 {
+	return parseQueryValue(parser, field, fallback, "a number", func(value string) (float64, error) {
+		number, err := strconv.ParseFloat(value, 64)
+		if err == nil && (math.IsNaN(number) || math.IsInf(number, 0)) {
+			err = strconv.ErrRange
+		}
+		return number, err
+	})
+}
+
+func (parser *queryParser) Bool(field string, fallback bool) bool {
+	return parseQueryValue(parser, field, fallback, "a boolean", strconv.ParseBool)
 }
 
-// Reload data mechanism in case the user requests limited dataset through the UI
-func reloadData(algorithm string, maxRecords int, dataDir string) {
+func (parser *queryParser) IDList(field string, fallback []int) []int {
+	return parseQueryValue(parser, field, fallback, "a comma separated list of IDs", config.ParseIDList)
+}
+
+func (parser *queryParser) List(field string) []string {
+	return config.ParseList(parser.values.Get(field))
+}
+
+// Parses the $field parameter with $parse, recording an error that it must be $kind if it's malformed
+func parseQueryValue[T any](parser *queryParser, field string, fallback T, kind string, parse func(string) (T, error)) T {
+	value := parser.values.Get(field)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		parser.errors = append(parser.errors, config.FieldError{
+			Code: config.InvalidParameter, Field: field, Message: fmt.Sprintf("'%s' must be %s, got '%s'", field, kind, value),
+		})
+		return fallback
+	}
+	return parsed
+}
+
+// Returns the HTTP status of validation errors: 400 for missing or malformed parameters and 422 for unaccepted values
+func validationStatus(fieldErrors []config.FieldError) int {
+	for _, err := range fieldErrors {
+		if err.Code != config.InvalidValue {
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// Sends an error response with the $status HTTP status and the structured $errors
+func writeErrors(w http.ResponseWriter, status int, errors ...config.FieldError) {
+	messages := make([]string, 0, len(errors))
+	for _, err := range errors {
+		messages = append(messages, err.Message)
+	}
+	response := ErrorResponse{
+		Status:     "error",
+		StatusCode: status,
+		Message:    strings.Join(messages, ". "),
+		Errors:     errors,
+	}
+	writeJSON(w, status, response)
+}
+
+// Sends a 500 error response if the request handler panicked, instead of dropping the connection
+func recoverRequest(w http.ResponseWriter) {
+	if err := recover(); err != nil {
+		fmt.Printf("Request failed: %v\n", err)
+		writeErrors(w, http.StatusInternalServerError, config.FieldError{Code: "internal_error", Message: "The request could not be completed"})
+	}
+}
+
+// Handler of every path without an endpoint
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	writeErrors(w, http.StatusNotFound, config.FieldError{Code: "not_found", Message: fmt.Sprintf("Path '%s' was not found", r.URL.Path)})
+}
+
+/*
+Returns a view of the shared dataset whose part used by $algorithm is limited to its $maxRecords records with
+the lowest IDs, like the files the CLI loads with -r maxRecords. The view shares the rows of its tables, and every
+other part, with the shared dataset, which is never modified, so it must be treated as read-only.
+*/
+func limitData(algorithm string, maxRecords int) *Data {
+	view := data
+	if maxRecords == -1 {
+		return &view
+	}
+	// Re-index the limited part only, without touching the shared index
+	view.Index = data.Index.Clone()
 	switch algorithm {
-	case "user":
-		util.LoadData(&data.Users, dataDir+"users.gob", maxRecords)
-	case "item", "hybrid":
-		util.LoadData(&data.Movies, dataDir+"movies.gob", maxRecords)
+	case "user", "bpr", "slopeone", "p3alpha", "rp3beta":
+		view.Users = data.Users.Limit(maxRecords)
+		view.Index.IndexUsers(&view.Users)
+	case "item", "hybrid", "popular", "top-rated", "trending":
+		// The index holds nothing about the ratings of movies
+		view.Movies = data.Movies.Limit(maxRecords)
 	case "tag":
-		util.LoadData(&data.MovieTags, dataDir+"tags.gob", maxRecords)
+		view.MovieTags = data.MovieTags.Limit(maxRecords)
+		view.Index.IndexMovieTags(&view.MovieTags)
 	case "title":
-		util.LoadData(&data.MovieTitles, dataDir+"movieTitles.gob", maxRecords)
+		view.MovieTitles = data.MovieTitles.Limit(maxRecords)
+		view.Index.IndexMovieTitles(&view.MovieTitles)
 	}
+	return &view
 }
 
 // The core function of the recommender both when using the CLI or the UI interface
-func performRecommendation(cfg *config.Config, data *Data) ([]model.Rating, []model.SimilarMovie) {
-	ratingForecasts := make([]model.Rating, 0, cfg.Recommendations)
-	relevantMovies := make([]model.SimilarMovie, 0, cfg.Recommendations)
+func performRecommendation(cfg *config.Config, data *Data) ([]model.Rating, []model.SimilarMovie, []model.AssociationRule) {
+	if cfg.Filters.Active() {
+		// Rank every movie, then keep the top ones that pass the filters
+		unfilteredCfg := *cfg
+		unfilteredCfg.Filters, unfilteredCfg.Recommendations = config.ResultFilters{}, -1
+		ratingForecasts, relevantMovies, rules := performRecommendation(&unfilteredCfg, data)
+		keep := recommenders.NewResultFilter(&cfg.Filters, &data.Movies, &data.MovieTitles, &data.MovieTags)
+		return recommenders.FilterResults(ratingForecasts, func(rating model.Rating) int { return rating.MovieID }, keep, cfg.Recommendations),
+			recommenders.FilterResults(relevantMovies, func(movie model.SimilarMovie) int { return movie.MovieID }, keep, cfg.Recommendations),
+			recommenders.FilterResults(rules, func(rule model.AssociationRule) int { return rule.Consequent }, keep, cfg.Recommendations)
+	}
+	ratingForecasts := make([]model.Rating, 0, max(cfg.Recommendations, 0))
+	relevantMovies := make([]model.SimilarMovie, 0, max(cfg.Recommendations, 0))
+	rules := make([]model.AssociationRule, 0, max(cfg.Recommendations, 0))
 	switch cfg.Algorithm {
 	case "user":
-		ratingForecasts = recommenders.RecommendBasedOnUser(cfg, &data.Users, &data.MovieTitles)
+		ratingForecasts = recommenders.RecommendBasedOnUser(cfg, &data.Users, &data.MovieTitles, data.Index)
 	case "item":
-		ratingForecasts = recommenders.RecommendBasedOnItem(cfg, &data.Movies)
-	case "tag":
-		relevantMovies = recommenders.RecommendBasedOnTag(cfg, &data.MovieTags)
-	case "title":
-		relevantMovies = recommenders.RecommendBasedOnTitle(cfg, &data.MovieTitles)
-	case "hybrid":
-		relevantMovies = recommenders.RecommendHybrid(cfg, &data.MovieTitles, &data.Movies, &data.MovieTags)
+		ratingForecasts = recommenders.RecommendBasedOnItem(cfg, &data.Movies, getNeighbors(cfg, data), data.Index)
+	case "bpr":
+		ratingForecasts = recommenders.RecommendBasedOnBPR(cfg, &data.Users)
+	case "slopeone":
+		ratingForecasts = recommenders.RecommendBasedOnSlopeOne(cfg, &data.Users, &data.Movies)
+	case "popular":
+		ratingForecasts = recommenders.RecommendPopular(cfg, &data.Movies)
+	case "top-rated":
+		ratingForecasts = recommenders.RecommendTopRated(cfg, &data.Movies)
+	case "trending":
+		ratingForecasts = recommenders.RecommendTrending(cfg, &data.Movies)
+	case "p3alpha", "rp3beta":
+		if cfg.InputIsMovie() {
+			relevantMovies = recommenders.RecommendSimilarByRandomWalk(cfg, &data.Users, &data.Movies)
+		} else {
+			ratingForecasts = recommenders.RecommendBasedOnRandomWalk(cfg, &data.Users, &data.Movies)
+		}
+	case "tag", "title", "hybrid":
+		recommend := func(cfg *config.Config) []model.SimilarMovie {
+			switch cfg.Algorithm {
+			case "tag":
+				return recommenders.RecommendBasedOnTag(cfg, &data.MovieTags, data.Index)
+			case "title":
+				return recommenders.RecommendBasedOnTitle(cfg, &data.MovieTitles, data.Index)
+			}
+			return recommenders.RecommendHybrid(cfg, &data.MovieTitles, &data.Movies, &data.MovieTags, getNeighbors(cfg, data), data.Index)
+		}
+		if cfg.UsesSeeds() {
+			relevantMovies = recommenders.RecommendSimilarToSeeds(cfg, recommend)
+		} else {
+			relevantMovies = recommend(cfg)
+		}
+	case "assoc":
+		rules = recommenders.RecommendBasedOnAssociation(cfg, &data.Rules)
+	}
+	return ratingForecasts, relevantMovies, rules
+}
+
+// Maps the snapshot of the dataset in memory if preprocess produced one, falling back to the gob files otherwise
+func openSnapshot(dataDir string) {
+	if _, err := os.Stat(dataDir + "snapshot.bin"); err != nil {
+		return
+	}
+	opened, err := util.OpenSnapshot(dataDir + "snapshot.bin")
+	if err != nil {
+		fmt.Printf("Failed to open snapshot, loading the gob files instead: %s\n", err)
+		return
+	}
+	snapshot = opened
+}
+
+// Loads the users, from their quantized snapshot in quantized mode if preprocess produced one
+func loadUsers(dataDir string, maxRecords int) {
+	if _, err := os.Stat(dataDir + "users.q.gob"); quantize && err == nil {
+		scale := util.LoadQuantizedRatings(&data.Users, dataDir+"users.q.gob", maxRecords)
+		data.Scale = &scale
+		return
+	}
+	if snapshot != nil {
+		data.Users = snapshot.Users(maxRecords)
+		return
+	}
+	users := make(map[int]model.User)
+	util.LoadData(&users, dataDir+"users.gob", maxRecords)
+	data.Users = util.NewUserTable(users)
+}
+
+// Loads the movies, from their quantized snapshot in quantized mode if preprocess produced one
+func loadMovies(dataDir string, maxRecords int) {
+	if _, err := os.Stat(dataDir + "movies.q.gob"); quantize && err == nil {
+		scale := util.LoadQuantizedRatings(&data.Movies, dataDir+"movies.q.gob", maxRecords)
+		data.Scale = &scale
+		return
+	}
+	if snapshot != nil {
+		data.Movies = snapshot.Movies(maxRecords)
+		return
+	}
+	movies := make(map[int]model.Movie)
+	util.LoadData(&movies, dataDir+"movies.gob", maxRecords)
+	data.Movies = util.NewMovieTable(movies)
+}
+
+func loadMovieTitles(dataDir string, maxRecords int) {
+	if snapshot != nil {
+		data.MovieTitles = snapshot.MovieTitles(maxRecords)
+		return
+	}
+	movieTitles := make(map[int]model.MovieTitle)
+	util.LoadData(&movieTitles, dataDir+"movieTitles.gob", maxRecords)
+	data.MovieTitles = util.NewTitleTable(movieTitles)
+}
+
+func loadMovieTags(dataDir string, maxRecords int) {
+	if snapshot != nil {
+		data.MovieTags = snapshot.MovieTags(maxRecords)
+		return
+	}
+	movieTags := make(map[int]model.MovieTags)
+	util.LoadData(&movieTags, dataDir+"tags.gob", maxRecords)
+	data.MovieTags = util.NewTagTable(movieTags)
+}
+
+// Loads the movies, titles and tags the algorithm didn't load, for the result filters
+func loadFilterData(dataDir string) {
+	if data.Movies.Len() == 0 {
+		loadMovies(dataDir, -1)
+	}
+	if data.MovieTitles.Len() == 0 {
+		loadMovieTitles(dataDir, -1)
+	}
+	if data.MovieTags.Len() == 0 {
+		loadMovieTags(dataDir, -1)
+	}
+}
+
+/*
+Builds the index of the loaded data. When every rating of the users and movies is loaded, the raters of every movie
+are the keys of its ratings, so they are indexed in place instead of being gathered from the users.
+*/
+func buildIndex(threads int, maxRecords int) *util.DatasetIndex {
+	if maxRecords != -1 || data.Users.Len() == 0 || data.Movies.Len() == 0 {
+		return util.BuildDatasetIndex(&data.Users, &data.MovieTags, &data.MovieTitles, threads)
+	}
+	index := util.BuildDatasetIndex(&util.RatingTable{}, &data.MovieTags, &data.MovieTitles, threads)
+	index.IndexRaters(&data.Movies)
+	return index
+}
+
+/*
+Stores the loaded ratings as steps of their detected scale in quantized mode, unless they were loaded from the
+quantized snapshots or don't fit in 256 steps. Mutations are replayed beforehand so that their ratings fit the scale too.
+*/
+func quantizeRatings() {
+	if !quantize || data.Scale != nil || data.Users.TotalRatings()+data.Movies.TotalRatings() == 0 {
+		return
+	}
+	scale, ok := util.DetectRatingScale(&data.Users, &data.Movies)
+	if !ok {
+		fmt.Println("Ratings don't fit in a scale of 256 steps, keeping them as floats.")
+		return
+	}
+	data.Users, data.Movies = data.Users.Quantize(scale), data.Movies.Quantize(scale)
+	data.Scale = &scale
+}
+
+// Loads the neighbor snapshot of a similarity metric if preprocess produced one
+func loadNeighbors(dataDir string, similarity string) {
+	neighborsFile := dataDir + "neighbors-" + similarity + ".gob"
+	if _, err := os.Stat(neighborsFile); err != nil {
+		return
 	}
-	return ratingForecasts, relevantMovies
+	neighbors := make(model.MovieNeighbors, 0)
+	util.LoadData(&neighbors, neighborsFile)
+	data.Neighbors[similarity] = neighbors
+}
+
+// Returns the neighbor snapshot of the requested metric, or nil if there is none or the dataset is limited
+func getNeighbors(cfg *config.Config, data *Data) *model.MovieNeighbors {
+	neighbors, exists := data.Neighbors[cfg.Similarity]
+	if !exists || cfg.MaxRecords != -1 {
+		return nil
+	}
+	return &neighbors
+}
+
+// Reruns the recommendation with exact search and prints the share of its movies that approximate search found
+func printRecall(cfg *config.Config, ratingForecasts []model.Rating, relevantMovies []model.SimilarMovie) {
+	exactCfg := *cfg
+	exactCfg.Approximate = false
+	exactForecasts, exactMovies, _ := performRecommendation(&exactCfg, &data)
+	approximateIDs, exactIDs := make([]int, 0, cfg.Recommendations), make([]int, 0, cfg.Recommendations)
+	for _, movieRating := range ratingForecasts {
+		approximateIDs = append(approximateIDs, movieRating.MovieID)
+	}
+	for _, relevantMovie := range relevantMovies {
+		approximateIDs = append(approximateIDs, relevantMovie.MovieID)
+	}
+	for _, movieRating := range exactForecasts {
+		exactIDs = append(exactIDs, movieRating.MovieID)
+	}
+	for _, relevantMovie := range exactMovies {
+		exactIDs = append(exactIDs, relevantMovie.MovieID)
+	}
+	fmt.Printf("Recall@%d of approximate against exact search: %.2f\n", cfg.Recommendations, algorithms.Recall(approximateIDs, exactIDs))
 }
 
 // Prints results if running in CLI mode
-func printRecommendations(cfg *config.Config, ratingForecasts []model.Rating, relevantMovies []model.SimilarMovie) {
+func printRecommendations(cfg *config.Config, ratingForecasts []model.Rating, relevantMovies []model.SimilarMovie, rules []model.AssociationRule) {
 	movieTitles := make(map[int]model.MovieTitle)
 	util.LoadData(&movieTitles, cfg.DataDir+"movieTitles.gob")
-	switch cfg.Algorithm {
-	case "user", "item":
+	switch {
+	case cfg.Algorithm == "assoc":
+		if len(rules) == 0 {
+			fmt.Printf("No association rules apply to movies %v. Try using another algorithm.\n", cfg.Seeds)
+			break
+		}
+		fmt.Printf("People who rated %s also rated:\n", getSeedTitles(cfg.Seeds, &data))
+		for i, rule := range rules {
+			fmt.Printf("%d: ID: %d, Title: %s => confidence: %.5f, support: %.5f, lift: %.5f (rule: %v => %d)\n",
+				i+1, rule.Consequent, movieTitles[rule.Consequent].Title, rule.Confidence, rule.Support, rule.Lift, rule.Antecedent, rule.Consequent,
+			)
+		}
+	case !config.UsesInput(cfg.Algorithm):
 		if len(ratingForecasts) == 0 {
-			fmt.Printf("No movie recommendations for user %d. Try using another algorithm.\n", cfg.Input)
+			fmt.Printf("No %s movies found in current dataset.\n", cfg.Algorithm)
 			break
 		}
-		fmt.Printf("Top movie recommendations for user %d are:\n", cfg.Input)
+		fmt.Printf("Top %s movies are:\n", cfg.Algorithm)
 		for i, recommendation := range ratingForecasts {
 			fmt.Printf("%d: ID: %d, Title: %s => %.2f\n",
 				i+1, recommendation.MovieID, movieTitles[recommendation.MovieID].Title, recommendation.Rating,
 			)
 		}
-	case "tag", "title", "hybrid":
+	case cfg.Profile != nil && cfg.InputIsMovie():
+		if len(relevantMovies) == 0 {
+			fmt.Println("No relevant movies found for the profile. Try using another algorithm.")
+			break
+		}
+		fmt.Println("Top movie recommendations for the profile are:")
+		for i, recommendation := range relevantMovies {
+			fmt.Printf("%d: ID: %d, Title: %s => %.5f\n", i+1, recommendation.MovieID, movieTitles[recommendation.MovieID].Title, recommendation.Similarity)
+		}
+	case !cfg.InputIsMovie():
+		subject := fmt.Sprintf("user %d", cfg.Input)
+		if cfg.Profile != nil {
+			subject = "the profile"
+		}
+		if len(ratingForecasts) == 0 {
+			fmt.Printf("No movie recommendations for %s. Try using another algorithm.\n", subject)
+			break
+		}
+		fmt.Printf("Top movie recommendations for %s are:\n", subject)
+		// Random walk scores are probabilities and need more decimals than ratings
+		format := "%d: ID: %d, Title: %s => %.2f\n"
+		if cfg.Algorithm == "p3alpha" || cfg.Algorithm == "rp3beta" {
+			format = "%d: ID: %d, Title: %s => %.5f\n"
+		}
+		for i, recommendation := range ratingForecasts {
+			fmt.Printf(format, i+1, recommendation.MovieID, movieTitles[recommendation.MovieID].Title, recommendation.Rating)
+		}
+	case cfg.UsesSeeds():
+		if len(relevantMovies) == 0 {
+			fmt.Printf("No relevant movies found for movies %v. Try using another algorithm.\n", cfg.Seeds)
+			break
+		}
+		fmt.Printf("Top movie recommendations for movies %s are:\n", getSeedTitles(cfg.Seeds, &Data{MovieTitles: util.NewTitleTable(movieTitles)}))
+		for i, recommendation := range relevantMovies {
+			fmt.Printf("%d: ID: %d, Title: %s => %.5f\n", i+1, recommendation.MovieID, movieTitles[recommendation.MovieID].Title, recommendation.Similarity)
+		}
+	default:
 		if len(relevantMovies) == 0 {
 			fmt.Printf("No relevant movies found for movie %d. Try using another algorithm.\n", cfg.Input)
 			break
@@ -248,31 +760,67 @@ func printRecommendations(cfg *config.Config, ratingForecasts []model.Rating, re
 
 // Checks if the request can be satisfied for the given input.
 // Returns empty string if request is feasible or an error message if not.
-func checkRequestFeasibility(algorithm string, input int) string {
-	switch algorithm {
-	case "user":
-		if _, exists := data.Users[input]; !exists {
+func checkRequestFeasibility(cfg *config.Config, data *Data) string {
+	input := cfg.Input
+	// Profiles are supplied by the request, and their movies missing from the dataset are simply ignored
+	if cfg.Profile != nil {
+		return ""
+	}
+	// Every seed must be feasible as the input of a single-seed request
+	if cfg.UsesSeeds() {
+		seedCfg := *cfg
+		for _, movieID := range append(append([]int{}, cfg.Seeds...), cfg.NegativeSeeds...) {
+			seedCfg.Input, seedCfg.Seeds, seedCfg.NegativeSeeds, seedCfg.SeedWeights = movieID, nil, nil, nil
+			if checkRequestFeasibility(&seedCfg, data) != "" {
+				return fmt.Sprintf("Movie ID %d not found in current dataset. Please try with another ID.", movieID)
+			}
+		}
+		return ""
+	}
+	switch cfg.Algorithm {
+	case "assoc":
+		for _, movieID := range cfg.Seeds {
+			if !data.MovieTitles.Has(movieID) {
+				return fmt.Sprintf("Movie ID %d not found in current dataset. Please try with another ID.", movieID)
+			}
+		}
+	case "p3alpha", "rp3beta":
+		if cfg.InputIsMovie() {
+			if !data.Movies.Has(input) {
+				return "Movie ID not found in current dataset. Please try with another ID."
+			}
+		} else if !data.Users.Has(input) {
+			return "User ID not found in current dataset. Please try with another ID."
+		}
+	case "user", "bpr", "slopeone":
+		if !data.Users.Has(input) {
 			return "User ID not found in current dataset. Please try with another ID."
 		}
 	case "item":
-		if _, exists := data.Movies[input]; !exists {
+		if !data.Movies.Has(input) {
 			return "Movie ID not found in current dataset. Please try with another ID."
 		}
 	case "tag":
-		if _, exists := data.MovieTags[input]; !exists {
+		if !data.MovieTags.Has(input) {
 			return "Movie ID not found in current dataset. Please try with another ID."
 		}
 	case "title":
-		if _, exists := data.MovieTitles[input]; !exists {
+		if !data.MovieTitles.Has(input) {
 			return "Movie ID not found in current dataset. Please try with another ID."
 		}
 	case "hybrid":
-		_, existsInMovies := data.Movies[input]
-		_, existsInTitle := data.MovieTitles[input]
-		_, existsInTags := data.MovieTags[input]
-		if !existsInMovies || !existsInTitle || !existsInTags {
+		if !data.Movies.Has(input) || !data.MovieTitles.Has(input) || !data.MovieTags.Has(input) {
 			return "Movie ID not found in current dataset. Please try with another ID."
 		}
 	}
 	return ""
 }
+
+// Returns the titles of the seed movies as a single comma separated string
+func getSeedTitles(seeds []int, data *Data) string {
+	titles := make([]string, 0, len(seeds))
+	for _, movieID := range seeds {
+		titles = append(titles, fmt.Sprintf("'%s'", data.MovieTitles.Title(movieID)))
+	}
+	return strings.Join(titles, ", ")
+}
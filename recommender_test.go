@@ -0,0 +1,865 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"recommender/config"
+	model "recommender/models"
+	"recommender/recommenders"
+	util "recommender/utils"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Loads a small dataset into the shared one, the way the Web-Server does on startup
+func loadTestData() {
+	users := map[int]model.User{
+		1: {MovieRatings: map[int]float32{1: 5.0, 2: 4.0, 3: 1.0, 5: 4.5}},
+		2: {MovieRatings: map[int]float32{1: 4.5, 2: 5.0, 4: 2.0, 6: 4.0}},
+		3: {MovieRatings: map[int]float32{2: 3.5, 3: 4.0, 4: 5.0, 6: 1.5}},
+		4: {MovieRatings: map[int]float32{1: 4.0, 3: 2.5, 5: 5.0, 6: 3.0}},
+		5: {MovieRatings: map[int]float32{2: 4.0, 4: 4.5, 5: 3.0}},
+		6: {MovieRatings: map[int]float32{1: 3.0, 3: 5.0, 4: 4.0, 5: 2.0, 6: 5.0}},
+	}
+	movies := make(map[int]model.Movie)
+	for userID, user := range users {
+		for movieID, rating := range user.MovieRatings {
+			if _, exists := movies[movieID]; !exists {
+				movies[movieID] = model.Movie{UserRatings: make(map[int]float32)}
+			}
+			movies[movieID].UserRatings[userID] = rating
+		}
+	}
+	// Users rated movies in ascending userID order
+	for movieID, movie := range movies {
+		movie.RatingTimes = make(map[int]int64)
+		for userID := range movie.UserRatings {
+			movie.RatingTimes[userID] = int64(1700000000 + userID)
+		}
+		movies[movieID] = movie
+	}
+	movieTitles := map[int]model.MovieTitle{
+		1: {Title: "The Dark Night", Genres: []string{"Action", "Crime"}},
+		2: {Title: "The Night Watch"},
+		3: {Title: "Dark City"},
+		4: {Title: "City Lights"},
+		5: {Title: "Lights Out"},
+		6: {Title: "The Watch"},
+	}
+	movieTags := map[int]model.MovieTags{
+		1: {UserTags: map[int]model.UserTags{1: {Tags: []string{"dark", "crime", "hero"}}}},
+		2: {UserTags: map[int]model.UserTags{1: {Tags: []string{"crime", "night"}}}},
+		3: {UserTags: map[int]model.UserTags{1: {Tags: []string{"dark", "noir"}}}},
+		4: {UserTags: map[int]model.UserTags{1: {Tags: []string{"noir", "romance"}}}},
+		5: {UserTags: map[int]model.UserTags{1: {Tags: []string{"horror", "dark"}}}},
+		6: {UserTags: map[int]model.UserTags{1: {Tags: []string{"crime", "hero", "night"}}}},
+	}
+	data.Users, data.Movies = util.NewUserTable(users), util.NewMovieTable(movies)
+	data.MovieTitles, data.MovieTags = util.NewTitleTable(movieTitles), util.NewTagTable(movieTags)
+	data.Neighbors, data.MutatedMovies, data.Scale = make(map[string]model.MovieNeighbors), make(map[int]bool), nil
+	recommenders.ResetCaches()
+	data.Index = buildIndex(numThreads, -1)
+}
+
+// Returns the response body of a request to the Web-Server
+func recommend(algorithm string, input int, maxRecords int) string {
+	url := fmt.Sprintf("/recommend?algorithm=%s&similarity=cosine&input=%d&recommendations=5", algorithm, input)
+	if maxRecords != -1 {
+		url += fmt.Sprintf("&maxRecords=%d", maxRecords)
+	}
+	recorder := httptest.NewRecorder()
+	handleRecommendationRequest(recorder, httptest.NewRequest("GET", url, nil), "")
+	return recorder.Body.String()
+}
+
+// Run with -race: concurrent requests with different limits must neither interfere nor modify the shared dataset
+func TestConcurrentLimitedRequests(t *testing.T) {
+	loadTestData()
+	algorithms := []string{"user", "item", "slopeone", "popular", "tag"}
+	limits := []int{-1, 2, 3, 4, 5}
+	// Responses of every request when served one at a time
+	expected := make(map[string]string)
+	for _, algorithm := range algorithms {
+		for _, maxRecords := range limits {
+			expected[fmt.Sprintf("%s-%d", algorithm, maxRecords)] = recommend(algorithm, 1, maxRecords)
+		}
+	}
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for _, algorithm := range algorithms {
+			for _, maxRecords := range limits {
+				wg.Add(1)
+				go func(algorithm string, maxRecords int) {
+					defer wg.Done()
+					key := fmt.Sprintf("%s-%d", algorithm, maxRecords)
+					if response := recommend(algorithm, 1, maxRecords); response != expected[key] {
+						t.Errorf("Concurrent request %s returned %s, expected %s", key, response, expected[key])
+					}
+				}(algorithm, maxRecords)
+			}
+		}
+	}
+	wg.Wait()
+	if data.Users.Len() != 6 || data.Movies.Len() != 6 || data.MovieTags.Len() != 6 || data.MovieTitles.Len() != 6 {
+		t.Errorf("Shared dataset was modified by limited requests")
+	}
+	if len(data.Index.MovieRaters) != 6 || len(data.Index.MovieTags) != 6 || len(data.Index.TitleTokens) != 6 {
+		t.Errorf("Shared index was modified by limited requests")
+	}
+}
+
+func TestQuantizedRatings(t *testing.T) {
+	loadTestData()
+	algorithms := []string{"user", "item", "slopeone", "top-rated"}
+	expected := make(map[string]string)
+	for _, algorithm := range algorithms {
+		expected[algorithm] = recommend(algorithm, 1, -1)
+	}
+	quantize = true
+	defer func() { quantize = false }()
+	quantizeRatings()
+	recommenders.ResetCaches()
+	if data.Scale == nil || !data.Users.Vector(1).Quantized() || !data.Movies.Vector(1).Quantized() {
+		t.Fatalf("Expected the ratings to be stored as steps of their scale, got scale %v", data.Scale)
+	}
+	if data.Movies.Time(1, 1) != 1700000001 {
+		t.Errorf("Expected the rating times to be kept, got %d", data.Movies.Time(1, 1))
+	}
+	// Ratings decode to the same values, so the recommendations don't change
+	for _, algorithm := range algorithms {
+		if response := recommend(algorithm, 1, -1); response != expected[algorithm] {
+			t.Errorf("Quantized %s request returned %s, expected %s", algorithm, response, expected[algorithm])
+		}
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	loadTestData()
+	tests := []struct {
+		method string
+		query  string
+		status int
+		fields []string
+	}{
+		{"GET", "algorithm=user&similarity=cosine&input=1&recommendations=5", http.StatusOK, nil},
+		{"GET", "algorithm=popular&recommendations=5", http.StatusOK, nil},
+		{"GET", "algorithm=user&similarity=cosine&input=1", http.StatusBadRequest, []string{"recommendations"}},
+		{"GET", "algorithm=user&similarity=cosine&input=abc&recommendations=5&approximate=maybe", http.StatusBadRequest, []string{"input", "approximate"}},
+		{"GET", "algorithm=user&similarity=euclidean&input=1&recommendations=5&maxRecords=0", http.StatusUnprocessableEntity, []string{"similarity", "maxRecords"}},
+		{"GET", "algorithm=random&similarity=cosine&input=1&recommendations=5", http.StatusUnprocessableEntity, []string{"algorithm"}},
+		{"GET", "algorithm=user&similarity=jaccard&input=1&recommendations=5&implicit=true&threshold=4", http.StatusOK, nil},
+		{"GET", "algorithm=user&similarity=pearson&input=1&recommendations=5&implicit=true", http.StatusUnprocessableEntity, []string{"similarity"}},
+		{"GET", "algorithm=slopeone&input=1&recommendations=5&implicit=true", http.StatusUnprocessableEntity, []string{"implicit"}},
+		{"GET", "algorithm=user&similarity=cosine&input=1&recommendations=10001", http.StatusUnprocessableEntity, []string{"recommendations"}},
+		{"GET", "algorithm=user&similarity=cosine&input=1&recommendations=5&approximate=true&bands=100&rows=6", http.StatusUnprocessableEntity, []string{"bands"}},
+		{"GET", "algorithm=user&similarity=cosine&input=1&recommendations=5&approximate=true&bands=250&rows=2", http.StatusOK, nil},
+		{"POST", "algorithm=user&similarity=cosine&input=1&recommendations=5", http.StatusMethodNotAllowed, []string{""}},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		handleRecommendationRequest(recorder, httptest.NewRequest(test.method, "/recommend?"+test.query, nil), "")
+		// Success responses have no errors but share the status of error ones
+		var response ErrorResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("%s %s: invalid JSON response: %v", test.method, test.query, err)
+		}
+		if recorder.Code != test.status || response.StatusCode != test.status {
+			t.Errorf("%s %s: expected status %d, got %d (%d in body)", test.method, test.query, test.status, recorder.Code, response.StatusCode)
+		}
+		if len(response.Errors) != len(test.fields) {
+			t.Errorf("%s %s: expected errors for %v, got %v", test.method, test.query, test.fields, response.Errors)
+			continue
+		}
+		for i, err := range response.Errors {
+			if err.Field != test.fields[i] || err.Code == "" || err.Message == "" {
+				t.Errorf("%s %s: expected an error for '%s', got %+v", test.method, test.query, test.fields[i], err)
+			}
+		}
+	}
+	// Unknown paths get a structured 404
+	recorder := httptest.NewRecorder()
+	handleNotFound(recorder, httptest.NewRequest("GET", "/unknown", nil))
+	if recorder.Code != http.StatusNotFound || !strings.Contains(recorder.Body.String(), `"code":"not_found"`) {
+		t.Errorf("Expected a structured 404, got %d %s", recorder.Code, recorder.Body.String())
+	}
+	// Failed requests get a structured 500
+	recorder = httptest.NewRecorder()
+	func() {
+		defer recoverRequest(recorder)
+		panic("failure")
+	}()
+	if recorder.Code != http.StatusInternalServerError || !strings.Contains(recorder.Body.String(), `"code":"internal_error"`) {
+		t.Errorf("Expected a structured 500, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+// Posts $body to the recommendations endpoint and returns the response status and body
+func postRecommendations(body string) (int, string) {
+	recorder := httptest.NewRecorder()
+	handleRecommendations(recorder, httptest.NewRequest("POST", "/api/v1/recommendations", strings.NewReader(body)))
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestRecommendationsAPI(t *testing.T) {
+	loadTestData()
+	tests := []struct {
+		body      string
+		scoreType string
+		legacy    string
+	}{
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1}`, PredictedRating, "algorithm=user&similarity=cosine&input=1&recommendations=5"},
+		{`{"algorithm": "item", "metric": "jaccard", "n": 3, "k": 2, "input": 2}`, PredictedRating, ""},
+		{`{"algorithm": "tag", "metric": "cosine", "n": 5, "input": 1, "filters": {"maxRecords": 4}}`, Similarity, "algorithm=tag&similarity=cosine&input=1&recommendations=5&maxRecords=4"},
+		{`{"algorithm": "popular", "n": 2}`, RatingCount, ""},
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 999}`, PredictedRating, ""},
+	}
+	for _, test := range tests {
+		status, body := postRecommendations(test.body)
+		var response RecommendationsResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil || status != http.StatusOK {
+			t.Fatalf("%s: expected a 200 response, got %d %s", test.body, status, body)
+		}
+		if response.ScoreType != test.scoreType || len(response.Results) == 0 {
+			t.Errorf("%s: expected %s scores, got %s", test.body, test.scoreType, body)
+		}
+		if test.legacy == "" {
+			continue
+		}
+		// Same movies and scores as the legacy endpoint, which truncates the scores
+		recorder := httptest.NewRecorder()
+		handleRecommendationRequest(recorder, httptest.NewRequest("GET", "/recommend?"+test.legacy, nil), "")
+		var legacy ResponseTemplate
+		json.NewDecoder(recorder.Body).Decode(&legacy)
+		if len(legacy.Data) != len(response.Results) {
+			t.Fatalf("%s: expected the %d legacy results, got %d", test.body, len(legacy.Data), len(response.Results))
+		}
+		for i, result := range response.Results {
+			if result.MovieID != legacy.Data[i].MovieID || result.Title != legacy.Data[i].MovieTitle || math.Abs(result.Score-legacy.Data[i].Result) > 0.01 {
+				t.Errorf("%s: result %d is %+v, legacy one is %+v", test.body, i, result, legacy.Data[i])
+			}
+		}
+	}
+	// Unknown inputs fall back to top-rated
+	_, body := postRecommendations(`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 999}`)
+	if !strings.Contains(body, `"algorithm":"top-rated","fallback":"top-rated"`) {
+		t.Errorf("Expected a top-rated fallback, got %s", body)
+	}
+	// The unknown movie of a fallback isn't taken for the user with the same ID, who rated movies 1, 3, 4, 5 and 6
+	_, expected := postRecommendations(`{"algorithm": "top-rated", "n": 5}`)
+	_, body = postRecommendations(`{"algorithm": "tag", "metric": "cosine", "n": 5, "input": 6, "filters": {"maxRecords": 4}}`)
+	var fallback, topRated RecommendationsResponse
+	json.Unmarshal([]byte(body), &fallback)
+	json.Unmarshal([]byte(expected), &topRated)
+	if fallback.Fallback != "top-rated" || !reflect.DeepEqual(fallback.Results, topRated.Results) {
+		t.Errorf("Expected the top-rated movies %s, got %s", expected, body)
+	}
+}
+
+// Hybrid looks up the neighbor snapshot of the input, and computes its neighbors without one
+func TestHybridNeighborSnapshots(t *testing.T) {
+	loadTestData()
+	request := `{"algorithm": "hybrid", "metric": "cosine", "n": 5, "input": 1}`
+	_, expected := postRecommendations(request)
+	var response RecommendationsResponse
+	if json.Unmarshal([]byte(expected), &response); len(response.Results) < 2 {
+		t.Fatalf("Expected several hybrid results, got %s", expected)
+	}
+	// A snapshot of every neighbor gives the same results
+	neighborCfg := config.Config{Similarity: "cosine", K: data.Movies.Len(), NumThreads: numThreads}
+	data.Neighbors["cosine"] = recommenders.ComputeMovieNeighbors(&neighborCfg, &data.Movies)
+	if _, body := postRecommendations(request); body != expected {
+		t.Errorf("Expected the results without snapshots %s, got %s", expected, body)
+	}
+	// Only the neighbors of the snapshot are ranked
+	lastMovieID := response.Results[len(response.Results)-1].MovieID
+	for _, neighbor := range data.Neighbors["cosine"][1] {
+		if neighbor.MovieID == lastMovieID {
+			data.Neighbors["cosine"][1] = []model.SimilarMovie{neighbor}
+		}
+	}
+	_, body := postRecommendations(request)
+	if json.Unmarshal([]byte(body), &response); len(response.Results) != 1 || response.Results[0].MovieID != lastMovieID {
+		t.Errorf("Expected movie %d, the only neighbor of the snapshot, got %s", lastMovieID, body)
+	}
+	// Mutated movies have no snapshot, so their neighbors are computed again
+	markMutatedMovie(1)
+	if _, body := postRecommendations(request); body != expected {
+		t.Errorf("Expected the results without snapshots %s, got %s", expected, body)
+	}
+}
+
+func TestApproximateViews(t *testing.T) {
+	loadTestData()
+	recommenders.ResetCaches()
+	request := `{"algorithm": "user", "metric": "jaccard", "n": 5, "input": 1, "approximate": true, "bands": 16, "rows": 1}`
+	_, expected := postRecommendations(request)
+	var response RecommendationsResponse
+	if json.Unmarshal([]byte(expected), &response); len(response.Results) == 0 {
+		t.Fatalf("Expected approximate results, got %s", expected)
+	}
+	// Other views and LSH parameters get their own tables instead of replacing the ones of the full view
+	for _, other := range []string{
+		`{"algorithm": "user", "metric": "jaccard", "n": 5, "input": 1, "approximate": true, "bands": 16, "rows": 1, "filters": {"maxRecords": 3}}`,
+		`{"algorithm": "user", "metric": "jaccard", "n": 5, "input": 1, "approximate": true, "bands": 4, "rows": 2, "filters": {"implicit": true, "threshold": 4}}`,
+		`{"algorithm": "item", "metric": "jaccard", "n": 5, "input": 1, "approximate": true, "bands": 8, "rows": 2}`,
+	} {
+		if status, body := postRecommendations(other); status != http.StatusOK {
+			t.Fatalf("Expected %s to succeed, got %d %s", other, status, body)
+		}
+		if _, body := postRecommendations(request); body != expected {
+			t.Errorf("Expected the same results after %s, %s, got %s", other, expected, body)
+		}
+	}
+}
+
+func TestRecommendationsAPIErrors(t *testing.T) {
+	loadTestData()
+	tests := []struct {
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{``, http.StatusBadRequest, "invalid_body", ""},
+		{`{"algorithm": "user"`, http.StatusBadRequest, "invalid_body", ""},
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1, "colour": "red"}`, http.StatusBadRequest, "invalid_body", ""},
+		{`{"algorithm": "user", "metric": "cosine", "n": "five", "input": 1}`, http.StatusBadRequest, config.InvalidParameter, "n"},
+		{`{"algorithm": "user", "metric": "cosine", "input": 1}`, http.StatusBadRequest, config.MissingParameter, "n"},
+		{`{"algorithm": "user", "metric": "euclidean", "n": 5, "input": 1}`, http.StatusUnprocessableEntity, config.InvalidValue, "metric"},
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1, "k": 0}`, http.StatusUnprocessableEntity, config.InvalidValue, "k"},
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1, "k": 10001}`, http.StatusUnprocessableEntity, config.InvalidValue, "k"},
+		{`{"algorithm": "user", "metric": "cosine", "n": 10001, "input": 1}`, http.StatusUnprocessableEntity, config.InvalidValue, "n"},
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1, "approximate": true, "bands": 1000, "rows": 1}`, http.StatusUnprocessableEntity, config.InvalidValue, "bands"},
+		{`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1, "filters": {"maxRecords": 0}}`, http.StatusUnprocessableEntity, config.InvalidValue, "filters.maxRecords"},
+	}
+	for _, test := range tests {
+		status, body := postRecommendations(test.body)
+		var response ErrorResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil || status != test.status || len(response.Errors) != 1 {
+			t.Errorf("'%s': expected a %d error, got %d %s", test.body, test.status, status, body)
+			continue
+		}
+		if err := response.Errors[0]; err.Code != test.code || err.Field != test.field {
+			t.Errorf("'%s': expected a %s error for '%s', got %+v", test.body, test.code, test.field, err)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	handleRecommendations(recorder, httptest.NewRequest("GET", "/api/v1/recommendations", nil))
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != "POST" {
+		t.Errorf("Expected a 405 allowing POST, got %d", recorder.Code)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	handleOpenAPI(recorder, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	var document struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&document); err != nil {
+		t.Fatalf("Invalid OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got version '%s'", document.OpenAPI)
+	}
+	for _, path := range []string{"/recommend", "/api/v1/recommendations", "/api/v1/openapi.json", "/api/v1/movies/{id}", "/api/v1/movies/{id}/tags", "/api/v1/users/{id}/ratings",
+		"/api/v1/users/{id}/predictions/{movieId}", "/api/v1/users/{id}/similarity/{otherId}", "/api/v1/movies/{id}/similarity/{otherId}"} {
+		if _, exists := document.Paths[path]; !exists {
+			t.Errorf("Path '%s' is not documented", path)
+		}
+	}
+}
+
+// Sends a GET request to $url with $handler and decodes its JSON response into $response
+func getJSON(handler http.HandlerFunc, url string, response interface{}) int {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", url, nil))
+	json.NewDecoder(recorder.Body).Decode(response)
+	return recorder.Code
+}
+
+func TestCatalogAPI(t *testing.T) {
+	loadTestData()
+	var movie MovieResponse
+	if status := getJSON(handleMovies, "/api/v1/movies/1", &movie); status != http.StatusOK {
+		t.Fatalf("Expected movie 1, got status %d", status)
+	}
+	// Movie 1 is rated 5.0, 4.5, 4.0 and 3.0
+	expectedHistogram := []RatingBucket{{3.0, 1}, {4.0, 1}, {4.5, 1}, {5.0, 1}}
+	if movie.Title != "The Dark Night" || !reflect.DeepEqual(movie.Genres, []string{"Action", "Crime"}) || movie.RatingCount != 4 ||
+		movie.MeanRating != 4.125 || !reflect.DeepEqual(movie.Histogram, expectedHistogram) {
+		t.Errorf("Unexpected details of movie 1: %+v", movie)
+	}
+	if !reflect.DeepEqual(movie.TopTags, []TagCount{{"crime", 1}, {"dark", 1}, {"hero", 1}}) {
+		t.Errorf("Unexpected top tags of movie 1: %v", movie.TopTags)
+	}
+	var movieTags MovieTagsResponse
+	if getJSON(handleMovies, "/api/v1/movies/6/tags", &movieTags); len(movieTags.Tags) != 3 || movieTags.MovieID != 6 {
+		t.Errorf("Unexpected tags of movie 6: %+v", movieTags)
+	}
+
+	var ratings UserRatingsResponse
+	getJSON(handleUsers, "/api/v1/users/6/ratings?sort=rating&order=desc&pageSize=2&page=2", &ratings)
+	// User 6 rated movies 3 and 6 with 5.0, 4 with 4.0, 1 with 3.0 and 5 with 2.0
+	expectedRatings := []UserRating{
+		{MovieID: 4, Title: "City Lights", Rating: 4.0, Time: 1700000006},
+		{MovieID: 1, Title: "The Dark Night", Rating: 3.0, Time: 1700000006},
+	}
+	if ratings.Total != 5 || ratings.Page != 2 || !reflect.DeepEqual(ratings.Ratings, expectedRatings) {
+		t.Errorf("Unexpected ratings of user 6: %+v", ratings)
+	}
+	getJSON(handleUsers, "/api/v1/users/6/ratings?page=3&pageSize=2", &ratings)
+	if len(ratings.Ratings) != 1 || ratings.Ratings[0].MovieID != 6 {
+		t.Errorf("Expected movie 6 on the last page of user 6, got %+v", ratings.Ratings)
+	}
+
+	errorTests := []struct {
+		handler http.HandlerFunc
+		url     string
+		status  int
+		field   string
+	}{
+		{handleMovies, "/api/v1/movies/42", http.StatusNotFound, "id"},
+		{handleMovies, "/api/v1/movies/abc", http.StatusBadRequest, "id"},
+		{handleMovies, "/api/v1/movies/1/ratings", http.StatusNotFound, ""},
+		{handleUsers, "/api/v1/users/42/ratings", http.StatusNotFound, "id"},
+		{handleUsers, "/api/v1/users/1/ratings?page=x", http.StatusBadRequest, "page"},
+		{handleUsers, "/api/v1/users/1/ratings?sort=year", http.StatusUnprocessableEntity, "sort"},
+		{handleUsers, "/api/v1/users/1/ratings?pageSize=1000", http.StatusUnprocessableEntity, "pageSize"},
+	}
+	for _, test := range errorTests {
+		var response ErrorResponse
+		if status := getJSON(test.handler, test.url, &response); status != test.status || len(response.Errors) != 1 || response.Errors[0].Field != test.field {
+			t.Errorf("%s: expected a %d error for '%s', got %d %+v", test.url, test.status, test.field, status, response.Errors)
+		}
+	}
+}
+
+// Sends a request with a JSON $body to $handler and decodes the JSON response, if any, into $response
+func sendJSON(handler http.HandlerFunc, method string, url string, body string, response interface{}) int {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(method, url, strings.NewReader(body)))
+	if response != nil && recorder.Body.Len() > 0 {
+		json.Unmarshal(recorder.Body.Bytes(), response)
+	}
+	return recorder.Code
+}
+
+func TestMutationAPI(t *testing.T) {
+	loadTestData()
+	dataDir := t.TempDir() + "/"
+	openMutationLog(dataDir)
+	defer func() { mutationLog.Close(); mutationLog = nil }()
+
+	var rating RatingResponse
+	if status := sendJSON(handleUsers, "PUT", "/api/v1/users/7/ratings/1", `{"rating": 4.5}`, &rating); status != http.StatusCreated {
+		t.Fatalf("Expected a new rating of user 7, got status %d", status)
+	}
+	if rating.UserID != 7 || rating.MovieID != 1 || rating.Rating != 4.5 || rating.Time == 0 {
+		t.Errorf("Unexpected rating of user 7: %+v", rating)
+	}
+	if status := sendJSON(handleUsers, "PUT", "/api/v1/users/6/ratings/1", `{"rating": 1}`, nil); status != http.StatusOK {
+		t.Errorf("Expected the rating of user 6 to be replaced, got status %d", status)
+	}
+	if status := sendJSON(handleUsers, "DELETE", "/api/v1/users/6/ratings/5", "", nil); status != http.StatusNoContent {
+		t.Errorf("Expected the rating of user 6 to be deleted, got status %d", status)
+	}
+	var movieTags MovieTagsResponse
+	if status := sendJSON(handleMovies, "POST", "/api/v1/movies/2/tags", `{"userId": 7, "tags": ["Dark!", "noir"]}`, &movieTags); status != http.StatusCreated {
+		t.Errorf("Expected tags of movie 2 to be added, got status %d", status)
+	}
+	if !reflect.DeepEqual(movieTags.Tags, []TagCount{{"crime", 1}, {"dark", 1}, {"night", 1}, {"noir", 1}}) {
+		t.Errorf("Unexpected tags of movie 2: %+v", movieTags.Tags)
+	}
+	// Both directions of the ratings and the index follow the mutations
+	if userRating, _ := data.Users.Rating(6, 1); userRating != 1 || data.Movies.Time(1, 7) != rating.Time || data.Movies.Count(5) != 3 {
+		t.Errorf("Ratings were not updated: %v %v", data.Users.Ratings(6), data.Movies.Ratings(1))
+	}
+	if movieRating, _ := data.Movies.Rating(1, 7); movieRating != 4.5 {
+		t.Errorf("Rating of user 7 to movie 1 was not updated: %v", data.Movies.Ratings(1))
+	}
+	expectedIndex := util.BuildDatasetIndex(&data.Users, &data.MovieTags, &data.MovieTitles, numThreads)
+	if !reflect.DeepEqual(data.Index.MovieRaters, expectedIndex.MovieRaters) || !reflect.DeepEqual(data.Index.MovieTags, expectedIndex.MovieTags) ||
+		!reflect.DeepEqual(data.Index.TagPostings, expectedIndex.TagPostings) {
+		t.Errorf("Index doesn't match the mutated dataset")
+	}
+	var ratings UserRatingsResponse
+	if getJSON(handleUsers, "/api/v1/users/7/ratings", &ratings); ratings.Total != 1 || ratings.Ratings[0].Time != rating.Time {
+		t.Errorf("Unexpected ratings of user 7: %+v", ratings)
+	}
+
+	errorTests := []struct {
+		handler http.HandlerFunc
+		method  string
+		url     string
+		body    string
+		status  int
+		field   string
+	}{
+		{handleUsers, "PUT", "/api/v1/users/7/ratings/1", `{"rating": 4.2}`, http.StatusUnprocessableEntity, "rating"},
+		{handleUsers, "PUT", "/api/v1/users/7/ratings/1", `{"rating": 6}`, http.StatusUnprocessableEntity, "rating"},
+		{handleUsers, "PUT", "/api/v1/users/7/ratings/1", `{}`, http.StatusBadRequest, "rating"},
+		{handleUsers, "PUT", "/api/v1/users/7/ratings/42", `{"rating": 4}`, http.StatusNotFound, "movieId"},
+		{handleUsers, "PUT", "/api/v1/users/0/ratings/1", `{"rating": 4}`, http.StatusUnprocessableEntity, "id"},
+		{handleUsers, "PUT", "/api/v1/users/7/ratings/x", `{"rating": 4}`, http.StatusBadRequest, "movieId"},
+		{handleUsers, "DELETE", "/api/v1/users/6/ratings/5", "", http.StatusNotFound, "movieId"},
+		{handleUsers, "POST", "/api/v1/users/7/ratings/1", `{"rating": 4}`, http.StatusMethodNotAllowed, ""},
+		{handleMovies, "POST", "/api/v1/movies/1/tags", `{"tags": ["dark"]}`, http.StatusBadRequest, "userId"},
+		{handleMovies, "POST", "/api/v1/movies/1/tags", `{"userId": 7, "tags": ["!?"]}`, http.StatusUnprocessableEntity, "tags"},
+		{handleMovies, "POST", "/api/v1/movies/42/tags", `{"userId": 7, "tags": ["dark"]}`, http.StatusNotFound, "id"},
+		{handleMovies, "POST", "/api/v1/movies/1", `{"userId": 7, "tags": ["dark"]}`, http.StatusMethodNotAllowed, ""},
+	}
+	for _, test := range errorTests {
+		var response ErrorResponse
+		if status := sendJSON(test.handler, test.method, test.url, test.body, &response); status != test.status || len(response.Errors) != 1 || response.Errors[0].Field != test.field {
+			t.Errorf("%s %s: expected a %d error for '%s', got %d %+v", test.method, test.url, test.status, test.field, status, response.Errors)
+		}
+	}
+
+	// Replaying the log on top of the original dataset restores the mutated one
+	users, movies, movieTags2 := data.Users.ToUsers(), data.Movies.ToMovies(), data.MovieTags.ToMovieTags()
+	loadTestData()
+	replayMutations(dataDir)
+	if !reflect.DeepEqual(data.Users.ToUsers(), users) || !reflect.DeepEqual(data.Movies.ToMovies(), movies) || !reflect.DeepEqual(data.MovieTags.ToMovieTags(), movieTags2) {
+		t.Errorf("Replayed dataset doesn't match the mutated one")
+	}
+}
+
+// The neighbors of mutated movies are computed again, and the responses based on out of date precomputed data say so
+func TestMutatedNeighbors(t *testing.T) {
+	loadTestData()
+	neighborCfg := config.Config{Similarity: "cosine", K: -1, NumThreads: numThreads}
+	data.Neighbors["cosine"] = recommenders.ComputeMovieNeighbors(&neighborCfg, &data.Movies)
+	staleData := func(body string) string {
+		recorder := httptest.NewRecorder()
+		handleRecommendations(recorder, httptest.NewRequest("POST", "/api/v1/recommendations", strings.NewReader(body)))
+		return recorder.Header().Get(staleDataHeader)
+	}
+	itemRequest := `{"algorithm": "item", "metric": "cosine", "n": 5, "input": 2}`
+	if header := staleData(itemRequest); header != "" {
+		t.Errorf("Expected no %s header before any mutation, got '%s'", staleDataHeader, header)
+	}
+
+	sendJSON(handleUsers, "PUT", "/api/v1/users/7/ratings/3", `{"rating": 2}`, nil)
+	if _, exists := data.Neighbors["cosine"][3]; exists || len(data.Neighbors["cosine"]) != 5 || !data.MutatedMovies[3] {
+		t.Errorf("Expected the neighbors of the mutated movie 3 only to be removed, got %v", data.Neighbors["cosine"])
+	}
+	tests := map[string]string{
+		itemRequest: "neighbors",
+		`{"algorithm": "item", "metric": "jaccard", "n": 5, "input": 2}`:                  "",
+		`{"algorithm": "item", "metric": "cosine", "n": 5, "input": 2, "implicit": true}`: "",
+		`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 2}`:                   "",
+		`{"algorithm": "assoc", "n": 5, "input": 2}`:                                      "rules",
+	}
+	for body, expected := range tests {
+		if header := staleData(body); header != expected {
+			t.Errorf("%s: expected the %s header '%s', got '%s'", body, staleDataHeader, expected, header)
+		}
+	}
+
+	// Compacted mutations are replayed from the list of mutated movies
+	dataDir := t.TempDir() + "/"
+	if err := util.WriteIDList(dataDir+mutatedMoviesFile, []int{1, 4}, "Mutated movies"); err != nil {
+		t.Fatalf("WriteIDList: %v", err)
+	}
+	loadTestData()
+	replayMutations(dataDir)
+	if !reflect.DeepEqual(data.MutatedMovies, map[int]bool{1: true, 4: true}) {
+		t.Errorf("Expected movies 1 and 4 to be mutated, got %v", data.MutatedMovies)
+	}
+}
+
+// Run with -race: mutations must not interfere with concurrent recommendation and catalog requests
+func TestConcurrentMutations(t *testing.T) {
+	loadTestData()
+	var wg sync.WaitGroup
+	for userID := 10; userID < 20; userID++ {
+		wg.Add(3)
+		go func(userID int) {
+			defer wg.Done()
+			sendJSON(handleUsers, "PUT", fmt.Sprintf("/api/v1/users/%d/ratings/%d", userID, userID%6+1), `{"rating": 3.5}`, nil)
+			sendJSON(handleMovies, "POST", fmt.Sprintf("/api/v1/movies/%d/tags", userID%6+1), fmt.Sprintf(`{"userId": %d, "tags": ["tag%d"]}`, userID, userID), nil)
+		}(userID)
+		go func() {
+			defer wg.Done()
+			for _, algorithm := range []string{"user", "item", "slopeone", "bpr", "tag"} {
+				recommend(algorithm, 1, -1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			getJSON(handleMovies, "/api/v1/movies/1", &MovieResponse{})
+		}()
+	}
+	wg.Wait()
+	if data.Users.Len() != 16 || data.Movies.TotalRatings() != 34 || len(data.Index.TagPostings) != 17 {
+		t.Errorf("Expected 16 users, 34 ratings and 17 tags, got %d users, %d ratings and %d tags", data.Users.Len(), data.Movies.TotalRatings(), len(data.Index.TagPostings))
+	}
+}
+
+func TestProfileRecommendations(t *testing.T) {
+	loadTestData()
+	users, movies := data.Users.ToUsers(), data.Movies.ToMovies()
+	// The profile has the ratings of user 1, so it gets the same recommendations. User 1 is a neighbor of the profile,
+	// but it doesn't add to the forecasts since it rated none of the recommendable movies
+	profile := `[{"movieId": 1, "rating": 5}, {"movieId": 2, "rating": 4}, {"movieId": 3, "rating": 1}, {"movieId": 5, "rating": 4.5}]`
+	for _, algorithm := range []string{"user", "item", "slopeone", "p3alpha"} {
+		_, expected := postRecommendations(fmt.Sprintf(`{"algorithm": "%s", "metric": "cosine", "n": 5, "input": 1}`, algorithm))
+		status, body := postRecommendations(fmt.Sprintf(`{"algorithm": "%s", "metric": "cosine", "n": 5, "profile": %s}`, algorithm, profile))
+		if status != http.StatusOK || body != expected {
+			t.Errorf("%s: expected the recommendations of user 1 %s, got %d %s", algorithm, expected, status, body)
+		}
+	}
+	// Tag profiles rank the movies sharing tags with the liked ones (1, 2 and 5), without the rated ones
+	var response RecommendationsResponse
+	status, body := postRecommendations(fmt.Sprintf(`{"algorithm": "tag", "metric": "cosine", "n": 5, "profile": %s}`, profile))
+	json.Unmarshal([]byte(body), &response)
+	if status != http.StatusOK || response.ScoreType != Similarity || len(response.Results) != 1 || response.Results[0].MovieID != 6 {
+		t.Errorf("tag: expected movie 6 only, got %d %s", status, body)
+	}
+
+	// BPR profiles only get the movies they didn't rate
+	status, body = postRecommendations(fmt.Sprintf(`{"algorithm": "bpr", "n": 5, "profile": %s}`, profile))
+	json.Unmarshal([]byte(body), &response)
+	if status != http.StatusOK || response.ScoreType != Preference || len(response.Results) != 2 {
+		t.Errorf("bpr: expected movies 4 and 6, got %d %s", status, body)
+	}
+
+	errorTests := []string{
+		fmt.Sprintf(`{"algorithm": "hybrid", "metric": "cosine", "n": 5, "profile": %s}`, profile),
+		fmt.Sprintf(`{"algorithm": "user", "metric": "cosine", "n": 5, "input": 1, "profile": %s}`, profile),
+		fmt.Sprintf(`{"algorithm": "p3alpha", "inputType": "movie", "n": 5, "profile": %s}`, profile),
+		`{"algorithm": "user", "metric": "cosine", "n": 5, "profile": []}`,
+		`{"algorithm": "user", "metric": "cosine", "n": 5, "profile": [{"movieId": 0, "rating": 4}]}`,
+	}
+	for _, body := range errorTests {
+		var response ErrorResponse
+		status, responseBody := postRecommendations(body)
+		if json.Unmarshal([]byte(responseBody), &response); status != http.StatusUnprocessableEntity || len(response.Errors) != 1 || response.Errors[0].Field != "profile" {
+			t.Errorf("'%s': expected a 422 error for 'profile', got %d %s", body, status, responseBody)
+		}
+	}
+	// Profiles are never added to the shared dataset
+	if !reflect.DeepEqual(data.Users.ToUsers(), users) || !reflect.DeepEqual(data.Movies.ToMovies(), movies) || data.Users.Len() != 6 {
+		t.Errorf("Profile requests modified the dataset")
+	}
+}
+
+func TestSeedRecommendations(t *testing.T) {
+	loadTestData()
+	// Similarities of the movies similar to a single seed
+	similarities := func(algorithm string, seed int) map[int]float64 {
+		var response RecommendationsResponse
+		_, body := postRecommendations(fmt.Sprintf(`{"algorithm": "%s", "metric": "cosine", "n": 10, "input": %d}`, algorithm, seed))
+		json.Unmarshal([]byte(body), &response)
+		scores := make(map[int]float64)
+		for _, result := range response.Results {
+			scores[result.MovieID] = result.Score
+		}
+		return scores
+	}
+	for _, algorithm := range []string{"tag", "title", "hybrid"} {
+		seed1, seed3 := similarities(algorithm, 1), similarities(algorithm, 3)
+		var response RecommendationsResponse
+		status, body := postRecommendations(fmt.Sprintf(`{"algorithm": "%s", "metric": "cosine", "n": 10, "seeds": [1, 3], "seedWeights": {"3": 3}}`, algorithm))
+		if json.Unmarshal([]byte(body), &response); status != http.StatusOK || response.ScoreType != Similarity {
+			t.Fatalf("%s: expected similar movies, got %d %s", algorithm, status, body)
+		}
+		for _, result := range response.Results {
+			if result.MovieID == 1 || result.MovieID == 3 {
+				t.Errorf("%s: expected the seeds to be excluded, got %+v", algorithm, result)
+			}
+			// Weighted mean of the similarities to both seeds
+			if expected := (seed1[result.MovieID] + 3*seed3[result.MovieID]) / 4; math.Abs(result.Score-expected) > 1e-9 {
+				t.Errorf("%s: expected movie %d to score %f, got %f", algorithm, result.MovieID, expected, result.Score)
+			}
+		}
+	}
+	// Movie 1 shares dark with the seed 3, but crime and hero with the negative seed 6, so it ranks last
+	_, body := postRecommendations(`{"algorithm": "tag", "metric": "jaccard", "n": 10, "seeds": [3], "negativeSeeds": [6], "aggregation": "sum"}`)
+	var response RecommendationsResponse
+	json.Unmarshal([]byte(body), &response)
+	if len(response.Results) != 3 || response.Results[2].MovieID != 1 || math.Abs(response.Results[2].Score-(0.25-0.5)) > 1e-9 {
+		t.Errorf("Expected movies 4 and 5 before movie 1, got %s", body)
+	}
+	// Unknown seeds fall back to top-rated
+	if _, body := postRecommendations(`{"algorithm": "tag", "metric": "cosine", "n": 5, "seeds": [1, 42]}`); !strings.Contains(body, `"fallback":"top-rated"`) {
+		t.Errorf("Expected a top-rated fallback, got %s", body)
+	}
+
+	errorTests := []struct {
+		body  string
+		field string
+	}{
+		{`{"algorithm": "item", "metric": "cosine", "n": 5, "input": 1, "negativeSeeds": [2]}`, "negativeSeeds"},
+		{`{"algorithm": "tag", "metric": "cosine", "n": 5, "seeds": [1, 2], "negativeSeeds": [2]}`, "negativeSeeds"},
+		{`{"algorithm": "tag", "metric": "cosine", "n": 5, "seeds": [1, 2], "seedWeights": {"2": -1}}`, "seedWeights"},
+		{`{"algorithm": "tag", "metric": "cosine", "n": 5, "seeds": [1, 2], "seedWeights": {"5": 2}}`, "seedWeights"},
+		{`{"algorithm": "tag", "metric": "cosine", "n": 5, "seeds": [1, 2], "aggregation": "median"}`, "aggregation"},
+	}
+	for _, test := range errorTests {
+		var response ErrorResponse
+		status, body := postRecommendations(test.body)
+		if json.Unmarshal([]byte(body), &response); status != http.StatusUnprocessableEntity || len(response.Errors) != 1 || response.Errors[0].Field != test.field {
+			t.Errorf("'%s': expected a 422 error for '%s', got %d %s", test.body, test.field, status, body)
+		}
+	}
+}
+
+func TestResultFilters(t *testing.T) {
+	loadTestData()
+	// IDs of the recommended movies, in order
+	movieIDs := func(body string) []int {
+		status, responseBody := postRecommendations(body)
+		var response RecommendationsResponse
+		if json.Unmarshal([]byte(responseBody), &response); status != http.StatusOK {
+			t.Fatalf("%s: expected a 200 response, got %d %s", body, status, responseBody)
+		}
+		ids := make([]int, 0, len(response.Results))
+		for _, result := range response.Results {
+			ids = append(ids, result.MovieID)
+		}
+		return ids
+	}
+	unfiltered := movieIDs(`{"algorithm": "tag", "metric": "cosine", "n": 10, "input": 1}`)
+	tests := []struct {
+		filters  string
+		n        int
+		excluded []int
+	}{
+		{`{"excludeTags": ["Dark"]}`, 1, []int{3, 5}},
+		{`{"deny": [2, 4]}`, 10, []int{2, 4}},
+		{`{"allow": [4, 5, 6], "includeTags": ["noir", "horror"]}`, 10, []int{2, 3, 6}},
+		{`{"includeGenres": ["crime"]}`, 10, []int{2, 3, 4, 5, 6}},
+		{`{"minRatings": 5}`, 10, []int{2, 3, 4, 5, 6}},
+	}
+	for _, test := range tests {
+		// The top n unfiltered movies that pass the filters
+		expected := make([]int, 0, test.n)
+		for _, movieID := range unfiltered {
+			if len(expected) < test.n && !slices.Contains(test.excluded, movieID) {
+				expected = append(expected, movieID)
+			}
+		}
+		body := fmt.Sprintf(`{"algorithm": "tag", "metric": "cosine", "n": %d, "input": 1, "filters": %s}`, test.n, test.filters)
+		if filtered := movieIDs(body); !reflect.DeepEqual(filtered, expected) {
+			t.Errorf("%s: expected movies %v, got %v", test.filters, expected, filtered)
+		}
+	}
+	// Same filters from the query of the legacy endpoint, applied to the fallback as well
+	recorder := httptest.NewRecorder()
+	handleRecommendationRequest(recorder, httptest.NewRequest("GET", "/recommend?algorithm=user&similarity=cosine&input=999&recommendations=5&excludeTags=dark&deny=2", nil), "")
+	var legacy ResponseTemplate
+	json.Unmarshal(recorder.Body.Bytes(), &legacy)
+	legacyIDs := make([]int, 0, len(legacy.Data))
+	for _, result := range legacy.Data {
+		legacyIDs = append(legacyIDs, result.MovieID)
+	}
+	if slices.Sort(legacyIDs); !reflect.DeepEqual(legacyIDs, []int{4, 6}) {
+		t.Errorf("Expected movies 4 and 6, got %s", recorder.Body.String())
+	}
+
+	errorTests := []struct {
+		filters string
+		field   string
+	}{
+		{`{"minRatings": -1}`, "filters.minRatings"},
+		{`{"allow": []}`, "filters.allow"},
+		{`{"minYear": -1990}`, "filters.minYear"},
+		{`{"minYear": 2000, "maxYear": 1990}`, "filters.maxYear"},
+	}
+	for _, test := range errorTests {
+		var response ErrorResponse
+		status, body := postRecommendations(`{"algorithm": "popular", "n": 5, "filters": ` + test.filters + `}`)
+		if json.Unmarshal([]byte(body), &response); status != http.StatusUnprocessableEntity || len(response.Errors) != 1 || response.Errors[0].Field != test.field {
+			t.Errorf("%s: expected a 422 error for '%s', got %d %s", test.filters, test.field, status, body)
+		}
+	}
+}
+
+func TestPairAPI(t *testing.T) {
+	loadTestData()
+	// Predictions match the forecasts of the recommendations of both algorithms
+	for _, test := range []struct {
+		body  string
+		query string
+	}{
+		{`{"algorithm": "user", "metric": "cosine", "n": 10, "input": 1}`, "metric=cosine"},
+		{`{"algorithm": "item", "metric": "jaccard", "n": 10, "k": 2, "input": 2}`, "algorithm=item&metric=jaccard&k=2"},
+	} {
+		var recommendations RecommendationsResponse
+		_, body := postRecommendations(test.body)
+		if json.Unmarshal([]byte(body), &recommendations); len(recommendations.Results) == 0 {
+			t.Fatalf("%s: expected recommendations, got %s", test.body, body)
+		}
+		var request RecommendationRequest
+		json.Unmarshal([]byte(test.body), &request)
+		for _, result := range recommendations.Results {
+			var prediction PredictionResponse
+			status := getJSON(handleUsers, fmt.Sprintf("/api/v1/users/%d/predictions/%d?%s", request.Input, result.MovieID, test.query), &prediction)
+			if status != http.StatusOK || !prediction.Predicted || math.Abs(prediction.Rating-result.Score) > 1e-5 || len(prediction.Neighbors) == 0 {
+				t.Errorf("%s: expected movie %d to be predicted %f, got %d %+v", test.body, result.MovieID, result.Score, status, prediction)
+			}
+			if prediction.Title != result.Title || prediction.Algorithm != request.Algorithm || prediction.Actual != 0 {
+				t.Errorf("%s: unexpected prediction of movie %d: %+v", test.body, result.MovieID, prediction)
+			}
+		}
+	}
+	// Movies the user rated are forecast too
+	var prediction PredictionResponse
+	if getJSON(handleUsers, "/api/v1/users/1/predictions/1?metric=cosine", &prediction); prediction.Actual != 5.0 || !prediction.Predicted {
+		t.Errorf("Expected a forecast of movie 1 with its actual rating, got %+v", prediction)
+	}
+
+	// Movies 1 and 3 are both rated by users 1, 4 and 6, and movies 5 and 6 are as similar to movie 1 by jaccard
+	var pair model.PairSimilarity
+	if status := getJSON(handleMovies, "/api/v1/movies/1/similarity/3?metric=jaccard", &pair); status != http.StatusOK {
+		t.Fatalf("Expected the similarity of movies 1 and 3, got status %d", status)
+	}
+	if pair.Ratings != 4 || pair.OtherRatings != 4 || pair.Overlap != 3 || pair.Metrics["jaccard"] != 0.6 || pair.Metrics["dice"] != 0.75 || pair.Neighbors != 5 || pair.Rank != 1 {
+		t.Errorf("Unexpected similarity of movies 1 and 3: %+v", pair)
+	}
+	pair = model.PairSimilarity{}
+	if getJSON(handleUsers, "/api/v1/users/1/similarity/2", &pair); pair.Overlap != 2 || len(pair.Metrics) != len(config.SimilarityMetrics) || pair.Metric != "" || pair.Rank != 0 {
+		t.Errorf("Unexpected similarity of users 1 and 2: %+v", pair)
+	}
+
+	errorTests := []struct {
+		url    string
+		status int
+		field  string
+	}{
+		{"/api/v1/users/1/predictions/2", http.StatusBadRequest, "metric"},
+		{"/api/v1/users/1/predictions/2?metric=cosine&algorithm=bpr", http.StatusUnprocessableEntity, "algorithm"},
+		{"/api/v1/users/1/predictions/2?metric=cosine&k=0", http.StatusUnprocessableEntity, "k"},
+		{"/api/v1/users/999/predictions/2?metric=cosine", http.StatusNotFound, "id"},
+		{"/api/v1/users/1/predictions/42?metric=cosine", http.StatusNotFound, "movieId"},
+		{"/api/v1/users/1/similarity/2?metric=euclidean", http.StatusUnprocessableEntity, "metric"},
+		{"/api/v1/users/1/similarity/999", http.StatusNotFound, "otherId"},
+		{"/api/v1/movies/1/similarity/0", http.StatusUnprocessableEntity, "otherId"},
+		{"/api/v1/movies/1/similarity/three", http.StatusBadRequest, "otherId"},
+	}
+	for _, test := range errorTests {
+		handler := handleMovies
+		if strings.HasPrefix(test.url, "/api/v1/users/") {
+			handler = handleUsers
+		}
+		var response ErrorResponse
+		status := getJSON(handler, test.url, &response)
+		if status != test.status || len(response.Errors) != 1 || response.Errors[0].Field != test.field {
+			t.Errorf("%s: expected a %d error for '%s', got %d %+v", test.url, test.status, test.field, status, response)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	handleUsers(recorder, httptest.NewRequest("POST", "/api/v1/users/1/predictions/2", nil))
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != "GET" {
+		t.Errorf("Expected a 405 allowing GET, got %d", recorder.Code)
+	}
+}
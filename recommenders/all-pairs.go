@@ -0,0 +1,139 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"slices"
+	"sync"
+)
+
+// Number of consecutive rows (movies) of the similarity matrix computed by a routine at a time
+const allPairsBlockSize = 64
+
+// A rating of the transposed rating matrix, ie. the rating of a user to the movie at position $movie
+type ratingEntry struct {
+	movie  int
+	rating float64
+}
+
+// Per-routine sparse accumulator of the products of one row of the rating matrix transpose with the rating matrix
+type pairAccumulator struct {
+	// Number of common users, dot product and sum and squared sum of the other movie's ratings over them
+	counts      []int
+	dotProducts []float64
+	sums        []float64
+	squaredSums []float64
+	// Positions of the movies with at least one common user, ie. the non-zero entries of the row
+	touched []int
+}
+
+/*
+Computes the cfg.Similarity of every pair of movies with at least one common user and returns the
+edges whose similarity is at least $minSimilarity, sorted by MovieA and MovieB. The similarity of
+MovieB to MovieA is the same as the one of findSimilarMovies when MovieA is the selected movie.
+
+The co-occurrence counts and dot products of all pairs are the product of the rating matrix transpose
+with the rating matrix. Rows (movies) are split into blocks that routines compute one at a time, by
+walking the users of every movie and the movies of every such user into a dense accumulator.
+*/
+func ComputeAllPairs(cfg *config.Config, movies *util.RatingTable, minSimilarity float64) []model.MovieEdge {
+	fmt.Printf("Computing all-pairs %s similarities of %d movies.\n", cfg.Similarity, movies.Len())
+	// Dense positions of the movies sorted by ID, so that edges come out sorted
+	movieIDs := movies.IDs()
+	// Transpose the movie ratings into the ratings of every user, sorted by movie position
+	movieVectors := make([]algorithms.SparseVector[int, float32], len(movieIDs))
+	userRatings := make(map[int][]ratingEntry)
+	for position, movieID := range movieIDs {
+		vector := movies.Vector(movieID)
+		movieVectors[position] = vector
+		for i, userID := range vector.Keys {
+			userRatings[userID] = append(userRatings[userID], ratingEntry{movie: position, rating: vector.Value(i)})
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	numBlocks := (len(movieIDs) + allPairsBlockSize - 1) / allPairsBlockSize
+	// Edges of every block, so that they can be concatenated in order once all routines finish
+	blockEdges := make([][]model.MovieEdge, numBlocks)
+	nextBlock := 0
+	numThreads := cfg.NumThreads
+	if numThreads > numBlocks {
+		numThreads = numBlocks
+	}
+	for thread := 0; thread < numThreads; thread++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accumulator := pairAccumulator{
+				counts:      make([]int, len(movieIDs)),
+				dotProducts: make([]float64, len(movieIDs)),
+				sums:        make([]float64, len(movieIDs)),
+				squaredSums: make([]float64, len(movieIDs)),
+			}
+			for {
+				// Take the next block while protecting the shared counter from concurrent writing
+				mu.Lock()
+				block := nextBlock
+				nextBlock++
+				mu.Unlock()
+				if block >= numBlocks {
+					return
+				}
+				start, end := block*allPairsBlockSize, (block+1)*allPairsBlockSize
+				if end > len(movieIDs) {
+					end = len(movieIDs)
+				}
+				edges := make([]model.MovieEdge, 0)
+				for position := start; position < end; position++ {
+					edges = accumulator.appendRow(cfg.Similarity, position, movieIDs, movieVectors, userRatings, minSimilarity, edges)
+				}
+				blockEdges[block] = edges
+			}
+		}()
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	edges := make([]model.MovieEdge, 0)
+	for _, blockEdge := range blockEdges {
+		edges = append(edges, blockEdge...)
+	}
+	return edges
+}
+
+// Accumulates the row of the movie at $position and appends its edges with a similarity of at least $minSimilarity to $edges
+func (accumulator *pairAccumulator) appendRow(metric string, position int, movieIDs []int, movieVectors []algorithms.SparseVector[int, float32], userRatings map[int][]ratingEntry, minSimilarity float64, edges []model.MovieEdge) []model.MovieEdge {
+	vector := movieVectors[position]
+	// Users are walked in ascending order, which is the order of the merge-join of findSimilarMovies
+	for i, userID := range vector.Keys {
+		rating := vector.Value(i)
+		for _, entry := range userRatings[userID] {
+			if accumulator.counts[entry.movie] == 0 {
+				accumulator.touched = append(accumulator.touched, entry.movie)
+			}
+			accumulator.counts[entry.movie]++
+			accumulator.dotProducts[entry.movie] += rating * entry.rating
+			accumulator.sums[entry.movie] += entry.rating
+			accumulator.squaredSums[entry.movie] += entry.rating * entry.rating
+		}
+	}
+	slices.Sort(accumulator.touched)
+	for _, other := range accumulator.touched {
+		if other != position {
+			similarity := accumulatedSimilarity(metric, vector, movieVectors[other].Len(), accumulator.counts[other], accumulator.dotProducts[other], accumulator.sums[other], accumulator.squaredSums[other])
+			if similarity >= minSimilarity {
+				edges = append(edges, model.MovieEdge{MovieA: movieIDs[position], MovieB: movieIDs[other], Similarity: similarity})
+			}
+		}
+		// Reset the accumulator for the next row
+		accumulator.counts[other] = 0
+		accumulator.dotProducts[other] = 0
+		accumulator.sums[other] = 0
+		accumulator.squaredSums[other] = 0
+	}
+	accumulator.touched = accumulator.touched[:0]
+	return edges
+}
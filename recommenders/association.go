@@ -0,0 +1,60 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+)
+
+/*
+Returns the movies implied by the cfg.Seeds movies through the mined association rules.
+A rule applies when all of its antecedent movies are seeds and its consequent isn't. Every
+movie is returned once, with the rule of highest confidence (and lift on ties) that implies it.
+*/
+func RecommendBasedOnAssociation(cfg *config.Config, rules *[]model.AssociationRule) []model.AssociationRule {
+	fmt.Printf("Working with %d association rules.\n", len(*rules))
+	util.StartProfiling("assoc")
+	seeds := make(map[int]bool, len(cfg.Seeds))
+	for _, movieID := range cfg.Seeds {
+		seeds[movieID] = true
+	}
+	bestRules := make(map[int]model.AssociationRule)
+	for _, rule := range *rules {
+		if seeds[rule.Consequent] || rule.Support < cfg.MinSupport || rule.Confidence < cfg.MinConfidence {
+			continue
+		}
+		applies := true
+		for _, movieID := range rule.Antecedent {
+			if !seeds[movieID] {
+				applies = false
+				break
+			}
+		}
+		if !applies {
+			continue
+		}
+		if bestRule, exists := bestRules[rule.Consequent]; !exists || isStrongerRule(rule, bestRule) {
+			bestRules[rule.Consequent] = rule
+		}
+	}
+	// Keep the top rules by confidence, then lift, then movieID so that results are reproducible
+	consequentRules := util.NewTopK(cfg.Recommendations, func(ruleA model.AssociationRule, ruleB model.AssociationRule) bool {
+		if ruleA.Confidence == ruleB.Confidence && ruleA.Lift == ruleB.Lift {
+			return ruleA.Consequent < ruleB.Consequent
+		}
+		return isStrongerRule(ruleA, ruleB)
+	})
+	for _, rule := range bestRules {
+		consequentRules.Push(rule)
+	}
+	util.StopProfiling()
+	return consequentRules.Sorted()
+}
+
+func isStrongerRule(ruleA model.AssociationRule, ruleB model.AssociationRule) bool {
+	if ruleA.Confidence == ruleB.Confidence {
+		return ruleA.Lift > ruleB.Lift
+	}
+	return ruleA.Confidence > ruleB.Confidence
+}
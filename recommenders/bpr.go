@@ -0,0 +1,100 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"sort"
+	"sync"
+)
+
+// Trained BPR models are reused until the dataset changes, keyed by the view of the dataset and the threshold
+var bprModels = util.NewLRUCache[bprKey, *algorithms.BPRModel](maxCachedViews)
+
+type bprKey struct {
+	maxRecords int
+	threshold  float64
+}
+
+func RecommendBasedOnBPR(cfg *config.Config, users *util.RatingTable) []model.Rating {
+	fmt.Printf("Working with %d user ratings.\n", users.TotalRatings())
+	util.StartProfiling("bpr")
+	bpr := bprModels.Get(bprKey{cfg.MaxRecords, cfg.Threshold}, func() *algorithms.BPRModel { return trainBPRModel(users, cfg.Threshold) })
+	selectedRatings := inputRatings(cfg, users)
+	// Factors of the input user, learned on top of the shared model for an anonymous profile
+	var userFactors []float64
+	if cfg.Profile != nil {
+		positives := make([]int, 0, len(cfg.Profile))
+		for movieID, rating := range cfg.Profile {
+			if util.IsPositiveInteraction(rating, cfg.Threshold) {
+				positives = append(positives, movieID)
+			}
+		}
+		sort.Ints(positives)
+		userFactors = bpr.FoldIn(positives, algorithms.DefaultBPRParams())
+	} else if userIdx, exists := bpr.UserIndex[cfg.Input]; exists {
+		userFactors = bpr.UserFactors[userIdx]
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// Divide movies into chunks to split the workload to multiple routines
+	movieIDs := make([]int, 0, len(bpr.ItemIDs))
+	for _, movieID := range bpr.ItemIDs {
+		// Skip movies the user has already interacted with
+		if _, exists := selectedRatings[movieID]; !exists {
+			movieIDs = append(movieIDs, movieID)
+		}
+	}
+	// Users without positive interactions are unknown to the model
+	if userFactors == nil {
+		movieIDs = movieIDs[:0]
+	}
+	if cfg.NumThreads > len(movieIDs) {
+		cfg.NumThreads = len(movieIDs)
+	}
+	movieChunks := util.GenerateChunkFromSet(movieIDs, cfg.NumThreads)
+	// The top ranked movies of every routine, to be merged once all routines finish
+	localTopMovies := make([][]model.Rating, 0, len(movieChunks))
+	for _, movieChunk := range movieChunks {
+		wg.Add(1)
+		go func(movieIDs []int) {
+			defer wg.Done()
+			// Top ranked movies of the current routine
+			localRankedMovies := util.NewTopK(cfg.Recommendations, higherRating)
+			for _, movieID := range movieIDs {
+				score, exists := bpr.ScoreFactors(userFactors, movieID)
+				if !exists {
+					continue
+				}
+				localRankedMovies.Push(model.Rating{MovieID: movieID, Rating: float32(score)})
+			}
+			// Append local results while protecting shared struct from concurrent writing
+			mu.Lock()
+			localTopMovies = append(localTopMovies, localRankedMovies.Sorted())
+			mu.Unlock()
+		}(movieChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	// Keep the overall top movies by BPR score
+	rankedMovies := util.MergeTopK(cfg.Recommendations, higherRating, localTopMovies...)
+	util.StopProfiling()
+	return rankedMovies
+}
+
+// Trains a BPR model on the positive interactions of $users
+func trainBPRModel(users *util.RatingTable, threshold float64) *algorithms.BPRModel {
+	// Every rating above the threshold counts as a positive interaction, in ascending order of movieID like the ratings
+	interactions := make(map[int][]int, users.Len())
+	for _, userID := range users.IDs() {
+		vector := users.Vector(userID)
+		for i, movieID := range vector.Keys {
+			if util.IsPositiveInteraction(float32(vector.Value(i)), threshold) {
+				interactions[userID] = append(interactions[userID], movieID)
+			}
+		}
+	}
+	return algorithms.TrainBPR(interactions, algorithms.DefaultBPRParams())
+}
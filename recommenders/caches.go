@@ -0,0 +1,16 @@
+package recommenders
+
+// Max number of views of the dataset whose models and tables are cached at once, eg. the full one and a few limited ones
+const maxCachedViews = 4
+
+/*
+Drops every model and table derived from the dataset, so that the next request rebuilds them. Must be called whenever
+the dataset changes, since the caches are keyed by the view of the dataset and not by its content.
+*/
+func ResetCaches() {
+	bprModels.Reset()
+	slopeOneRows.Reset()
+	userLSH.Reset()
+	movieLSH.Reset()
+	movieTagLSH.Reset()
+}
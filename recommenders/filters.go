@@ -0,0 +1,74 @@
+package recommenders
+
+import (
+	"recommender/config"
+	"recommender/helpers"
+	util "recommender/utils"
+	"slices"
+	"strings"
+)
+
+/*
+Returns whether a movie passes $filters, based on its number of ratings in $movies, the year and genres of its title
+in $titles and its tags in $movieTags. Tags are compared once normalized like the ones of the dataset, and genres
+regardless of case.
+*/
+func NewResultFilter(filters *config.ResultFilters, movies *util.RatingTable, titles *util.TitleTable, movieTags *util.TagTable) func(movieID int) bool {
+	allowed := make(map[int]bool, len(filters.Allow))
+	for _, movieID := range filters.Allow {
+		allowed[movieID] = true
+	}
+	normalizeTags := func(tags []string) []string {
+		normalized := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			normalized = append(normalized, strings.Join(helpers.ExtractTokensFromStr(tag), " "))
+		}
+		return normalized
+	}
+	includeTags, excludeTags := normalizeTags(filters.IncludeTags), normalizeTags(filters.ExcludeTags)
+	return func(movieID int) bool {
+		if (filters.Allow != nil && !allowed[movieID]) || slices.Contains(filters.Deny, movieID) {
+			return false
+		}
+		if filters.MinRatings > 0 && movies.Count(movieID) < filters.MinRatings {
+			return false
+		}
+		title, _ := titles.Get(movieID)
+		if filters.MinYear > 0 || filters.MaxYear > 0 {
+			_, year := util.SplitTitleYear(title.Title)
+			if year == 0 || year < filters.MinYear || (filters.MaxYear > 0 && year > filters.MaxYear) {
+				return false
+			}
+		}
+		if len(filters.IncludeGenres) > 0 || len(filters.ExcludeGenres) > 0 {
+			hasGenre := func(genre string) bool {
+				return slices.ContainsFunc(title.Genres, func(movieGenre string) bool { return strings.EqualFold(movieGenre, genre) })
+			}
+			if (len(filters.IncludeGenres) > 0 && !slices.ContainsFunc(filters.IncludeGenres, hasGenre)) || slices.ContainsFunc(filters.ExcludeGenres, hasGenre) {
+				return false
+			}
+		}
+		if len(includeTags) > 0 || len(excludeTags) > 0 {
+			tagCounts := movieTags.Occurrences(movieID)
+			hasTag := func(tag string) bool { return tagCounts[tag] > 0 }
+			if (len(includeTags) > 0 && !slices.ContainsFunc(includeTags, hasTag)) || slices.ContainsFunc(excludeTags, hasTag) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Returns the first $n of the ranked $results whose movie passes $keep, or all of them if $n is negative
+func FilterResults[T any](results []T, movieID func(T) int, keep func(movieID int) bool, n int) []T {
+	filtered := make([]T, 0, max(n, 0))
+	for _, result := range results {
+		if n >= 0 && len(filtered) == n {
+			break
+		}
+		if keep(movieID(result)) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
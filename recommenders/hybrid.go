@@ -8,38 +8,42 @@ import (
 	"sort"
 )
 
-func RecommendHybrid(cfg *config.Config, titles *map[int]model.MovieTitle, movies *map[int]model.Movie, tags *map[int]model.MovieTags) []model.SimilarMovie {
-	totalRatings := 0
-	for _, movie := range *movies {
-		totalRatings += len(movie.UserRatings)
-	}
-	fmt.Printf("Working with %d movie ratings.\n", totalRatings)
+/*
+Ranks the movies similar to the input by their tags, titles and ratings. The rating similarities are the $neighbors of
+the input that the tag and title similarities also recommend, or are computed over those movies when the input has no
+neighbors, eg. without a snapshot or when its ratings changed since preprocess.
+*/
+func RecommendHybrid(cfg *config.Config, titles *util.TitleTable, movies *util.RatingTable, tags *util.TagTable, neighbors *model.MovieNeighbors, index *util.DatasetIndex) []model.SimilarMovie {
+	fmt.Printf("Working with %d movie ratings.\n", movies.TotalRatings())
 	util.StartProfiling("hybrid")
-	finalSimilarMovies := make([]model.SimilarMovie, 0)
+	// Keep only the top recommendations while merging
+	finalSimilarMovies := util.NewTopK(cfg.Recommendations, moreSimilarMovie)
 	// Combine tag, title & item-item collaborative filtering and sort all
 	// result-slices for more efficient calulcations of average similarity.
-	tagCfg := config.Config{Recommendations: len(*tags), Similarity: cfg.Similarity, Input: cfg.Input}
-	similarMoviesByTag := RecommendBasedOnTag(&tagCfg, tags)
+	// The tags are never limited for hybrid recommendations, so they are the full view
+	tagCfg := config.Config{Recommendations: tags.Len(), Similarity: cfg.Similarity, Input: cfg.Input, MaxRecords: -1,
+		NumThreads: cfg.NumThreads, Approximate: cfg.Approximate, Bands: cfg.Bands, Rows: cfg.Rows}
+	similarMoviesByTag := RecommendBasedOnTag(&tagCfg, tags, index)
 	sort.SliceStable(similarMoviesByTag, func(i, j int) bool {
 		return similarMoviesByTag[i].MovieID < similarMoviesByTag[j].MovieID
 	})
 	// Create a subset of movie titles, only keeping the movieIDs that are recommendable by Tag-based correlation
-	recommendableTitles := map[int]model.MovieTitle{cfg.Input: (*titles)[cfg.Input]}
-	for _, movie := range similarMoviesByTag {
-		recommendableTitles[movie.MovieID] = ((*titles)[movie.MovieID])
-	}
-	titleCgf := config.Config{Recommendations: len(*titles), Similarity: cfg.Similarity, Input: cfg.Input}
-	similarMoviesByTitle := RecommendBasedOnTitle(&titleCgf, &recommendableTitles)
+	recommendableTitles := titles.Subset(similarMovieIDs(cfg.Input, similarMoviesByTag))
+	titleCgf := config.Config{Recommendations: titles.Len(), Similarity: cfg.Similarity, Input: cfg.Input}
+	similarMoviesByTitle := RecommendBasedOnTitle(&titleCgf, &recommendableTitles, index)
 	sort.SliceStable(similarMoviesByTitle, func(i, j int) bool {
 		return similarMoviesByTitle[i].MovieID < similarMoviesByTitle[j].MovieID
 	})
 	// Create a subset of movies, only keeping the movieIDs that are recommendable by Title-based correlation
-	recommendableMovies := map[int]model.Movie{cfg.Input: (*movies)[cfg.Input]}
-	for _, movie := range similarMoviesByTitle {
-		recommendableMovies[movie.MovieID] = ((*movies)[movie.MovieID])
-	}
+	recommendableMovies := movies.Subset(similarMovieIDs(cfg.Input, similarMoviesByTitle))
 	movieCfg := config.Config{Similarity: cfg.Similarity, NumThreads: cfg.NumThreads}
-	similarMovies := findSimilarMovies(&movieCfg, cfg.Input, &recommendableMovies)
+	similarMovies := make([]model.SimilarMovie, 0)
+	for _, movie := range getSimilarMovies(&movieCfg, cfg.Input, &recommendableMovies, neighbors) {
+		// Keep the precomputed neighbors of the input that are recommendable
+		if recommendableMovies.Has(movie.MovieID) {
+			similarMovies = append(similarMovies, movie)
+		}
+	}
 	sort.SliceStable(similarMovies, func(i, j int) bool {
 		return similarMovies[i].MovieID < similarMovies[j].MovieID
 	})
@@ -52,7 +56,7 @@ func RecommendHybrid(cfg *config.Config, titles *map[int]model.MovieTitle, movie
 		if similarMovie.MovieID == similarMovieByTitle.MovieID && similarMovie.MovieID == similarMovieByTag.MovieID {
 			// Combine the 3 similarity scores using 20%-40%-40% weights so that the upper limit of similarity remains 1.0
 			similarity := 0.2*similarMovie.Similarity + 0.4*similarMovieByTitle.Similarity + 0.4*similarMovieByTag.Similarity
-			finalSimilarMovies = append(finalSimilarMovies, model.SimilarMovie{
+			finalSimilarMovies.Push(model.SimilarMovie{
 				MovieID:    similarMovie.MovieID,
 				Similarity: similarity,
 			})
@@ -73,12 +77,16 @@ func RecommendHybrid(cfg *config.Config, titles *map[int]model.MovieTitle, movie
 			}
 		}
 	}
-	sort.SliceStable(finalSimilarMovies, func(i, j int) bool {
-		return finalSimilarMovies[i].Similarity > finalSimilarMovies[j].Similarity
-	})
-	if len(finalSimilarMovies) > cfg.Recommendations {
-		finalSimilarMovies = finalSimilarMovies[:cfg.Recommendations]
-	}
 	util.StopProfiling()
-	return finalSimilarMovies
+	return finalSimilarMovies.Sorted()
+}
+
+// Returns the IDs of $inputID and its $similarMovies
+func similarMovieIDs(inputID int, similarMovies []model.SimilarMovie) []int {
+	movieIDs := make([]int, 0, len(similarMovies)+1)
+	movieIDs = append(movieIDs, inputID)
+	for _, movie := range similarMovies {
+		movieIDs = append(movieIDs, movie.MovieID)
+	}
+	return movieIDs
 }
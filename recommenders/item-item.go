@@ -6,94 +6,114 @@ import (
 	"recommender/config"
 	model "recommender/models"
 	util "recommender/utils"
-	"sort"
 	"sync"
 )
 
-func RecommendBasedOnItem(cfg *config.Config, movies *map[int]model.Movie) []model.Rating {
-	totalRatings := 0
-	for _, movie := range *movies {
-		totalRatings += len(movie.UserRatings)
-	}
-	fmt.Printf("Working with %d movie ratings.\n", totalRatings)
+/*
+Forecasts ratings from the most similar movies of the movies the user liked, looked up in $neighbors when available.
+$index only provides the implicit feedback of $movies in implicit mode.
+*/
+func RecommendBasedOnItem(cfg *config.Config, movies *util.RatingTable, neighbors *model.MovieNeighbors, index *util.DatasetIndex) []model.Rating {
+	fmt.Printf("Working with %d movie ratings.\n", movies.TotalRatings())
 	util.StartProfiling("item")
-	user := model.User{MovieRatings: make(map[int]float32)}
+	_, movies, _ = implicitRatings(cfg, nil, movies, index)
+	user := model.User{MovieRatings: itemInputRatings(cfg, movies)}
+	// Find top most similar movies for each movie the user has rated
+	similarMoviesMap := likedMovieNeighbors(cfg, user.MovieRatings, movies, neighbors)
+	// A movie is recommendable when its similar to at least one movie rated by the selected user
+	recommendableMovies := make(map[int]bool, 0)
+	for _, similarMovies := range similarMoviesMap {
+		for otherMovieID := range similarMovies {
+			// Skip movies the user has already rated
+			if _, exists := user.MovieRatings[otherMovieID]; !exists {
+				recommendableMovies[otherMovieID] = true
+			}
+		}
+	}
+	// Continue to recommendation part
+	// Keep only the top recommendations while forecasting
+	ratingForecasts := util.NewTopK(cfg.Recommendations, higherRating)
+	for movieID := range recommendableMovies {
+		rating, _ := forecastItemRating(cfg, user.MovieRatings, similarMoviesMap, movieID)
+		ratingForecasts.Push(model.Rating{
+			MovieID: movieID,
+			Rating:  float32(rating),
+		})
+	}
+	util.StopProfiling()
+	return ratingForecasts.Sorted()
+}
+
+// Returns the ratings of the Input user (or profile) gathered from $movies
+func itemInputRatings(cfg *config.Config, movies *util.RatingTable) map[int]float32 {
+	if cfg.Profile != nil {
+		return profileRatings(cfg)
+	}
 	// Gather all the user's ratings
-	for movieID := range *movies {
-		rating, exists := (*movies)[movieID].UserRatings[cfg.Input]
+	userRatings := make(map[int]float32)
+	for _, movieID := range movies.IDs() {
+		rating, exists := movies.Rating(movieID, cfg.Input)
 		if exists {
-			user.MovieRatings[movieID] = rating
+			userRatings[movieID] = rating
 		}
 	}
-	// Find top most similar movies for each movie the user has rated
+	return userRatings
+}
+
+// Returns the top K most similar movies of every movie of $userRatings the user liked, keyed by the liked movie
+func likedMovieNeighbors(cfg *config.Config, userRatings map[int]float32, movies *util.RatingTable, neighbors *model.MovieNeighbors) map[int]map[int]model.SimilarMovie {
 	similarMoviesMap := make(map[int]map[int]model.SimilarMovie, 0)
-	// A movie is recommendable when its similar to at least one movie rated by the selected user
-	recommendableMovies := make(map[int]bool, 0)
-	for movieID := range user.MovieRatings {
-		// Find similar movies only for movies the user liked
-		if user.MovieRatings[movieID] >= 4 {
+	for movieID := range userRatings {
+		// Find similar movies only for movies the user liked. In implicit mode every interaction is positive
+		if cfg.Implicit || userRatings[movieID] >= likedRating {
 			// Find the top k most similar movies to movieID
-			similarMovies := findSimilarMovies(cfg, movieID, movies, cfg.K)
+			similarMovies := getSimilarMovies(cfg, movieID, movies, neighbors, cfg.K)
 			currentSimilarMoviesMap := make(map[int]model.SimilarMovie, len(similarMovies))
 			for _, movie := range similarMovies {
 				currentSimilarMoviesMap[movie.MovieID] = movie
 			}
-			for otherMovieID := range currentSimilarMoviesMap {
-				// Skip movies the user has already rated
-				if _, exists := user.MovieRatings[otherMovieID]; !exists {
-					recommendableMovies[otherMovieID] = true
-				}
-			}
 			similarMoviesMap[movieID] = currentSimilarMoviesMap
 		}
 	}
-	// Continue to recommendation part
-	ratingForecasts := make([]model.Rating, 0)
-	for movieID := range recommendableMovies {
-		numerator, denominator := 0.0, 0.0
-		for ratedMovieID, similarMovies := range similarMoviesMap {
-			if _, exists := similarMovies[movieID]; exists {
-				numerator += float64(user.MovieRatings[ratedMovieID]) * float64(similarMovies[movieID].Similarity)
-				denominator += float64(similarMovies[movieID].Similarity)
-			}
+	return similarMoviesMap
+}
+
+// Forecasts the rating of $movieID from the liked movies it's similar to, returning false if it's similar to none of them
+func forecastItemRating(cfg *config.Config, userRatings map[int]float32, similarMoviesMap map[int]map[int]model.SimilarMovie, movieID int) (float64, bool) {
+	numerator, denominator := 0.0, 0.0
+	similar := false
+	for ratedMovieID, similarMovies := range similarMoviesMap {
+		if similarMovie, exists := similarMovies[movieID]; exists {
+			numerator += float64(userRatings[ratedMovieID]) * float64(similarMovie.Similarity)
+			denominator += float64(similarMovie.Similarity)
+			similar = true
 		}
-		ratingForecasts = append(ratingForecasts, model.Rating{
-			MovieID: movieID,
-			Rating:  float32(numerator / denominator),
-		})
 	}
-	// Sort recommended movies by forecasted rating in descending order
-	sort.SliceStable(ratingForecasts, func(i, j int) bool {
-		return ratingForecasts[i].Rating > ratingForecasts[j].Rating
-	})
-	if len(ratingForecasts) > cfg.Recommendations {
-		ratingForecasts = ratingForecasts[:cfg.Recommendations]
+	// In implicit mode the forecast is the average similarity to the movies the user interacted with
+	if cfg.Implicit {
+		denominator = float64(len(similarMoviesMap))
 	}
-	util.StopProfiling()
-	return ratingForecasts
+	return numerator / denominator, similar
 }
 
-func findSimilarMovies(cfg *config.Config, selectedMovieID int, movies *map[int]model.Movie, maxMovies ...int) []model.SimilarMovie {
+func findSimilarMovies(cfg *config.Config, selectedMovieID int, movies *util.RatingTable, maxMovies ...int) []model.SimilarMovie {
 	moviesToKeep := -1
 	if len(maxMovies) > 0 {
 		moviesToKeep = maxMovies[0]
 	}
-	selectedMovie := (*movies)[selectedMovieID]
-	// Slice of userIDs who have rated the selected movie
-	selectedMovieUsers := make([]int, 0, len(selectedMovie.UserRatings))
-	for userID := range selectedMovie.UserRatings {
-		selectedMovieUsers = append(selectedMovieUsers, userID)
-	}
+	// Ratings of the selected movie sorted by userID
+	selectedMovieVector := movies.Vector(selectedMovieID)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	// Divide movies into chunks to split the workload to multiple routines
-	movieIDs := make([]int, 0, len(*movies))
-	for movieID := range *movies {
+	movieIDs := make([]int, 0, movies.Len())
+	candidateIDs := getCandidateIDs(cfg, movies, func() []int { return getMovieCandidates(cfg, movies, selectedMovieID) })
+	for _, movieID := range candidateIDs {
 		if movieID == selectedMovieID {
 			continue
 		}
 		// Skip movie if it has no ratings
-		if len((*movies)[movieID].UserRatings) == 0 {
+		if movies.Count(movieID) == 0 {
 			continue
 		}
 		movieIDs = append(movieIDs, movieID)
@@ -102,75 +122,34 @@ func findSimilarMovies(cfg *config.Config, selectedMovieID int, movies *map[int]
 		cfg.NumThreads = len(movieIDs)
 	}
 	movieChunks := util.GenerateChunkFromSet(movieIDs, cfg.NumThreads)
-	// The final slice of similar movies from all routines
-	similarMovies := make([]model.SimilarMovie, 0, len(*movies))
+	// The top similar movies of every routine, to be merged once all routines finish
+	localTopMovies := make([][]model.SimilarMovie, 0, len(movieChunks))
 	for _, movieChunk := range movieChunks {
 		wg.Add(1)
 		go func(movieIDs []int) {
 			defer wg.Done()
-			// Slice of similar movies for the current routine
-			localSimilarMovies := make([]model.SimilarMovie, 0, len(movieIDs))
+			// Top similar movies of the current routine
+			localSimilarMovies := util.NewTopK(moviesToKeep, moreSimilarMovie)
 			for _, otherMovieID := range movieIDs {
-				otherMovie := (*movies)[otherMovieID]
-				// Slice of userIDs who have rated the other movie
-				otherMovieUsers := make([]int, 0, len(otherMovie.UserRatings))
-				for userID := range otherMovie.UserRatings {
-					otherMovieUsers = append(otherMovieUsers, userID)
-				}
+				otherMovieVector := movies.Vector(otherMovieID)
 				// Skip otherMovie if it has no common users rating it with selectedMovie
-				if len(algorithms.Intersection[int](selectedMovieUsers, otherMovieUsers)) == 0 {
+				if !algorithms.HasIntersection(selectedMovieVector.Keys, otherMovieVector.Keys) {
 					continue
 				}
 				// Finally, calculate the similarity using the requested similarity metric
-				var similarity float64
-				switch cfg.Similarity {
-				case "jaccard":
-					similarity = algorithms.JaccardSimilarity[int](selectedMovieUsers, otherMovieUsers)
-				case "dice":
-					similarity = algorithms.DiceSimilarity[int](selectedMovieUsers, otherMovieUsers)
-				case "cosine":
-					vectorA, vectorB := util.GetUserRatingVectors(selectedMovie, otherMovie, selectedMovieUsers, otherMovieUsers)
-					similarity = algorithms.CosineSimilarity[float32](vectorA, vectorB, algorithms.DotProductFloat32)
-				case "pearson":
-					vectorA, vectorB := util.GetUserRatingVectors(selectedMovie, otherMovie, selectedMovieUsers, otherMovieUsers)
-					similarity = (algorithms.PearsonSimilarity[float32](vectorA, vectorB) + 1) / 2
-				}
-				localSimilarMovies = append(localSimilarMovies, model.SimilarMovie{
+				similarity := sparseSimilarity(cfg.Similarity, selectedMovieVector, otherMovieVector)
+				localSimilarMovies.Push(model.SimilarMovie{
 					MovieID: otherMovieID, Similarity: similarity,
 				})
 			}
-			// Merge all local slices of similarMovies while protecting concurrent writing to shared struct
+			// Append local results while protecting shared struct from concurrent writing
 			mu.Lock()
-			similarMovies = append(similarMovies, localSimilarMovies...)
+			localTopMovies = append(localTopMovies, localSimilarMovies.Sorted())
 			mu.Unlock()
 		}(movieChunk)
 	}
 	// Wait for all routines to finish
 	wg.Wait()
-	updateMostSimilarMovies(&similarMovies, moviesToKeep)
-	return similarMovies
-}
-
-func updateMostSimilarMovies(movies *[]model.SimilarMovie, moviesToKeep int) {
-	/*
-		The following sort ensures that the same set of moviesToKeep movies will be
-		returned everytime. However this is based on the arbitrary assumption that
-		we sort	movies primarily on their similarity and secondarily by their ID.
-		Effectivelly this method tends to promote newer movies over older ones.
-		If we just use the similarity factor the last movies in the moviesToKeep-set
-		will be ordered randomly, thus affecting slightly the final similar movies map.
-	*/
-	// sort.SliceStable((*movies), func(i, j int) bool {
-	// 	if (*movies)[i].Similarity == (*movies)[j].Similarity {
-	// 		return (*movies)[i].MovieID > (*movies)[j].MovieID
-	// 	}
-	// 	return (*movies)[i].Similarity > (*movies)[j].Similarity
-	// })
-	// Sort similar movies by similarity in descending order and limit its size to moviesToKeep
-	sort.SliceStable((*movies), func(i, j int) bool {
-		return (*movies)[i].Similarity > (*movies)[j].Similarity
-	})
-	if moviesToKeep != -1 && len(*movies) > moviesToKeep {
-		(*movies) = (*movies)[:moviesToKeep]
-	}
+	// Keep the overall top moviesToKeep most similar movies
+	return util.MergeTopK(moviesToKeep, moreSimilarMovie, localTopMovies...)
 }
@@ -0,0 +1,171 @@
+package recommenders
+
+import (
+	"recommender/algorithms"
+	"recommender/config"
+	util "recommender/utils"
+	"sync"
+)
+
+// MinHash signatures and LSH index of one kind of entity (users, movies by raters or movies by tags)
+type lshTable struct {
+	index      *algorithms.LSHIndex
+	hasher     *algorithms.MinHasher
+	signatures map[int][]uint64
+}
+
+// View of the dataset and LSH parameters an LSH table was built for
+type lshKey struct {
+	maxRecords int
+	implicit   bool
+	threshold  float64
+	bands      int
+	rows       int
+}
+
+var (
+	userLSH     = util.NewLRUCache[lshKey, *lshTable](maxCachedViews)
+	movieLSH    = util.NewLRUCache[lshKey, *lshTable](maxCachedViews)
+	movieTagLSH = util.NewLRUCache[lshKey, *lshTable](maxCachedViews)
+	// MinHashers by number of bands and rows, shared by the tables of every view
+	lshHashers = util.NewLRUCache[[2]int, *algorithms.MinHasher](maxCachedViews)
+)
+
+// Builds every LSH index ahead of the first approximate request, eg. when the Web-Server starts
+func BuildLSHIndexes(cfg *config.Config, users *util.RatingTable, movies *util.RatingTable, movieTags *util.TagTable) {
+	getUserCandidates(cfg, users, 0)
+	getMovieCandidates(cfg, movies, 0)
+	getMovieTagCandidates(cfg, movieTags, 0)
+}
+
+// Returns the IDs of the users sharing an LSH bucket with $userID, based on the sets of movies they rated.
+// An anonymous user outside the dataset is looked up by the $outsideMovieIDs it rated instead.
+func getUserCandidates(cfg *config.Config, users *util.RatingTable, userID int, outsideMovieIDs ...int) []int {
+	outsideHashes := make([]uint64, 0, len(outsideMovieIDs))
+	for _, movieID := range outsideMovieIDs {
+		outsideHashes = append(outsideHashes, algorithms.HashInt(movieID))
+	}
+	return getLSHCandidates(cfg, userLSH, userID, func() map[int][]uint64 {
+		elementHashes := make(map[int][]uint64, users.Len())
+		for _, userID := range users.IDs() {
+			elementHashes[userID] = hashIntKeys(users.Vector(userID).Keys)
+		}
+		return elementHashes
+	}, outsideHashes)
+}
+
+// Returns the IDs of the movies sharing an LSH bucket with $movieID, based on the sets of users who rated them
+func getMovieCandidates(cfg *config.Config, movies *util.RatingTable, movieID int) []int {
+	return getLSHCandidates(cfg, movieLSH, movieID, func() map[int][]uint64 {
+		elementHashes := make(map[int][]uint64, movies.Len())
+		for _, movieID := range movies.IDs() {
+			elementHashes[movieID] = hashIntKeys(movies.Vector(movieID).Keys)
+		}
+		return elementHashes
+	}, nil)
+}
+
+// Returns the IDs of the movies sharing an LSH bucket with $movieID, based on the sets of their tags.
+// A tag profile outside the dataset is looked up by its $outsideTags instead.
+func getMovieTagCandidates(cfg *config.Config, movieTags *util.TagTable, movieID int, outsideTags ...string) []int {
+	outsideHashes := make([]uint64, 0, len(outsideTags))
+	for _, tag := range outsideTags {
+		outsideHashes = append(outsideHashes, algorithms.HashString(tag))
+	}
+	return getLSHCandidates(cfg, movieTagLSH, movieID, func() map[int][]uint64 {
+		elementHashes := make(map[int][]uint64, movieTags.Len())
+		for _, movieID := range movieTags.IDs() {
+			tagCounts := movieTags.Occurrences(movieID)
+			hashes := make([]uint64, 0, len(tagCounts))
+			for tag := range tagCounts {
+				hashes = append(hashes, algorithms.HashString(tag))
+			}
+			elementHashes[movieID] = hashes
+		}
+		return elementHashes
+	}, outsideHashes)
+}
+
+/*
+Returns the LSH candidates of $id, building the table of the view and LSH parameters of $cfg from $elementHashes first
+if it isn't cached yet. When $outsideHashes isn't empty, the candidates are the ones of an entity outside the dataset
+made of these elements.
+*/
+func getLSHCandidates(cfg *config.Config, tables *util.LRUCache[lshKey, *lshTable], id int, elementHashes func() map[int][]uint64, outsideHashes []uint64) []int {
+	key := lshKey{maxRecords: cfg.MaxRecords, implicit: cfg.Implicit, bands: cfg.Bands, rows: cfg.Rows}
+	if cfg.Implicit {
+		key.threshold = cfg.Threshold
+	}
+	table := tables.Get(key, func() *lshTable {
+		hasher := lshHashers.Get([2]int{cfg.Bands, cfg.Rows}, func() *algorithms.MinHasher {
+			return algorithms.NewMinHasher(cfg.Bands*cfg.Rows, 42)
+		})
+		return buildLSHTable(cfg, hasher, elementHashes())
+	})
+	if len(outsideHashes) > 0 {
+		return table.index.Candidates(table.hasher.Signature(outsideHashes))
+	}
+	signature, exists := table.signatures[id]
+	if !exists {
+		return []int{}
+	}
+	return table.index.Candidates(signature)
+}
+
+// Computes the MinHash signatures with $hasher in parallel and indexes them
+func buildLSHTable(cfg *config.Config, hasher *algorithms.MinHasher, elementHashes map[int][]uint64) *lshTable {
+	table := &lshTable{
+		index:      algorithms.NewLSHIndex(cfg.Bands, cfg.Rows),
+		hasher:     hasher,
+		signatures: make(map[int][]uint64, len(elementHashes)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// Divide entities into chunks to split the workload to multiple routines
+	ids := make([]int, 0, len(elementHashes))
+	for id := range elementHashes {
+		ids = append(ids, id)
+	}
+	numThreads := cfg.NumThreads
+	if numThreads > len(ids) {
+		numThreads = len(ids)
+	}
+	for _, idChunk := range util.GenerateChunkFromSet(ids, numThreads) {
+		wg.Add(1)
+		go func(ids []int) {
+			defer wg.Done()
+			localSignatures := make(map[int][]uint64, len(ids))
+			for _, id := range ids {
+				localSignatures[id] = hasher.Signature(elementHashes[id])
+			}
+			// Merge all local signatures while protecting concurrent writing to shared map
+			mu.Lock()
+			for id, signature := range localSignatures {
+				table.signatures[id] = signature
+			}
+			mu.Unlock()
+		}(idChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	for id, signature := range table.signatures {
+		table.index.Add(id, signature)
+	}
+	return table
+}
+
+// Returns the IDs to be compared with the selected entity: its LSH candidates in approximate mode or every ID otherwise
+func getCandidateIDs(cfg *config.Config, entities interface{ IDs() []int }, lshCandidates func() []int) []int {
+	if cfg.Approximate {
+		return lshCandidates()
+	}
+	return entities.IDs()
+}
+
+func hashIntKeys(keys []int) []uint64 {
+	hashes := make([]uint64, 0, len(keys))
+	for _, id := range keys {
+		hashes = append(hashes, algorithms.HashInt(id))
+	}
+	return hashes
+}
@@ -0,0 +1,63 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"sync"
+)
+
+/*
+Computes the cfg.K most similar movies of every movie with the cfg.Similarity metric. Movies
+are split into chunks processed in parallel, so every findSimilarMovies call is sequential.
+*/
+func ComputeMovieNeighbors(cfg *config.Config, movies *util.RatingTable) model.MovieNeighbors {
+	fmt.Printf("Computing the %d nearest %s neighbors of %d movies.\n", cfg.K, cfg.Similarity, movies.Len())
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// Divide movies into chunks to split the workload to multiple routines
+	movieIDs := movies.IDs()
+	numThreads := cfg.NumThreads
+	if numThreads > len(movieIDs) {
+		numThreads = len(movieIDs)
+	}
+	neighbors := make(model.MovieNeighbors, len(movieIDs))
+	for _, movieChunk := range util.GenerateChunkFromSet(movieIDs, numThreads) {
+		wg.Add(1)
+		go func(movieIDs []int) {
+			defer wg.Done()
+			movieCfg := config.Config{Similarity: cfg.Similarity, NumThreads: 1}
+			localNeighbors := make(model.MovieNeighbors, len(movieIDs))
+			for _, movieID := range movieIDs {
+				localNeighbors[movieID] = findSimilarMovies(&movieCfg, movieID, movies, cfg.K)
+			}
+			// Merge all local neighbors while protecting concurrent writing to shared map
+			mu.Lock()
+			for movieID, similarMovies := range localNeighbors {
+				neighbors[movieID] = similarMovies
+			}
+			mu.Unlock()
+		}(movieChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	return neighbors
+}
+
+/*
+Returns the most similar movies of $movieID, looked up in the $neighbors snapshot when it can
+be used and computed with findSimilarMovies otherwise. Snapshots hold explicit-rating
+similarities of the full dataset, so implicit and approximate requests always compute them.
+*/
+func getSimilarMovies(cfg *config.Config, movieID int, movies *util.RatingTable, neighbors *model.MovieNeighbors, maxMovies ...int) []model.SimilarMovie {
+	if neighbors != nil && !cfg.Implicit && !cfg.Approximate {
+		if similarMovies, exists := (*neighbors)[movieID]; exists {
+			if len(maxMovies) > 0 && maxMovies[0] != -1 && len(similarMovies) > maxMovies[0] {
+				similarMovies = similarMovies[:maxMovies[0]]
+			}
+			return similarMovies
+		}
+	}
+	return findSimilarMovies(cfg, movieID, movies, maxMovies...)
+}
@@ -0,0 +1,174 @@
+package recommenders
+
+import (
+	"cmp"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"slices"
+)
+
+/*
+Forecasts the rating of $movieID by the Input user (or profile) the way the user-user or item-item algorithm would
+if it recommended the movie, ie. from the top K neighbours of the user who rated it or from the movies the user liked
+whose top K neighbours include it. Movies the user already rated are forecast as well.
+*/
+func PredictRating(cfg *config.Config, movieID int, users *util.RatingTable, movies *util.RatingTable, neighbors *model.MovieNeighbors, index *util.DatasetIndex) model.RatingPrediction {
+	if cfg.Algorithm == "item" {
+		_, movies, _ = implicitRatings(cfg, nil, movies, index)
+	} else {
+		users, _, index = implicitRatings(cfg, users, nil, index)
+	}
+	prediction := model.RatingPrediction{UserID: cfg.Input, MovieID: movieID, Neighbors: make([]model.PredictionNeighbor, 0)}
+	switch cfg.Algorithm {
+	case "user":
+		userRatings := users.Ratings(cfg.Input)
+		userVector := users.Vector(cfg.Input)
+		if cfg.Profile != nil {
+			userRatings = profileRatings(cfg)
+			userVector = algorithms.NewSparseVector(userRatings)
+		}
+		similarUsers := findSimilarUsers(cfg, users, index)
+		totalSimilarity := 0.0
+		for _, similarUser := range similarUsers {
+			totalSimilarity += similarUser.Similarity
+			if rating, exists := users.Rating(similarUser.UserID, movieID); exists {
+				prediction.Neighbors = append(prediction.Neighbors, model.PredictionNeighbor{
+					ID:         similarUser.UserID,
+					Similarity: similarUser.Similarity,
+					Rating:     rating,
+					Overlap:    algorithms.IntersectionSize(userVector.Keys, users.Vector(similarUser.UserID).Keys),
+				})
+			}
+		}
+		prediction.Rating, prediction.Predicted = forecastUserRating(cfg, users, similarUsers, totalSimilarity, movieID)
+		prediction.Actual = userRatings[movieID]
+	case "item":
+		userRatings := itemInputRatings(cfg, movies)
+		similarMoviesMap := likedMovieNeighbors(cfg, userRatings, movies, neighbors)
+		movieVector := movies.Vector(movieID)
+		for ratedMovieID, similarMovies := range similarMoviesMap {
+			if similarMovie, exists := similarMovies[movieID]; exists {
+				prediction.Neighbors = append(prediction.Neighbors, model.PredictionNeighbor{
+					ID:         ratedMovieID,
+					Similarity: similarMovie.Similarity,
+					Rating:     userRatings[ratedMovieID],
+					Overlap:    algorithms.IntersectionSize(movieVector.Keys, movies.Vector(ratedMovieID).Keys),
+				})
+			}
+		}
+		prediction.Rating, prediction.Predicted = forecastItemRating(cfg, userRatings, similarMoviesMap, movieID)
+		prediction.Actual = userRatings[movieID]
+	}
+	if !prediction.Predicted {
+		prediction.Rating = 0
+	}
+	slices.SortFunc(prediction.Neighbors, func(a, b model.PredictionNeighbor) int {
+		if a.Similarity != b.Similarity {
+			return cmp.Compare(b.Similarity, a.Similarity)
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return prediction
+}
+
+/*
+Compares the ratings of the Input user and $otherID, or of the Input movie and $otherID if the InputType is movie,
+under every similarity metric, except pearson for implicit feedback. If a Similarity metric is given, $otherID is also ranked among every neighbour of
+the Input, ie. every user or movie sharing at least one rating with it, which costs one similarity per neighbour.
+*/
+func ComparePair(cfg *config.Config, otherID int, users *util.RatingTable, movies *util.RatingTable, index *util.DatasetIndex) model.PairSimilarity {
+	inputIsMovie := cfg.InputType == "movie"
+	var vector, otherVector algorithms.SparseVector[int, float32]
+	if inputIsMovie {
+		vector, otherVector = movies.Vector(cfg.Input), movies.Vector(otherID)
+	} else {
+		vector, otherVector = users.Vector(cfg.Input), users.Vector(otherID)
+	}
+	if cfg.Implicit {
+		// Only the two vectors are compared, so there is no need for the implicit feedback of every other one
+		vector, otherVector = util.ToImplicitVector(vector, cfg.Threshold), util.ToImplicitVector(otherVector, cfg.Threshold)
+	}
+	pair := model.PairSimilarity{
+		ID:           cfg.Input,
+		OtherID:      otherID,
+		Ratings:      vector.Len(),
+		OtherRatings: otherVector.Len(),
+		Overlap:      algorithms.IntersectionSize(vector.Keys, otherVector.Keys),
+		Metrics:      make(map[string]float64, len(config.SimilarityMetrics)),
+		Metric:       cfg.Similarity,
+	}
+	for _, metric := range config.SimilarityMetrics {
+		// Implicit ratings are all 1.0, whose correlation is undefined
+		if metric != "pearson" || !cfg.Implicit {
+			pair.Metrics[metric] = sparseSimilarity(metric, vector, otherVector)
+		}
+	}
+	if cfg.Similarity == "" {
+		return pair
+	}
+	// Rank $otherID among every neighbour of the Input, the way findSimilarMovies and findSimilarUsers order them
+	if inputIsMovie {
+		_, movies, _ = implicitRatings(cfg, nil, movies, index)
+		candidateIDs := getCandidateIDs(cfg, movies, func() []int { return getMovieCandidates(cfg, movies, cfg.Input) })
+		pair.Neighbors, pair.Rank = rankNeighbor(cfg.Similarity, cfg.Input, otherID, movies, candidateIDs, nil)
+	} else {
+		users, _, index = implicitRatings(cfg, users, nil, index)
+		var overlaps map[int]int
+		if !cfg.Approximate {
+			overlaps = index.UserOverlaps(users.Vector(cfg.Input).Keys)
+		}
+		candidateIDs := make([]int, 0, len(overlaps))
+		for userID := range overlaps {
+			candidateIDs = append(candidateIDs, userID)
+		}
+		if overlaps == nil {
+			candidateIDs = getCandidateIDs(cfg, users, func() []int { return getUserCandidates(cfg, users, cfg.Input) })
+		}
+		pair.Neighbors, pair.Rank = rankNeighbor(cfg.Similarity, cfg.Input, otherID, users, candidateIDs, overlaps)
+	}
+	return pair
+}
+
+/*
+Counts the $candidateIDs sharing at least one rating with $inputID and returns their number along with the 1-based rank
+of $otherID among them (0 if it isn't one of them), by descending $metric similarity and ascending ID. The similarity of
+$otherID is computed first, so a single pass over the candidates ranks it without sorting them. $overlaps are the
+numbers of common ratings of the candidates from the index, if any.
+*/
+func rankNeighbor(metric string, inputID int, otherID int, ratings *util.RatingTable, candidateIDs []int, overlaps map[int]int) (int, int) {
+	vector := ratings.Vector(inputID)
+	similarity := func(id int) (float64, bool) {
+		otherVector := ratings.Vector(id)
+		if overlaps != nil {
+			overlap, exists := overlaps[id]
+			return overlapSimilarity(metric, overlap, vector, otherVector), exists
+		}
+		if !algorithms.HasIntersection(vector.Keys, otherVector.Keys) {
+			return 0, false
+		}
+		return sparseSimilarity(metric, vector, otherVector), true
+	}
+	otherSimilarity, isNeighbor := similarity(otherID)
+	neighbors, moreSimilar := 0, 0
+	isCandidate := false
+	for _, id := range candidateIDs {
+		if id == inputID {
+			continue
+		}
+		currentSimilarity, exists := similarity(id)
+		if !exists {
+			continue
+		}
+		neighbors++
+		isCandidate = isCandidate || id == otherID
+		if currentSimilarity > otherSimilarity || (currentSimilarity == otherSimilarity && id < otherID) {
+			moreSimilar++
+		}
+	}
+	if !isNeighbor || !isCandidate {
+		return neighbors, 0
+	}
+	return neighbors, moreSimilar + 1
+}
@@ -0,0 +1,126 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"sync"
+)
+
+// Ranks movies by their number of ratings
+func RecommendPopular(cfg *config.Config, movies *util.RatingTable) []model.Rating {
+	util.StartProfiling("popular")
+	popularMovies := rankMovies(cfg, movies, func(movieID int) (float64, bool) {
+		return float64(movies.Count(movieID)), movies.Count(movieID) > 0
+	})
+	util.StopProfiling()
+	return popularMovies
+}
+
+// Ranks movies by their Bayesian average rating, using the mean rating of all movies as prior
+func RecommendTopRated(cfg *config.Config, movies *util.RatingTable) []model.Rating {
+	util.StartProfiling("top-rated")
+	ratingSum, totalRatings := 0.0, 0
+	for _, movieID := range movies.IDs() {
+		vector := movies.Vector(movieID)
+		ratingSum += vector.Sum()
+		totalRatings += vector.Len()
+	}
+	globalMean := 0.0
+	if totalRatings > 0 {
+		globalMean = ratingSum / float64(totalRatings)
+	}
+	topRatedMovies := rankMovies(cfg, movies, func(movieID int) (float64, bool) {
+		vector := movies.Vector(movieID)
+		if vector.Len() == 0 {
+			return 0.0, false
+		}
+		return algorithms.BayesianAverage(vector.Mean(), vector.Len(), globalMean, cfg.Prior), true
+	})
+	util.StopProfiling()
+	return topRatedMovies
+}
+
+// Ranks movies by their number of ratings within the last cfg.Window days of the dataset
+func RecommendTrending(cfg *config.Config, movies *util.RatingTable) []model.Rating {
+	util.StartProfiling("trending")
+	// The dataset is a snapshot, so the window ends at its most recent rating instead of now
+	latestTimestamp := int64(0)
+	for _, movieID := range movies.IDs() {
+		for _, timestamp := range movies.Times(movieID) {
+			if timestamp > latestTimestamp {
+				latestTimestamp = timestamp
+			}
+		}
+	}
+	if latestTimestamp == 0 {
+		fmt.Println("Dataset has no rating timestamps. Please re-run preprocess to use trending recommendations.")
+		util.StopProfiling()
+		return []model.Rating{}
+	}
+	windowStart := latestTimestamp - int64(cfg.Window)*24*60*60
+	trendingMovies := rankMovies(cfg, movies, func(movieID int) (float64, bool) {
+		recentRatings := 0
+		for _, timestamp := range movies.Times(movieID) {
+			if timestamp >= windowStart {
+				recentRatings++
+			}
+		}
+		return float64(recentRatings), recentRatings > 0
+	})
+	util.StopProfiling()
+	return trendingMovies
+}
+
+/*
+Scores every movie in parallel with $scoreFunc and returns the top cfg.Recommendations movies.
+Movies for which $scoreFunc returns false are skipped, as well as movies already rated by
+cfg.Input when it refers to a known user, or by the anonymous profile of the request.
+*/
+func rankMovies(cfg *config.Config, movies *util.RatingTable, scoreFunc func(movieID int) (float64, bool)) []model.Rating {
+	fmt.Printf("Working with %d movie ratings.\n", movies.TotalRatings())
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// Divide movies into chunks to split the workload to multiple routines
+	movieIDs := make([]int, 0, movies.Len())
+	for _, movieID := range movies.IDs() {
+		// Skip movies the user has already rated
+		if _, exists := movies.Rating(movieID, cfg.Input); exists {
+			continue
+		}
+		if _, exists := cfg.Profile[movieID]; exists {
+			continue
+		}
+		movieIDs = append(movieIDs, movieID)
+	}
+	numThreads := cfg.NumThreads
+	if numThreads > len(movieIDs) {
+		numThreads = len(movieIDs)
+	}
+	movieChunks := util.GenerateChunkFromSet(movieIDs, numThreads)
+	// The top ranked movies of every routine, to be merged once all routines finish
+	localTopMovies := make([][]model.Rating, 0, len(movieChunks))
+	for _, movieChunk := range movieChunks {
+		wg.Add(1)
+		go func(movieIDs []int) {
+			defer wg.Done()
+			// Top ranked movies of the current routine
+			localRankedMovies := util.NewTopK(cfg.Recommendations, higherRating)
+			for _, movieID := range movieIDs {
+				if score, ok := scoreFunc(movieID); ok {
+					localRankedMovies.Push(model.Rating{MovieID: movieID, Rating: float32(score)})
+				}
+			}
+			// Append local results while protecting shared struct from concurrent writing
+			mu.Lock()
+			localTopMovies = append(localTopMovies, localRankedMovies.Sorted())
+			mu.Unlock()
+		}(movieChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	// Keep the overall top movies by score, breaking ties by ID
+	return util.MergeTopK(cfg.Recommendations, higherRating, localTopMovies...)
+}
@@ -0,0 +1,59 @@
+package recommenders
+
+import (
+	"recommender/algorithms"
+	"recommender/config"
+	util "recommender/utils"
+)
+
+// Min rating of a movie the input user liked, whose similar movies are recommended
+const likedRating = 4
+
+// Returns the ratings of the input user: the anonymous profile of the request if any, or the ones of cfg.Input otherwise
+func inputRatings(cfg *config.Config, users *util.RatingTable) map[int]float32 {
+	if cfg.Profile != nil {
+		return cfg.Profile
+	}
+	return users.Ratings(cfg.Input)
+}
+
+// Returns the anonymous profile of the request, with only its positive interactions as 1.0 ratings in implicit mode
+func profileRatings(cfg *config.Config) map[int]float32 {
+	if cfg.Implicit {
+		return util.ToImplicitRatings(cfg.Profile, cfg.Threshold)
+	}
+	return cfg.Profile
+}
+
+/*
+Returns $users and $movies as implicit feedback in implicit mode, where every positive interaction becomes a 1.0
+rating and the rest are ignored, along with the index of the implicit users. Returns them unchanged otherwise.
+Either of $users and $movies may be nil when the caller doesn't need it.
+*/
+func implicitRatings(cfg *config.Config, users *util.RatingTable, movies *util.RatingTable, index *util.DatasetIndex) (*util.RatingTable, *util.RatingTable, *util.DatasetIndex) {
+	if !cfg.Implicit {
+		return users, movies, index
+	}
+	if movies != nil {
+		movies = index.ImplicitMovies(cfg.Threshold, movies)
+	}
+	if users != nil {
+		users, index = index.ImplicitUsers(cfg.Threshold, users)
+	}
+	return users, movies, index
+}
+
+// Returns the sorted tag occurrences of all the movies the anonymous profile of the request liked
+func profileTagVector(cfg *config.Config, movieTags *util.TagTable, index *util.DatasetIndex) algorithms.SparseVector[string, int] {
+	tagCounts := make(map[string]int)
+	for movieID, rating := range cfg.Profile {
+		if rating < likedRating {
+			continue
+		}
+		vector := index.TagVector(movieID, movieTags)
+		for i, tag := range vector.Keys {
+			tagCounts[tag] += vector.Values[i]
+		}
+	}
+	return algorithms.NewSparseVector(tagCounts)
+}
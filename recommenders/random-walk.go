@@ -0,0 +1,129 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"sync"
+)
+
+/*
+Ranks movies for a user with a 3-step random walk (user -> movie -> user -> movie) on the
+bipartite user-movie graph. Every rating above cfg.Threshold is an edge. For rp3beta the
+final scores are divided by the movie popularity raised to cfg.Beta.
+*/
+func RecommendBasedOnRandomWalk(cfg *config.Config, users *util.RatingTable, movies *util.RatingTable) []model.Rating {
+	fmt.Printf("Working with %d user ratings.\n", users.TotalRatings())
+	util.StartProfiling(cfg.Algorithm)
+	selectedUser := model.User{MovieRatings: inputRatings(cfg, users)}
+	selectedUserVector := algorithms.NewSparseVector(selectedUser.MovieRatings)
+	// The first step starts from the ratings of the input user, which may be an anonymous profile
+	movieWeights := randomWalkStep(cfg, map[int]float64{cfg.Input: 1.0}, func(int) algorithms.SparseVector[int, float32] { return selectedUserVector })
+	userWeights := randomWalkStep(cfg, movieWeights, movies.Vector)
+	movieWeights = randomWalkStep(cfg, userWeights, users.Vector)
+	// Keep only the top movies by walk probability
+	ratingForecasts := util.NewTopK(cfg.Recommendations, higherRating)
+	for movieID, weight := range movieWeights {
+		// Skip movies the user has already rated
+		if _, exists := selectedUser.MovieRatings[movieID]; exists {
+			continue
+		}
+		score := algorithms.PopularityPenalty(weight, countPositiveEdges(cfg, movies.Vector(movieID)), randomWalkBeta(cfg))
+		ratingForecasts.Push(model.Rating{MovieID: movieID, Rating: float32(score)})
+	}
+	util.StopProfiling()
+	return ratingForecasts.Sorted()
+}
+
+/*
+Ranks movies similar to the cfg.Input movie with a 2-step random walk (movie -> user -> movie),
+which is the item-item similarity of P3alpha, or of RP3beta when popularity is penalized.
+*/
+func RecommendSimilarByRandomWalk(cfg *config.Config, users *util.RatingTable, movies *util.RatingTable) []model.SimilarMovie {
+	fmt.Printf("Working with %d movie ratings.\n", movies.TotalRatings())
+	util.StartProfiling(cfg.Algorithm)
+	userWeights := randomWalkStep(cfg, map[int]float64{cfg.Input: 1.0}, movies.Vector)
+	movieWeights := randomWalkStep(cfg, userWeights, users.Vector)
+	// Keep only the top movies by walk probability
+	similarMovies := util.NewTopK(cfg.Recommendations, moreSimilarMovie)
+	for movieID, weight := range movieWeights {
+		if movieID == cfg.Input {
+			continue
+		}
+		similarity := algorithms.PopularityPenalty(weight, countPositiveEdges(cfg, movies.Vector(movieID)), randomWalkBeta(cfg))
+		similarMovies.Push(model.SimilarMovie{MovieID: movieID, Similarity: similarity})
+	}
+	util.StopProfiling()
+	return similarMovies.Sorted()
+}
+
+// Propagates the probability mass of $weights one step through the graph, where $edges returns the rated neighbours of a node
+func randomWalkStep(cfg *config.Config, weights map[int]float64, edges func(int) algorithms.SparseVector[int, float32]) map[int]float64 {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// Divide nodes into chunks to split the workload to multiple routines
+	nodeIDs := make([]int, 0, len(weights))
+	for nodeID := range weights {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	numThreads := cfg.NumThreads
+	if numThreads > len(nodeIDs) {
+		numThreads = len(nodeIDs)
+	}
+	nodeChunks := util.GenerateChunkFromSet(nodeIDs, numThreads)
+	// The final weights of the next step from all routines
+	nextWeights := make(map[int]float64)
+	for _, nodeChunk := range nodeChunks {
+		wg.Add(1)
+		go func(nodeIDs []int) {
+			defer wg.Done()
+			// Weights reached by the current routine
+			localWeights := make(map[int]float64)
+			for _, nodeID := range nodeIDs {
+				neighbours := edges(nodeID)
+				transition := weights[nodeID] * algorithms.TransitionProbability(countPositiveEdges(cfg, neighbours), cfg.Alpha)
+				if transition == 0 {
+					continue
+				}
+				for i, neighbourID := range neighbours.Keys {
+					if util.IsPositiveInteraction(float32(neighbours.Value(i)), cfg.Threshold) {
+						localWeights[neighbourID] += transition
+					}
+				}
+			}
+			// Merge all local weights while protecting concurrent writing to shared map
+			mu.Lock()
+			for nodeID, weight := range localWeights {
+				nextWeights[nodeID] += weight
+			}
+			mu.Unlock()
+		}(nodeChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	return nextWeights
+}
+
+// Returns the popularity penalty exponent. P3alpha doesn't penalize popular movies.
+func randomWalkBeta(cfg *config.Config) float64 {
+	if cfg.Algorithm == "rp3beta" {
+		return cfg.Beta
+	}
+	return 0.0
+}
+
+// Returns the number of ratings that count as edges of the graph
+func countPositiveEdges(cfg *config.Config, ratings algorithms.SparseVector[int, float32]) int {
+	if cfg.Threshold <= 0 {
+		return ratings.Len()
+	}
+	edges := 0
+	for i := range ratings.Keys {
+		if util.IsPositiveInteraction(float32(ratings.Value(i)), cfg.Threshold) {
+			edges++
+		}
+	}
+	return edges
+}
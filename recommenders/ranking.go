@@ -0,0 +1,27 @@
+package recommenders
+
+import model "recommender/models"
+
+// Orders movies by forecasted rating (or score) in descending order and by ID for equal ratings
+func higherRating(a model.Rating, b model.Rating) bool {
+	if a.Rating == b.Rating {
+		return a.MovieID < b.MovieID
+	}
+	return a.Rating > b.Rating
+}
+
+// Orders movies by similarity in descending order and by ID for equal similarities
+func moreSimilarMovie(a model.SimilarMovie, b model.SimilarMovie) bool {
+	if a.Similarity == b.Similarity {
+		return a.MovieID < b.MovieID
+	}
+	return a.Similarity > b.Similarity
+}
+
+// Orders users by similarity in descending order and by ID for equal similarities
+func moreSimilarUser(a model.SimilarUser, b model.SimilarUser) bool {
+	if a.Similarity == b.Similarity {
+		return a.UserID < b.UserID
+	}
+	return a.Similarity > b.Similarity
+}
@@ -0,0 +1,68 @@
+package recommenders
+
+import (
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"slices"
+)
+
+// Constant of reciprocal rank fusion, which dampens the gap between the first ranks
+const rrfK = 60
+
+/*
+Ranks the movies similar to every cfg.Seeds movie and dissimilar to the cfg.NegativeSeeds ones. $recommend returns every
+movie similar to the cfg.Input movie of its configuration, and is run once per seed. The similarities to the seeds are
+combined with the cfg.Aggregation strategy, weighted by cfg.SeedWeights:
+  - mean: weighted mean of the similarities, counting 0 for the seeds a movie isn't similar to
+  - max: highest weighted similarity
+  - sum: weighted sum of the similarities
+  - rrf: reciprocal rank fusion, ie. the weighted sum of 1 / (60 + rank) over the rankings of the seeds
+
+The score of a movie is the aggregation over the seeds minus the one over the negative seeds. Only movies similar to
+at least one seed are ranked, and seeds are never recommended.
+*/
+func RecommendSimilarToSeeds(cfg *config.Config, recommend func(seedCfg *config.Config) []model.SimilarMovie) []model.SimilarMovie {
+	seedScores := aggregateSeedSimilarities(cfg, cfg.Seeds, recommend)
+	negativeScores := aggregateSeedSimilarities(cfg, cfg.NegativeSeeds, recommend)
+	similarMovies := util.NewTopK(cfg.Recommendations, moreSimilarMovie)
+	for movieID, score := range seedScores {
+		if slices.Contains(cfg.Seeds, movieID) || slices.Contains(cfg.NegativeSeeds, movieID) {
+			continue
+		}
+		similarMovies.Push(model.SimilarMovie{MovieID: movieID, Similarity: score - negativeScores[movieID]})
+	}
+	return similarMovies.Sorted()
+}
+
+// Returns the {movieID:score} aggregation of the similarities of the movies similar to any of $seeds
+func aggregateSeedSimilarities(cfg *config.Config, seeds []int, recommend func(seedCfg *config.Config) []model.SimilarMovie) map[int]float64 {
+	scores := make(map[int]float64)
+	totalWeight := 0.0
+	for _, seed := range seeds {
+		weight, weighted := cfg.SeedWeights[seed]
+		if !weighted {
+			weight = 1.0
+		}
+		totalWeight += weight
+		// Every movie similar to the seed, with the parameters of the request
+		seedCfg := *cfg
+		seedCfg.Input, seedCfg.Seeds, seedCfg.NegativeSeeds, seedCfg.SeedWeights, seedCfg.Recommendations = seed, nil, nil, nil, -1
+		for rank, movie := range recommend(&seedCfg) {
+			switch cfg.Aggregation {
+			case "max":
+				scores[movie.MovieID] = max(scores[movie.MovieID], weight*movie.Similarity)
+			case "rrf":
+				scores[movie.MovieID] += weight / float64(rrfK+rank+1)
+			default:
+				scores[movie.MovieID] += weight * movie.Similarity
+			}
+		}
+	}
+	if cfg.Aggregation == "mean" || cfg.Aggregation == "" {
+		for movieID := range scores {
+			scores[movieID] /= totalWeight
+		}
+	}
+	return scores
+}
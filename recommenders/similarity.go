@@ -0,0 +1,64 @@
+package recommenders
+
+import (
+	"cmp"
+	"recommender/algorithms"
+)
+
+// Returns the similarity of two sparse vectors with the requested metric. Pearson is scaled from [-1, 1] to [0, 1].
+func sparseSimilarity[K cmp.Ordered, V algorithms.Number](metric string, vector1 algorithms.SparseVector[K, V], vector2 algorithms.SparseVector[K, V]) float64 {
+	switch metric {
+	case "jaccard":
+		return algorithms.JaccardSimilaritySorted(vector1.Keys, vector2.Keys)
+	case "dice":
+		return algorithms.DiceSimilaritySorted(vector1.Keys, vector2.Keys)
+	case "cosine":
+		return algorithms.CosineSimilaritySparse(vector1, vector2)
+	case "pearson":
+		return (algorithms.PearsonSimilaritySparse(vector1, vector2) + 1) / 2
+	}
+	return 0.0
+}
+
+/*
+Same as sparseSimilarity for vectors of tag occurrences, but Jaccard and Dice compare the tags with their repetitions,
+like algorithms.JaccardSimilarity and DiceSimilarity over the lists of tags of every movie
+*/
+func tagSimilarity(metric string, vector1 algorithms.SparseVector[string, int], vector2 algorithms.SparseVector[string, int]) float64 {
+	switch metric {
+	case "jaccard":
+		return algorithms.MultisetJaccardSimilarity(vector1, vector2)
+	case "dice":
+		return algorithms.MultisetDiceSimilarity(vector1, vector2)
+	}
+	return sparseSimilarity(metric, vector1, vector2)
+}
+
+// Same as sparseSimilarity, but Jaccard and Dice use the already known $intersection size of the vectors' keys
+func overlapSimilarity[K cmp.Ordered, V algorithms.Number](metric string, intersection int, vector1 algorithms.SparseVector[K, V], vector2 algorithms.SparseVector[K, V]) float64 {
+	switch metric {
+	case "jaccard":
+		return algorithms.JaccardSimilarityCounts(intersection, vector1.Len(), vector2.Len())
+	case "dice":
+		return algorithms.DiceSimilarityCounts(intersection, vector1.Len(), vector2.Len())
+	}
+	return sparseSimilarity(metric, vector1, vector2)
+}
+
+/*
+Same as sparseSimilarity of $vector1 to a vector of $length2 keys, from their $intersection size, $dotProduct
+and the $sum2 and $squaredSum2 of the second vector's values over their common keys, eg. as accumulated by ComputeAllPairs.
+*/
+func accumulatedSimilarity[K cmp.Ordered, V algorithms.Number](metric string, vector1 algorithms.SparseVector[K, V], length2 int, intersection int, dotProduct float64, sum2 float64, squaredSum2 float64) float64 {
+	switch metric {
+	case "jaccard":
+		return algorithms.JaccardSimilarityCounts(intersection, vector1.Len(), length2)
+	case "dice":
+		return algorithms.DiceSimilarityCounts(intersection, vector1.Len(), length2)
+	case "cosine":
+		return algorithms.CosineSimilarityOverlap(dotProduct, vector1.SquaredNorm(), squaredSum2)
+	case "pearson":
+		return (algorithms.PearsonSimilarityOverlap(float64(vector1.Len()), vector1.Sum(), vector1.SquaredNorm(), dotProduct, sum2, squaredSum2) + 1) / 2
+	}
+	return 0.0
+}
@@ -0,0 +1,82 @@
+package recommenders
+
+import (
+	"fmt"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	util "recommender/utils"
+	"sync"
+)
+
+// Deviation rows computed lazily per rated movie and reused until the dataset changes, keyed by the max users of the view
+var slopeOneRows = util.NewLRUCache[int, *sync.Map](maxCachedViews)
+
+func RecommendBasedOnSlopeOne(cfg *config.Config, users *util.RatingTable, movies *util.RatingTable) []model.Rating {
+	fmt.Printf("Working with %d user ratings.\n", users.TotalRatings())
+	util.StartProfiling("slopeone")
+	selectedUser := model.User{MovieRatings: inputRatings(cfg, users)}
+	ratedMovieIDs := make([]int, 0, len(selectedUser.MovieRatings))
+	for movieID := range selectedUser.MovieRatings {
+		ratedMovieIDs = append(ratedMovieIDs, movieID)
+	}
+	rows := getSlopeOneRows(cfg, ratedMovieIDs, users, movies)
+	// A movie is recommendable when it shares at least one user with a movie the selected user rated
+	recommendableMovies := make(map[int]bool, 0)
+	for _, row := range rows {
+		for movieID := range row.Counts {
+			if _, exists := selectedUser.MovieRatings[movieID]; !exists {
+				recommendableMovies[movieID] = true
+			}
+		}
+	}
+	// Keep only the top recommendations while forecasting
+	ratingForecasts := util.NewTopK(cfg.Recommendations, higherRating)
+	for movieID := range recommendableMovies {
+		if rating, exists := algorithms.WeightedSlopeOne(selectedUser.MovieRatings, rows, movieID); exists {
+			ratingForecasts.Push(model.Rating{MovieID: movieID, Rating: float32(rating)})
+		}
+	}
+	util.StopProfiling()
+	return ratingForecasts.Sorted()
+}
+
+// Returns the deviation rows of $movieIDs in the view of the dataset of the request, computing the ones missing from the cache in parallel
+func getSlopeOneRows(cfg *config.Config, movieIDs []int, users *util.RatingTable, movies *util.RatingTable) map[int]algorithms.SlopeOneRow {
+	cachedRows := slopeOneRows.Get(cfg.MaxRecords, func() *sync.Map { return &sync.Map{} })
+	rows := make(map[int]algorithms.SlopeOneRow, len(movieIDs))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	numThreads := cfg.NumThreads
+	if numThreads > len(movieIDs) {
+		numThreads = len(movieIDs)
+	}
+	movieChunks := util.GenerateChunkFromSet(movieIDs, numThreads)
+	for _, movieChunk := range movieChunks {
+		wg.Add(1)
+		go func(movieIDs []int) {
+			defer wg.Done()
+			// Rows of the current routine
+			localRows := make(map[int]algorithms.SlopeOneRow, len(movieIDs))
+			for _, movieID := range movieIDs {
+				if cachedRow, exists := cachedRows.Load(movieID); exists {
+					localRows[movieID] = cachedRow.(algorithms.SlopeOneRow)
+					continue
+				}
+				// Users missing from the current (possibly limited) dataset have no ratings, so they are ignored
+				row := algorithms.SlopeOneDeviations(movieID, movies.Vector(movieID), users.Vector)
+				cachedRows.Store(movieID, row)
+				localRows[movieID] = row
+			}
+			// Merge all local rows while protecting concurrent writing to shared map
+			mu.Lock()
+			for movieID, row := range localRows {
+				rows[movieID] = row
+			}
+			mu.Unlock()
+		}(movieChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	return rows
+}
@@ -6,38 +6,34 @@ import (
 	"recommender/config"
 	model "recommender/models"
 	util "recommender/utils"
-	"sort"
 	"sync"
 )
 
-var cachedTagStrings sync.Map
-
-func RecommendBasedOnTag(cfg *config.Config, movieTags *map[int]model.MovieTags) []model.SimilarMovie {
-	totalTags := 0
-	for movieID := range *movieTags {
-		for _, userTags := range (*movieTags)[movieID].UserTags {
-			totalTags += len(userTags.Tags)
-		}
-	}
-	fmt.Printf("Working with %d movie tags.\n", totalTags)
+func RecommendBasedOnTag(cfg *config.Config, movieTags *util.TagTable, index *util.DatasetIndex) []model.SimilarMovie {
+	fmt.Printf("Working with %d movie tags.\n", movieTags.TotalTags())
 	util.StartProfiling("tag")
-	selectedMovieTags := make([]string, 0)
-	for _, userTags := range (*movieTags)[cfg.Input].UserTags {
-		selectedMovieTags = append(selectedMovieTags, userTags.Tags...)
+	// Tag occurrences of the selected movie sorted by tag
+	selectedMovieVector := index.TagVector(cfg.Input, movieTags)
+	if cfg.Profile != nil {
+		// Content-based profile of an anonymous user, made of the tags of the movies it liked
+		selectedMovieVector = profileTagVector(cfg, movieTags, index)
 	}
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	// Divide movies into chunks to split the workload to multiple routines
-	movieIDs := make([]int, 0, len(*movieTags))
-	for movieID := range *movieTags {
-		if movieID == cfg.Input {
+	movieIDs := make([]int, 0, movieTags.Len())
+	// Only movies with at least one common tag can be similar to the selected movie
+	candidateIDs := index.TagCandidates(selectedMovieVector.Keys)
+	if cfg.Approximate || candidateIDs == nil {
+		candidateIDs = getCandidateIDs(cfg, movieTags, func() []int { return getMovieTagCandidates(cfg, movieTags, cfg.Input, selectedMovieVector.Keys...) })
+	}
+	for _, movieID := range candidateIDs {
+		// Skip the selected movie and the movies of the profile
+		if _, rated := cfg.Profile[movieID]; rated || movieID == cfg.Input {
 			continue
 		}
-		// Movies with not at least 1 common tag will always have 0 similarity
-		if len(algorithms.Intersection[string](
-			getAllMovieTagStrings(cfg.Input, (*movieTags)[cfg.Input]),
-			getAllMovieTagStrings(movieID, (*movieTags)[movieID]),
-		)) == 0 {
+		// Skip movies missing from the requested tags
+		if !movieTags.Has(movieID) {
 			continue
 		}
 		movieIDs = append(movieIDs, movieID)
@@ -46,98 +42,36 @@ func RecommendBasedOnTag(cfg *config.Config, movieTags *map[int]model.MovieTags)
 		cfg.NumThreads = len(movieIDs)
 	}
 	movieChunks := util.GenerateChunkFromSet(movieIDs, cfg.NumThreads)
-	// The final slice of similar movies from all routines
-	similarMovies := make([]model.SimilarMovie, 0, len(*movieTags))
+	// The top similar movies of every routine, to be merged once all routines finish
+	localTopMovies := make([][]model.SimilarMovie, 0, len(movieChunks))
 	for _, movieChunk := range movieChunks {
 		wg.Add(1)
 		go func(movieIDs []int) {
 			defer wg.Done()
-			// Slice of similar movies for the current routine
-			localSimilarMovies := make([]model.SimilarMovie, 0, len(movieIDs))
+			// Top similar movies of the current routine
+			localSimilarMovies := util.NewTopK(cfg.Recommendations, moreSimilarMovie)
 			for _, otherMovieID := range movieIDs {
-				otherMovieTags := make([]string, 0)
-				otherMovieUsersThatTagged := (*movieTags)[otherMovieID].UserTags
-				for _, userTags := range otherMovieUsersThatTagged {
-					otherMovieTags = append(otherMovieTags, userTags.Tags...)
-				}
-				// Skip movie if it has no tags
-				if len(otherMovieTags) == 0 {
+				otherMovieVector := index.TagVector(otherMovieID, movieTags)
+				// Movies with not at least 1 common tag will always have 0 similarity
+				if !algorithms.HasIntersection(selectedMovieVector.Keys, otherMovieVector.Keys) {
 					continue
 				}
 				// Finally, calculate the similarity using the requested similarity metric
-				var similarity float64
-				switch cfg.Similarity {
-				case "jaccard":
-					similarity = algorithms.JaccardSimilarity[string](selectedMovieTags, otherMovieTags)
-				case "dice":
-					similarity = algorithms.DiceSimilarity[string](selectedMovieTags, otherMovieTags)
-				case "cosine":
-					vectorA, vectorB := util.GetTagOccurenceVectors(
-						countTagOccurrences((*movieTags)[cfg.Input]),
-						countTagOccurrences((*movieTags)[otherMovieID]),
-					)
-					similarity = algorithms.CosineSimilarity[int](vectorA, vectorB, algorithms.DotProductInt)
-				case "pearson":
-					vectorA, vectorB := util.GetTagOccurenceVectors(
-						countTagOccurrences((*movieTags)[cfg.Input]),
-						countTagOccurrences((*movieTags)[otherMovieID]),
-					)
-					similarity = (algorithms.PearsonSimilarity[int](vectorA, vectorB) + 1) / 2
-				}
-				localSimilarMovies = append(localSimilarMovies, model.SimilarMovie{
+				similarity := tagSimilarity(cfg.Similarity, selectedMovieVector, otherMovieVector)
+				localSimilarMovies.Push(model.SimilarMovie{
 					MovieID: otherMovieID, Similarity: similarity,
 				})
 			}
-			// Merge all local slices of similarMovies while protecting concurrent writing to shared struct
+			// Append local results while protecting shared struct from concurrent writing
 			mu.Lock()
-			similarMovies = append(similarMovies, localSimilarMovies...)
+			localTopMovies = append(localTopMovies, localSimilarMovies.Sorted())
 			mu.Unlock()
 		}(movieChunk)
 	}
 	// Wait for all routines to finish
 	wg.Wait()
-	// Sort recommended movies by similarity in descending order
-	sort.SliceStable(similarMovies, func(i, j int) bool {
-		return similarMovies[i].Similarity > similarMovies[j].Similarity
-	})
-	if len(similarMovies) > cfg.Recommendations {
-		similarMovies = similarMovies[:cfg.Recommendations]
-	}
+	// Keep the overall top recommendations
+	similarMovies := util.MergeTopK(cfg.Recommendations, moreSimilarMovie, localTopMovies...)
 	util.StopProfiling()
 	return similarMovies
 }
-
-func getAllMovieTagStrings(movieID int, movieTags model.MovieTags) []string {
-	// Generate a unique key for the movie to use in the cache
-	cacheKey := fmt.Sprintf("%d", movieID)
-	// Check if the tag strings are already cached
-	if cachedTags, exist := cachedTagStrings.Load(cacheKey); exist {
-		if cachedTagsSlice, exist := cachedTags.([]string); exist {
-			return cachedTagsSlice
-		}
-	}
-	// If tags aren't cached, get them and cache them for future use
-	movieTagStrings := gatherMovieTagStrings(movieTags)
-	cachedTagStrings.Store(cacheKey, movieTagStrings)
-	return movieTagStrings
-}
-
-// Function to gather the tag strings for a movie
-func gatherMovieTagStrings(movie model.MovieTags) []string {
-	tagStrings := make([]string, 0)
-	// Retrieving tags from countTagOccurrences ensures each tag is appended at most once
-	for tag := range countTagOccurrences(movie) {
-		tagStrings = append(tagStrings, tag)
-	}
-	return tagStrings
-}
-
-func countTagOccurrences(movieTags model.MovieTags) map[string]int {
-	tagCounts := make(map[string]int)
-	for _, userTags := range movieTags.UserTags {
-		for _, tag := range userTags.Tags {
-			tagCounts[tag]++
-		}
-	}
-	return tagCounts
-}
@@ -4,40 +4,35 @@ import (
 	"fmt"
 	"recommender/algorithms"
 	"recommender/config"
-	"recommender/helpers"
 	model "recommender/models"
 	util "recommender/utils"
-	"sort"
 	"sync"
 )
 
-func RecommendBasedOnTitle(cfg *config.Config, movieTitles *map[int]model.MovieTitle) []model.SimilarMovie {
-	fmt.Printf("Working with %d movie titles.\n", len(*movieTitles))
+func RecommendBasedOnTitle(cfg *config.Config, movieTitles *util.TitleTable, index *util.DatasetIndex) []model.SimilarMovie {
+	fmt.Printf("Working with %d movie titles.\n", movieTitles.Len())
 	util.StartProfiling("title")
-	idfMap := make(map[string]float64, 0)
-	selectedMovieTFMap := make(map[string]float64, 0)
-	selectedMovieTitleTokens := make([]string, 0)
-	totalTitles := make([]string, 0, len(*movieTitles))
-	for _, movie := range *movieTitles {
-		totalTitles = append(totalTitles, movie.Title)
-	}
-	// Calculate IDF for all movie titles to be used for Cosine or Pearson
-	idfMap = algorithms.IDF(totalTitles)
+	// IDF of all movie titles to be used for Cosine or Pearson
+	idfMap := index.IDF(movieTitles)
 	// Calculate TF vector for the selected movie to be used for Cosine or Pearson
-	selectedMovieTFMap = algorithms.TF((*movieTitles)[cfg.Input].Title)
-	// Extract selected movie title tokens to be used for Jaccard or Dice
-	selectedMovieTitleTokens = helpers.ExtractTokensFromStr((*movieTitles)[cfg.Input].Title)
+	selectedMovieTFMap := algorithms.TF(movieTitles.Title(cfg.Input))
+	// Sorted set of the selected movie title tokens to be used for Jaccard or Dice
+	selectedMovieTitleTokens := index.Tokens(cfg.Input, movieTitles)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	// Divide movies into chunks to split the workload to multiple routines
-	movieIDs := make([]int, 0, len(*movieTitles))
-	for movieID := range *movieTitles {
+	movieIDs := make([]int, 0)
+	// Only movies with at least one common token can be similar to the selected movie
+	candidateIDs := index.TitleCandidates(selectedMovieTitleTokens)
+	if candidateIDs == nil {
+		candidateIDs = getCandidateIDs(cfg, movieTitles, nil)
+	}
+	for _, movieID := range candidateIDs {
 		if movieID == cfg.Input {
 			continue
 		}
-		movieTitleTokens := helpers.ExtractTokensFromStr((*movieTitles)[movieID].Title)
-		// Skip current movie if it has not at least one common token with the selected movie.
-		if len(algorithms.Intersection[string](selectedMovieTitleTokens, movieTitleTokens)) == 0 {
+		// Skip movies missing from the requested titles (eg. a subset) or without common tokens
+		if !movieTitles.Has(movieID) || !algorithms.HasIntersection(selectedMovieTitleTokens, index.Tokens(movieID, movieTitles)) {
 			continue
 		}
 		movieIDs = append(movieIDs, movieID)
@@ -47,51 +42,44 @@ func RecommendBasedOnTitle(cfg *config.Config, movieTitles *map[int]model.MovieT
 		numChunks = len(movieIDs)
 	}
 	movieChunks := util.GenerateChunkFromSet(movieIDs, numChunks)
-	// The final slice of similar movier from all routines
-	similarMovies := make([]model.SimilarMovie, 0, len(*movieTitles))
+	// The top similar movies of every routine, to be merged once all routines finish
+	localTopMovies := make([][]model.SimilarMovie, 0, len(movieChunks))
 	for _, movieChunk := range movieChunks {
 		wg.Add(1)
 		go func(movieIDs []int) {
 			defer wg.Done()
-			// Slice of similar movies for the current routine
-			localSimilarMovies := make([]model.SimilarMovie, 0, len(movieIDs))
+			// Top similar movies of the current routine
+			localSimilarMovies := util.NewTopK(cfg.Recommendations, moreSimilarMovie)
 			for _, otherMovieID := range movieIDs {
 				var similarity float64
 				switch cfg.Similarity {
 				case "jaccard":
-					otherMovieTitleTokens := helpers.ExtractTokensFromStr((*movieTitles)[otherMovieID].Title)
-					similarity = algorithms.JaccardSimilarity[string](selectedMovieTitleTokens, otherMovieTitleTokens)
+					similarity = algorithms.JaccardSimilaritySorted(selectedMovieTitleTokens, index.Tokens(otherMovieID, movieTitles))
 				case "dice":
-					otherMovieTitleTokens := helpers.ExtractTokensFromStr((*movieTitles)[otherMovieID].Title)
-					similarity = algorithms.DiceSimilarity[string](selectedMovieTitleTokens, otherMovieTitleTokens)
+					similarity = algorithms.DiceSimilaritySorted(selectedMovieTitleTokens, index.Tokens(otherMovieID, movieTitles))
 				case "cosine":
-					othetMovieTFMap := algorithms.TF((*movieTitles)[otherMovieID].Title)
+					othetMovieTFMap := algorithms.TF(movieTitles.Title(otherMovieID))
 					vectorA, vectorB := util.GetTfIdfVectors(idfMap, selectedMovieTFMap, othetMovieTFMap)
 					similarity = algorithms.CosineSimilarity[float64](vectorA, vectorB, algorithms.DotProductFloat64)
 				case "pearson":
-					othetMovieTFMap := algorithms.TF((*movieTitles)[otherMovieID].Title)
+					othetMovieTFMap := algorithms.TF(movieTitles.Title(otherMovieID))
 					vectorA, vectorB := util.GetTfIdfVectors(idfMap, selectedMovieTFMap, othetMovieTFMap)
 					similarity = (algorithms.PearsonSimilarity[float64](vectorA, vectorB) + 1) / 2
 				}
-				localSimilarMovies = append(localSimilarMovies, model.SimilarMovie{
+				localSimilarMovies.Push(model.SimilarMovie{
 					MovieID: otherMovieID, Similarity: similarity,
 				})
 			}
-			// Merge all local slices of similarMovies while protecting concurrent writing to shared struct
+			// Append local results while protecting shared struct from concurrent writing
 			mu.Lock()
-			similarMovies = append(similarMovies, localSimilarMovies...)
+			localTopMovies = append(localTopMovies, localSimilarMovies.Sorted())
 			mu.Unlock()
 		}(movieChunk)
 	}
 	// Wait for all routines to finish
 	wg.Wait()
-	// Sort recommended movies by similarity in descending order
-	sort.SliceStable(similarMovies, func(i, j int) bool {
-		return similarMovies[i].Similarity > similarMovies[j].Similarity
-	})
-	if len(similarMovies) > cfg.Recommendations {
-		similarMovies = similarMovies[:cfg.Recommendations]
-	}
+	// Keep the overall top recommendations
+	similarMovies := util.MergeTopK(cfg.Recommendations, moreSimilarMovie, localTopMovies...)
 	util.StopProfiling()
 	return similarMovies
 }
@@ -6,62 +6,92 @@ import (
 	"recommender/config"
 	model "recommender/models"
 	util "recommender/utils"
-	"sort"
+	"slices"
 	"sync"
 )
 
-func RecommendBasedOnUser(cfg *config.Config, users *map[int]model.User, movieTitles *map[int]model.MovieTitle) []model.Rating {
-	totalRatings := 0
-	for _, user := range *users {
-		totalRatings += len(user.MovieRatings)
-	}
-	fmt.Printf("Working with %d user ratings.\n", totalRatings)
+func RecommendBasedOnUser(cfg *config.Config, users *util.RatingTable, movieTitles *util.TitleTable, index *util.DatasetIndex) []model.Rating {
+	fmt.Printf("Working with %d user ratings.\n", users.TotalRatings())
 	util.StartProfiling("user")
-	selectedUser := (*users)[cfg.Input]
-	similarUsers := findSimilarUsers(cfg, users)
-	ratingForecasts := make([]model.Rating, 0)
-	for movieID := range *movieTitles {
+	users, _, index = implicitRatings(cfg, users, nil, index)
+	// Ratings of the selected user sorted by movieID
+	selectedUserVector := users.Vector(cfg.Input)
+	if cfg.Profile != nil {
+		selectedUserVector = algorithms.NewSparseVector(profileRatings(cfg))
+	}
+	similarUsers := findSimilarUsers(cfg, users, index)
+	totalSimilarity := 0.0
+	for _, similarUser := range similarUsers {
+		totalSimilarity += similarUser.Similarity
+	}
+	// Keep only the top recommendations while forecasting
+	ratingForecasts := util.NewTopK(cfg.Recommendations, higherRating)
+	for _, movieID := range movieTitles.IDs() {
 		// Skip movies the user has already rated
-		if _, exists := selectedUser.MovieRatings[movieID]; !exists {
-			numerator, denominator := float64(0), float64(0)
-			for _, similarUser := range similarUsers {
-				// Only consider (similar) users who have rated this movie
-				if rating, exists := (*users)[similarUser.UserID].MovieRatings[movieID]; exists {
-					numerator += float64(rating) * float64(similarUser.Similarity)
-					denominator += float64(similarUser.Similarity)
-				}
-			}
-			if denominator != 0 {
-				// At least one (similar) user must have rated the movie in order to forecast
-				ratingForecasts = append(ratingForecasts, model.Rating{
-					MovieID: movieID, Rating: float32(numerator / denominator),
+		if _, exists := slices.BinarySearch(selectedUserVector.Keys, movieID); !exists {
+			// At least one (similar) user must have rated the movie in order to forecast
+			if rating, exists := forecastUserRating(cfg, users, similarUsers, totalSimilarity, movieID); exists {
+				ratingForecasts.Push(model.Rating{
+					MovieID: movieID, Rating: float32(rating),
 				})
 			}
 		}
 	}
-	// Sort recommended movies by forecasted rating in descending order
-	sort.SliceStable(ratingForecasts, func(i, j int) bool {
-		return ratingForecasts[i].Rating > ratingForecasts[j].Rating
-	})
-	if len(ratingForecasts) > cfg.Recommendations {
-		ratingForecasts = ratingForecasts[:cfg.Recommendations]
-	}
 	util.StopProfiling()
-	return ratingForecasts
+	return ratingForecasts.Sorted()
 }
 
-func findSimilarUsers(cfg *config.Config, users *map[int]model.User) []model.SimilarUser {
-	selectedUser := (*users)[cfg.Input]
-	// Slice of movieIDs the selecter user has rated
-	selectedUserMovies := make([]int, 0, len(selectedUser.MovieRatings))
-	for movieID := range selectedUser.MovieRatings {
-		selectedUserMovies = append(selectedUserMovies, movieID)
+// Forecasts the rating of $movieID from the ones of the $similarUsers, returning false if none of them rated it
+func forecastUserRating(cfg *config.Config, users *util.RatingTable, similarUsers []model.SimilarUser, totalSimilarity float64, movieID int) (float64, bool) {
+	numerator, denominator := float64(0), float64(0)
+	for _, similarUser := range similarUsers {
+		// Only consider (similar) users who have rated this movie
+		if rating, exists := users.Rating(similarUser.UserID, movieID); exists {
+			numerator += float64(rating) * float64(similarUser.Similarity)
+			denominator += float64(similarUser.Similarity)
+		}
+	}
+	if denominator == 0 {
+		return 0, false
+	}
+	// In implicit mode the forecast is the similarity-weighted share of neighbours
+	// who interacted with the movie, since every interaction is equal to 1.0
+	if cfg.Implicit {
+		denominator = totalSimilarity
+	}
+	return numerator / denominator, true
+}
+
+func findSimilarUsers(cfg *config.Config, users *util.RatingTable, index *util.DatasetIndex) []model.SimilarUser {
+	// Ratings of the selected user sorted by movieID
+	selectedUserVector := users.Vector(cfg.Input)
+	// Profile movies, to find the LSH candidates of an anonymous user
+	profileMovieIDs := make([]int, 0)
+	if cfg.Profile != nil {
+		selectedUserVector = algorithms.NewSparseVector(profileRatings(cfg))
+		for movieID := range cfg.Profile {
+			profileMovieIDs = append(profileMovieIDs, movieID)
+		}
 	}
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	// Divide users into chunks to split the workload to multiple routines
-	userIDs := make([]int, 0, len(*users))
-	for userID := range *users {
+	userIDs := make([]int, 0, users.Len())
+	candidateIDs := make([]int, 0)
+	// Number of common movies of every user who rated at least one movie of $selectedUser
+	var overlaps map[int]int
+	if !cfg.Approximate {
+		overlaps = index.UserOverlaps(selectedUserVector.Keys)
+	}
+	if overlaps != nil {
+		for userID := range overlaps {
+			candidateIDs = append(candidateIDs, userID)
+		}
+		slices.Sort(candidateIDs)
+	} else {
+		candidateIDs = getCandidateIDs(cfg, users, func() []int { return getUserCandidates(cfg, users, cfg.Input, profileMovieIDs...) })
+	}
+	for _, userID := range candidateIDs {
 		if userID == cfg.Input {
 			continue
 		}
@@ -71,65 +101,42 @@ func findSimilarUsers(cfg *config.Config, users *map[int]model.User) []model.Sim
 		cfg.NumThreads = len(userIDs)
 	}
 	userChunks := util.GenerateChunkFromSet(userIDs, cfg.NumThreads)
-	// The final slice of similar users from all routines
-	similarUsers := make([]model.SimilarUser, 0, len(*users))
+	// The top-k similar users of every routine, to be merged once all routines finish
+	localTopUsers := make([][]model.SimilarUser, 0, len(userChunks))
 	for _, userChunk := range userChunks {
 		wg.Add(1)
 		go func(userIDs []int) {
 			defer wg.Done()
-			// Slice of similar users for the current routine
-			localSimilarUsers := make([]model.SimilarUser, 0, len(userIDs))
+			// Top-k similar users of the current routine
+			localSimilarUsers := util.NewTopK(cfg.K, moreSimilarUser)
 			// Calculate the similarity to $selectedUser for every other user in $userChunk
 			for _, otherUserID := range userIDs {
-				user := (*users)[otherUserID]
-				userMovies := make([]int, 0, len(user.MovieRatings))
-				// Store all movies rated by the current user
-				for movieID := range user.MovieRatings {
-					userMovies = append(userMovies, movieID)
-				}
-				// Skip current user if he has rated 0 common movies with $selectedUser
-				if len(algorithms.Intersection[int](selectedUserMovies, userMovies)) == 0 {
-					continue
-				}
-				// Finally, calculate the similarity using the requested similarity metric
+				userVector := users.Vector(otherUserID)
 				var similarity float64
-				switch cfg.Similarity {
-				case "jaccard":
-					similarity = algorithms.JaccardSimilarity[int](selectedUserMovies, userMovies)
-				case "dice":
-					similarity = algorithms.DiceSimilarity[int](selectedUserMovies, userMovies)
-				case "cosine":
-					vectorA, vectorB := util.GetMovieRatingVectors(selectedUser, user, selectedUserMovies, userMovies)
-					similarity = algorithms.CosineSimilarity[float32](vectorA, vectorB, algorithms.DotProductFloat32)
-				case "pearson":
-					vectorA, vectorB := util.GetMovieRatingVectors(selectedUser, user, selectedUserMovies, userMovies)
-					similarity = (algorithms.PearsonSimilarity[float32](vectorA, vectorB) + 1) / 2
+				if overlaps != nil {
+					// Candidates from the index have rated at least one common movie with $selectedUser
+					similarity = overlapSimilarity(cfg.Similarity, overlaps[otherUserID], selectedUserVector, userVector)
+				} else {
+					// Skip current user if he has rated 0 common movies with $selectedUser
+					if !algorithms.HasIntersection(selectedUserVector.Keys, userVector.Keys) {
+						continue
+					}
+					// Finally, calculate the similarity using the requested similarity metric
+					similarity = sparseSimilarity(cfg.Similarity, selectedUserVector, userVector)
 				}
-				localSimilarUsers = append(localSimilarUsers, model.SimilarUser{
+				localSimilarUsers.Push(model.SimilarUser{
 					UserID:     otherUserID,
 					Similarity: similarity,
 				})
 			}
-			// Keep the top-k most similar users this routine found
-			updateMostSimilarUsers(&localSimilarUsers, cfg.K)
 			// Append local results while protecting shared struct from concurrent writing
 			mu.Lock()
-			similarUsers = append(similarUsers, localSimilarUsers...)
+			localTopUsers = append(localTopUsers, localSimilarUsers.Sorted())
 			mu.Unlock()
 		}(userChunk)
 	}
 	// Wait for all routines to finish
 	wg.Wait()
-	updateMostSimilarUsers(&similarUsers, cfg.K)
-	return similarUsers
-}
-
-func updateMostSimilarUsers(users *[]model.SimilarUser, usersToKeep int) {
-	// Sort similar movies by similarity in descending order and trim its size to usersToKeep
-	sort.SliceStable((*users), func(i, j int) bool {
-		return (*users)[i].Similarity > (*users)[j].Similarity
-	})
-	if len(*users) > usersToKeep {
-		(*users) = (*users)[:usersToKeep]
-	}
+	// Keep the overall top-k most similar users
+	return util.MergeTopK(cfg.K, moreSimilarUser, localTopUsers...)
 }
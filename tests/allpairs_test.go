@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"bytes"
+	"recommender/algorithms"
+	"recommender/config"
+	model "recommender/models"
+	"recommender/recommenders"
+	util "recommender/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeAllPairs(t *testing.T) {
+	movies := map[int]model.Movie{
+		1: {UserRatings: map[int]float32{1: 4.0, 2: 3.0, 3: 5.0}},
+		2: {UserRatings: map[int]float32{1: 2.0, 3: 1.0, 4: 4.5}},
+		3: {UserRatings: map[int]float32{2: 5.0}},
+		4: {UserRatings: map[int]float32{5: 1.0}},
+	}
+	table := util.NewMovieTable(movies)
+	for _, similarity := range config.SimilarityMetrics {
+		cfg := config.Config{Similarity: similarity, NumThreads: 3}
+		edges := recommenders.ComputeAllPairs(&cfg, &table, 0)
+		// Every pair with a common user, in both directions, and none for movie 4
+		expected := make([]model.MovieEdge, 0)
+		for _, pair := range [][2]int{{1, 2}, {1, 3}, {2, 1}, {3, 1}} {
+			vector1 := algorithms.NewSparseVector(movies[pair[0]].UserRatings)
+			vector2 := algorithms.NewSparseVector(movies[pair[1]].UserRatings)
+			var value float64
+			switch similarity {
+			case "jaccard":
+				value = algorithms.JaccardSimilaritySorted(vector1.Keys, vector2.Keys)
+			case "dice":
+				value = algorithms.DiceSimilaritySorted(vector1.Keys, vector2.Keys)
+			case "cosine":
+				value = algorithms.CosineSimilaritySparse(vector1, vector2)
+			case "pearson":
+				value = (algorithms.PearsonSimilaritySparse(vector1, vector2) + 1) / 2
+			}
+			expected = append(expected, model.MovieEdge{MovieA: pair[0], MovieB: pair[1], Similarity: value})
+		}
+		if !reflect.DeepEqual(edges, expected) {
+			t.Errorf("ComputeAllPairs %s: Expected %v, got %v", similarity, expected, edges)
+		}
+	}
+
+	// Edges below the threshold are dropped
+	cfg := config.Config{Similarity: "jaccard", NumThreads: 2}
+	edges := recommenders.ComputeAllPairs(&cfg, &table, 0.5)
+	if len(edges) != 2 || edges[0].MovieA != 1 || edges[0].MovieB != 2 {
+		t.Errorf("ComputeAllPairs: Expected the 1-2 edges of similarity 0.5, got %v", edges)
+	}
+}
+
+func TestMovieEdgesFormats(t *testing.T) {
+	edges := []model.MovieEdge{{MovieA: 1, MovieB: 2, Similarity: 0.5}, {MovieA: 2, MovieB: 1, Similarity: 0.25}}
+
+	var binary bytes.Buffer
+	if err := util.WriteMovieEdgesBinary(&binary, edges); err != nil {
+		t.Fatalf("WriteMovieEdgesBinary: %v", err)
+	}
+	if binary.Len() != 32 {
+		t.Errorf("WriteMovieEdgesBinary: Expected 32 bytes, got %d", binary.Len())
+	}
+	decoded, err := util.ReadMovieEdgesBinary(&binary)
+	if err != nil || !reflect.DeepEqual(decoded, edges) {
+		t.Errorf("ReadMovieEdgesBinary: Expected %v, got %v (%v)", edges, decoded, err)
+	}
+
+	var csv strings.Builder
+	if err := util.WriteMovieEdgesCSV(&csv, edges); err != nil {
+		t.Fatalf("WriteMovieEdgesCSV: %v", err)
+	}
+	expected := "movieA,movieB,similarity\n1,2,0.5\n2,1,0.25\n"
+	if csv.String() != expected {
+		t.Errorf("WriteMovieEdgesCSV: Expected %q, got %q", expected, csv.String())
+	}
+}
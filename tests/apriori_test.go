@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"fmt"
+	"math"
+	"recommender/algorithms"
+	"testing"
+)
+
+func TestFrequentItemsets(t *testing.T) {
+	transactions := [][]int{
+		{1, 2, 5},
+		{2, 4},
+		{2, 3},
+		{1, 2, 4},
+		{1, 3},
+		{2, 3},
+		{1, 3},
+		{1, 2, 3, 5},
+		{1, 2, 3},
+	}
+
+	itemsets := algorithms.FrequentItemsets(transactions, 2.0/9.0, 3)
+
+	// Expected frequent itemsets with min count 2, as in "Data Mining: Concepts and Techniques"
+	expectedCounts := map[string]int{
+		"[1]": 6, "[2]": 7, "[3]": 6, "[4]": 2, "[5]": 2,
+		"[1 2]": 4, "[1 3]": 4, "[1 5]": 2, "[2 3]": 4, "[2 4]": 2, "[2 5]": 2,
+		"[1 2 3]": 2, "[1 2 5]": 2,
+	}
+	if len(itemsets) != len(expectedCounts) {
+		t.Errorf("Apriori: Expected %d frequent itemsets, got %d: %v", len(expectedCounts), len(itemsets), itemsets)
+		return
+	}
+	for _, itemset := range itemsets {
+		key := fmt.Sprint(itemset.Items)
+		if expectedCounts[key] != itemset.Count {
+			t.Errorf("Apriori: Expected count %d for itemset %s, got %d", expectedCounts[key], key, itemset.Count)
+		}
+	}
+}
+
+func TestAssociationRules(t *testing.T) {
+	transactions := [][]int{
+		{1, 2, 5},
+		{2, 4},
+		{2, 3},
+		{1, 2, 4},
+		{1, 3},
+		{2, 3},
+		{1, 3},
+		{1, 2, 3, 5},
+		{1, 2, 3},
+	}
+
+	itemsets := algorithms.FrequentItemsets(transactions, 2.0/9.0, 3)
+	rules := algorithms.AssociationRules(itemsets, len(transactions), 1.0)
+
+	// Only {4}=>2, {5}=>2, {5}=>1, {1,5}=>2 and {2,5}=>1 have a confidence of 100%
+	if len(rules) != 5 {
+		t.Errorf("Association rules: Expected 5 rules, got %d: %v", len(rules), rules)
+		return
+	}
+	tolerance := 0.000001
+	for _, rule := range rules {
+		if fmt.Sprint(rule.Antecedent) == "[5]" && rule.Consequent == 2 {
+			if diff := math.Abs(rule.Lift - 9.0/7.0); diff > tolerance {
+				t.Errorf("Association rules: Expected lift %f for {5}=>2, got %f", 9.0/7.0, rule.Lift)
+			}
+			if diff := math.Abs(rule.Support - 2.0/9.0); diff > tolerance {
+				t.Errorf("Association rules: Expected support %f for {5}=>2, got %f", 2.0/9.0, rule.Support)
+			}
+		}
+	}
+}
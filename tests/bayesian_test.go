@@ -0,0 +1,22 @@
+package tests
+
+import (
+	"math"
+	"recommender/algorithms"
+	"testing"
+)
+
+func TestBayesianAverage(t *testing.T) {
+	result := algorithms.BayesianAverage(4.5, 10, 3.5, 30)
+
+	tolerance := 0.000001
+	if diff := math.Abs(result - 3.75); diff > tolerance {
+		t.Errorf("Bayesian average: Expected 3.75, got %f", result)
+		return
+	}
+	// Without a prior the weighted rating equals the item mean
+	result = algorithms.BayesianAverage(4.5, 10, 3.5, 0)
+	if diff := math.Abs(result - 4.5); diff > tolerance {
+		t.Errorf("Bayesian average: Expected 4.5, got %f", result)
+	}
+}
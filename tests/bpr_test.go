@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"recommender/algorithms"
+	"reflect"
+	"testing"
+)
+
+func TestBPR(t *testing.T) {
+	// Users 1-3 share movies 10, 20 & 30 while users 4-6 share movies 40, 50 & 60
+	interactions := map[int][]int{
+		1: {10, 20, 30},
+		2: {10, 20, 30},
+		3: {10, 20},
+		4: {40, 50, 60},
+		5: {40, 50, 60},
+		6: {40, 50},
+	}
+
+	model := algorithms.TrainBPR(interactions, algorithms.DefaultBPRParams())
+
+	if len(model.ItemIDs) != 6 {
+		t.Errorf("BPR: Expected 6 items in the model, got %d", len(model.ItemIDs))
+		return
+	}
+	sameGroupScore, _ := model.Score(3, 30)
+	otherGroupScore, _ := model.Score(3, 60)
+	if sameGroupScore <= otherGroupScore {
+		t.Errorf("BPR: Expected movie 30 (%f) to rank above movie 60 (%f) for user 3", sameGroupScore, otherGroupScore)
+	}
+	sameGroupScore, _ = model.Score(6, 60)
+	otherGroupScore, _ = model.Score(6, 30)
+	if sameGroupScore <= otherGroupScore {
+		t.Errorf("BPR: Expected movie 60 (%f) to rank above movie 30 (%f) for user 6", sameGroupScore, otherGroupScore)
+	}
+	if _, exists := model.Score(7, 10); exists {
+		t.Errorf("BPR: Expected unknown user to have no score")
+	}
+}
+
+func TestBPRFoldIn(t *testing.T) {
+	interactions := map[int][]int{
+		1: {10, 20, 30},
+		2: {10, 20, 30},
+		3: {10, 20},
+		4: {40, 50, 60},
+		5: {40, 50, 60},
+		6: {40, 50},
+	}
+	model := algorithms.TrainBPR(interactions, algorithms.DefaultBPRParams())
+	itemFactors := append([]float64{}, model.ItemFactors[0]...)
+
+	// An anonymous user who liked movies of the second group, and a movie unknown to the model
+	userFactors := model.FoldIn([]int{40, 50, 99}, algorithms.DefaultBPRParams())
+	sameGroupScore, _ := model.ScoreFactors(userFactors, 60)
+	otherGroupScore, _ := model.ScoreFactors(userFactors, 30)
+	if sameGroupScore <= otherGroupScore {
+		t.Errorf("BPR: Expected movie 60 (%f) to rank above movie 30 (%f) for the folded in user", sameGroupScore, otherGroupScore)
+	}
+	if _, exists := model.ScoreFactors(userFactors, 99); exists {
+		t.Errorf("BPR: Expected unknown movie to have no score")
+	}
+	if !reflect.DeepEqual(model.ItemFactors[0], itemFactors) || len(model.UserIndex) != 6 {
+		t.Errorf("BPR: Expected the model to stay the same")
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"recommender/config"
+	"reflect"
+	"testing"
+)
+
+// A valid configuration with the CLI defaults
+func validConfig() config.Config {
+	return config.Config{
+		Recommendations: 10, Similarity: "cosine", Algorithm: "user", Input: 1, MaxRecords: -1, K: 128,
+		Prior: 10, Window: 30, InputType: "user", Alpha: 1.0, Beta: 0.5, Bands: 50, Rows: 2,
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.Config)
+		code   string
+		fields []string
+	}{
+		{"valid", func(cfg *config.Config) {}, "", nil},
+		{"similarity free", func(cfg *config.Config) { cfg.Algorithm, cfg.Similarity = "bpr", "" }, "", nil},
+		{"input free", func(cfg *config.Config) { cfg.Algorithm, cfg.Input = "popular", 0 }, "", nil},
+		{"seeds instead of input", func(cfg *config.Config) { cfg.Algorithm, cfg.Input, cfg.Seeds = "assoc", 0, []int{1, 2} }, "", nil},
+		{"missing", func(cfg *config.Config) { cfg.Recommendations, cfg.Similarity, cfg.Input = 0, "", 0 }, config.MissingParameter, []string{"recommendations", "similarity", "input"}},
+		{"unknown algorithm", func(cfg *config.Config) { cfg.Algorithm = "random" }, config.InvalidValue, []string{"algorithm"}},
+		{"unknown similarity", func(cfg *config.Config) { cfg.Similarity = "euclidean" }, config.InvalidValue, []string{"similarity"}},
+		{"negative recommendations", func(cfg *config.Config) { cfg.Recommendations, cfg.K = -5, 0 }, config.InvalidValue, []string{"recommendations", "k"}},
+		{"random walk", func(cfg *config.Config) { cfg.InputType, cfg.Alpha, cfg.Beta = "tag", 0, -1 }, config.InvalidValue, []string{"inputType", "alpha", "beta"}},
+		{"weighted seeds", func(cfg *config.Config) {
+			cfg.Algorithm, cfg.Seeds, cfg.NegativeSeeds, cfg.SeedWeights, cfg.Aggregation = "tag", []int{1, 2}, []int{3}, map[int]float64{1: 2, 3: 0.5}, "rrf"
+		}, "", nil},
+		{"negative seeds", func(cfg *config.Config) {
+			cfg.Seeds, cfg.NegativeSeeds, cfg.SeedWeights, cfg.Aggregation = []int{1}, []int{3}, map[int]float64{3: 1}, "median"
+		}, config.InvalidValue, []string{"negativeSeeds", "seedWeights", "aggregation"}},
+		{"seed weights", func(cfg *config.Config) {
+			cfg.Algorithm, cfg.Seeds, cfg.NegativeSeeds, cfg.SeedWeights = "title", []int{1, 2}, []int{2}, map[int]float64{1: 0}
+		}, config.InvalidValue, []string{"negativeSeeds", "seedWeights"}},
+		{"result filters", func(cfg *config.Config) {
+			cfg.Filters = config.ResultFilters{MinRatings: 50, Allow: []int{1, 2}, Deny: []int{2}, MinYear: 1990, MaxYear: 1990, ExcludeGenres: []string{"Horror"}}
+		}, "", nil},
+		{"invalid result filters", func(cfg *config.Config) {
+			cfg.Filters = config.ResultFilters{MinRatings: -1, Allow: []int{}, MinYear: 2000, MaxYear: 1990}
+		}, config.InvalidValue, []string{"minRatings", "allow", "maxYear"}},
+		{"prediction", func(cfg *config.Config) { cfg.Recommendations, cfg.Algorithm, cfg.Predict = 0, "item", 6 }, "", nil},
+		{"comparison", func(cfg *config.Config) {
+			cfg.Recommendations, cfg.Algorithm, cfg.Similarity, cfg.CompareTo = 0, "", "", 2
+		}, "", nil},
+		{"invalid prediction", func(cfg *config.Config) { cfg.Algorithm, cfg.Predict, cfg.CompareTo = "bpr", 6, 2 }, config.InvalidValue, []string{"algorithm", "compareTo"}},
+		{"invalid comparison", func(cfg *config.Config) { cfg.Algorithm, cfg.Similarity, cfg.CompareTo = "", "euclidean", -2 }, config.InvalidValue, []string{"similarity", "compareTo"}},
+		{"ranges", func(cfg *config.Config) { cfg.Prior, cfg.Window, cfg.Bands, cfg.Rows, cfg.MaxRecords = -1, 0, 0, -2, 0 }, config.InvalidValue, []string{"prior", "window", "bands", "rows", "maxRecords"}},
+	}
+	for _, test := range tests {
+		cfg := validConfig()
+		test.modify(&cfg)
+		fieldErrors := cfg.Validate()
+		if len(fieldErrors) != len(test.fields) {
+			t.Errorf("%s: expected errors for %v, got %v", test.name, test.fields, fieldErrors)
+			continue
+		}
+		for i, err := range fieldErrors {
+			if err.Code != test.code || err.Field != test.fields[i] || err.Message == "" {
+				t.Errorf("%s: expected a %s error for '%s', got %+v", test.name, test.code, test.fields[i], err)
+			}
+		}
+	}
+}
+
+func TestParseWeightedIDList(t *testing.T) {
+	ids, weights, err := config.ParseWeightedIDList(" 1:2, 260 ,1196:0.5,")
+	if err != nil || !reflect.DeepEqual(ids, []int{1, 260, 1196}) || !reflect.DeepEqual(weights, map[int]float64{1: 2, 1196: 0.5}) {
+		t.Errorf("ParseWeightedIDList: Expected 3 IDs and 2 weights, got %v %v %v", ids, weights, err)
+	}
+	if ids, weights, err := config.ParseWeightedIDList("1,2"); err != nil || len(ids) != 2 || weights != nil {
+		t.Errorf("ParseWeightedIDList: Expected 2 IDs without weights, got %v %v %v", ids, weights, err)
+	}
+	for _, list := range []string{"1:", "a:2", ":2", "1:2:3"} {
+		if _, _, err := config.ParseWeightedIDList(list); err == nil {
+			t.Errorf("ParseWeightedIDList: Expected an error for '%s'", list)
+		}
+	}
+}
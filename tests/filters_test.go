@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"recommender/config"
+	model "recommender/models"
+	"recommender/recommenders"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+func TestResultFilter(t *testing.T) {
+	movies := util.NewMovieTable(map[int]model.Movie{
+		1: {UserRatings: map[int]float32{1: 4, 2: 5, 3: 3}},
+		2: {UserRatings: map[int]float32{1: 2}},
+		3: {UserRatings: map[int]float32{2: 4, 3: 4}},
+		4: {UserRatings: map[int]float32{1: 5, 3: 1}},
+	})
+	titles := util.NewTitleTable(map[int]model.MovieTitle{
+		1: {Title: "Heat (1995)", Genres: []string{"Action", "Crime", "Thriller"}},
+		2: {Title: "Scream (1996)", Genres: []string{"Comedy", "Horror"}},
+		3: {Title: "Alien (1979)", Genres: []string{"Horror", "Sci-Fi"}},
+		4: {Title: "Primer", Genres: []string{"Sci-Fi"}},
+	})
+	movieTags := util.NewTagTable(map[int]model.MovieTags{
+		1: {UserTags: map[int]model.UserTags{1: {Tags: []string{"heist"}}, 2: {Tags: []string{"al pacino"}}}},
+		3: {UserTags: map[int]model.UserTags{1: {Tags: []string{"space", "al pacino"}}}},
+		4: {UserTags: map[int]model.UserTags{3: {Tags: []string{"time travel"}}}},
+	})
+	tests := []struct {
+		name     string
+		filters  config.ResultFilters
+		expected []int
+	}{
+		{"none", config.ResultFilters{}, []int{1, 2, 3, 4}},
+		{"min ratings", config.ResultFilters{MinRatings: 2}, []int{1, 3, 4}},
+		{"allow and deny", config.ResultFilters{Allow: []int{1, 2, 3}, Deny: []int{2}}, []int{1, 3}},
+		// Movies without a year never pass a year filter
+		{"years", config.ResultFilters{MinYear: 1990}, []int{1, 2}},
+		{"year range", config.ResultFilters{MinYear: 1970, MaxYear: 1995}, []int{1, 3}},
+		{"genres", config.ResultFilters{IncludeGenres: []string{"sci-fi", "crime"}, ExcludeGenres: []string{"HORROR"}}, []int{1, 4}},
+		{"tags", config.ResultFilters{IncludeTags: []string{"Al Pacino!", "time travel"}, ExcludeTags: []string{"space"}}, []int{1, 4}},
+	}
+	for _, test := range tests {
+		keep := recommenders.NewResultFilter(&test.filters, &movies, &titles, &movieTags)
+		kept := make([]int, 0)
+		for movieID := 1; movieID <= 4; movieID++ {
+			if keep(movieID) {
+				kept = append(kept, movieID)
+			}
+		}
+		if !reflect.DeepEqual(kept, test.expected) {
+			t.Errorf("%s: expected movies %v to pass, got %v", test.name, test.expected, kept)
+		}
+	}
+}
+
+func TestFilterResults(t *testing.T) {
+	ratings := []model.Rating{{MovieID: 4, Rating: 5}, {MovieID: 1, Rating: 4}, {MovieID: 3, Rating: 3}, {MovieID: 2, Rating: 2}}
+	movieID := func(rating model.Rating) int { return rating.MovieID }
+	odd := func(movieID int) bool { return movieID%2 == 1 }
+	if filtered := recommenders.FilterResults(ratings, movieID, odd, 1); !reflect.DeepEqual(filtered, ratings[1:2]) {
+		t.Errorf("Expected the top odd movie, got %v", filtered)
+	}
+	if filtered := recommenders.FilterResults(ratings, movieID, odd, -1); !reflect.DeepEqual(filtered, ratings[1:3]) {
+		t.Errorf("Expected every odd movie, got %v", filtered)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	model "recommender/models"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+var importTitles = util.NewTitleTable(map[int]model.MovieTitle{
+	1:    {Title: "Toy Story (1995)"},
+	29:   {Title: "City of Lost Children, The (Cité des enfants perdus, La) (1995)"},
+	32:   {Title: "Twelve Monkeys (a.k.a. 12 Monkeys) (1995)"},
+	2571: {Title: "Matrix, The (1999)"},
+	4973: {Title: "Amelie (Fabuleux destin d'Amélie Poulain, Le) (2001)"},
+	5618: {Title: "Spirited Away (Sen to Chihiro no kamikakushi) (2001)"},
+	6539: {Title: "Pirates of the Caribbean: The Curse of the Black Pearl (2003)"},
+	8000: {Title: "Toy Story (2010)"},
+})
+
+func TestTitleMatcher(t *testing.T) {
+	matcher := util.NewTitleMatcher(&importTitles)
+	tests := []struct {
+		title   string
+		year    int
+		movieID int
+	}{
+		{"Toy Story", 1995, 1},
+		{"Toy Story", 2010, 8000},
+		{"The Matrix", 1999, 2571},
+		{"the matrix", 2000, 2571},
+		{"The City of Lost Children", 1995, 29},
+		{"La Cité des Enfants Perdus", 1995, 29},
+		{"12 Monkeys", 1995, 32},
+		{"Amélie", 2001, 4973},
+		{"Le Fabuleux Destin d'Amélie Poulain", 2001, 4973},
+		{"Sen to Chihiro no Kamikakushi", 2001, 5618},
+		{"Pirates of the Caribbean - The Curse of the Black Pearl", 2003, 6539},
+		{"The Matrix", 2003, 0},
+		{"Heat", 1995, 0},
+	}
+	for _, test := range tests {
+		movieID, found := matcher.Match(test.title, test.year)
+		if movieID != test.movieID || found != (test.movieID != 0) {
+			t.Errorf("Match(%q, %d): Expected movie %d, got %d", test.title, test.year, test.movieID, movieID)
+		}
+	}
+	if name, year := util.SplitTitleYear("Twelve Monkeys (a.k.a. 12 Monkeys) (1995)"); name != "Twelve Monkeys (a.k.a. 12 Monkeys)" || year != 1995 {
+		t.Errorf("SplitTitleYear: Expected the name and 1995, got %q and %d", name, year)
+	}
+}
+
+func TestImportRatings(t *testing.T) {
+	directory := t.TempDir()
+	matcher := util.NewTitleMatcher(&importTitles)
+	tests := []struct {
+		name      string
+		content   string
+		source    string
+		profile   map[int]float32
+		unmatched []int
+	}{
+		{
+			"letterboxd",
+			"Date,Name,Year,Letterboxd URI,Rating\n" +
+				"2024-01-02,The Matrix,1999,https://boxd.it/29hc,4.5\n" +
+				"2024-01-03,Heat,1995,https://boxd.it/2bbs,5\n" +
+				"2024-01-04,\"Amélie\",2001,https://boxd.it/2b4e,\n" +
+				"2024-01-05,Toy Story,1995,https://boxd.it/2a9q,0.5\n",
+			util.Letterboxd, map[int]float32{2571: 4.5, 1: 0.5}, []int{3},
+		},
+		{
+			"imdb",
+			"Const,Your Rating,Date Rated,Title,Original Title,URL,Title Type,IMDb Rating,Runtime (mins),Year,Genres,Num Votes,Release Date,Directors\n" +
+				"tt0211915,9,2024-01-02,Amélie,Le fabuleux destin d'Amélie Poulain,https://www.imdb.com/title/tt0211915/,Movie,8.3,122,2001,\"Comedy, Romance\",800000,2001-04-25,Jean-Pierre Jeunet\n" +
+				"tt0245429,10,2024-01-03,Spirited Away,Sen to Chihiro no kamikakushi,https://www.imdb.com/title/tt0245429/,Movie,8.6,125,2001,Animation,900000,2001-07-20,Hayao Miyazaki\n" +
+				"tt0903747,1,2024-01-04,Breaking Bad,Breaking Bad,https://www.imdb.com/title/tt0903747/,TV Series,9.5,49,2008,Drama,2000000,2008-01-20,\n",
+			util.IMDb, map[int]float32{4973: 4.5, 5618: 5}, []int{4},
+		},
+	}
+	for _, test := range tests {
+		filePath := filepath.Join(directory, test.name+".csv")
+		os.WriteFile(filePath, []byte(test.content), 0644)
+		source, ratings, err := util.ReadRatingsExport(filePath)
+		if err != nil || source != test.source {
+			t.Fatalf("ReadRatingsExport(%s): Expected %s ratings, got %s %v", test.name, test.source, source, err)
+		}
+		profile, unmatched := util.MatchImportedRatings(ratings, matcher)
+		if !reflect.DeepEqual(profile, test.profile) {
+			t.Errorf("MatchImportedRatings(%s): Expected %v, got %v", test.name, test.profile, profile)
+		}
+		unmatchedRows := make([]int, 0)
+		for _, rating := range unmatched {
+			unmatchedRows = append(unmatchedRows, rating.Row)
+		}
+		if !reflect.DeepEqual(unmatchedRows, test.unmatched) {
+			t.Errorf("MatchImportedRatings(%s): Expected rows %v to be unmatched, got %v", test.name, test.unmatched, unmatchedRows)
+		}
+
+		// The profile file is read back by -profile
+		profilePath := filepath.Join(directory, test.name+"-profile.csv")
+		if err := util.WriteProfile(profilePath, profile, &importTitles); err != nil {
+			t.Fatalf("WriteProfile: %v", err)
+		}
+		if loaded, err := util.LoadProfile(profilePath); err != nil || !reflect.DeepEqual(loaded, profile) {
+			t.Errorf("LoadProfile: Expected %v, got %v %v", profile, loaded, err)
+		}
+	}
+
+	filePath := filepath.Join(directory, "ratings.csv")
+	os.WriteFile(filePath, []byte("userId,movieId,rating,timestamp\n1,1,4.0,964982703\n"), 0644)
+	if _, _, err := util.ReadRatingsExport(filePath); err == nil {
+		t.Errorf("ReadRatingsExport: Expected an error for a MovieLens ratings file")
+	}
+}
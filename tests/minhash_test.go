@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"math"
+	"recommender/algorithms"
+	"testing"
+)
+
+func TestMinHashEstimateJaccard(t *testing.T) {
+	// Sets {0..99} and {50..149} have a Jaccard similarity of 50/150
+	set1, set2 := make([]uint64, 0), make([]uint64, 0)
+	for i := 0; i < 100; i++ {
+		set1 = append(set1, algorithms.HashInt(i))
+		set2 = append(set2, algorithms.HashInt(i+50))
+	}
+	hasher := algorithms.NewMinHasher(512, 1)
+
+	result := algorithms.EstimateJaccard(hasher.Signature(set1), hasher.Signature(set2))
+
+	tolerance := 0.05
+	if diff := math.Abs(result - 1.0/3.0); diff > tolerance {
+		t.Errorf("MinHash: Expected Jaccard estimate close to 0.333333, got %f", result)
+	}
+}
+
+func TestLSHCandidates(t *testing.T) {
+	hasher := algorithms.NewMinHasher(20, 1)
+	index := algorithms.NewLSHIndex(10, 2)
+	sets := map[int][]string{
+		1: {"pixar", "animation", "toys", "funny"},
+		2: {"pixar", "animation", "toys", "sequel"},
+		3: {"space", "aliens", "horror", "sci-fi"},
+	}
+	signatures := make(map[int][]uint64)
+	for id, set := range sets {
+		elementHashes := make([]uint64, 0, len(set))
+		for _, element := range set {
+			elementHashes = append(elementHashes, algorithms.HashString(element))
+		}
+		signatures[id] = hasher.Signature(elementHashes)
+		index.Add(id, signatures[id])
+	}
+
+	candidates := index.Candidates(signatures[1])
+
+	foundSimilar, foundDissimilar := false, false
+	for _, id := range candidates {
+		foundSimilar = foundSimilar || id == 2
+		foundDissimilar = foundDissimilar || id == 3
+	}
+	if !foundSimilar {
+		t.Errorf("LSH: Expected set 2 among the candidates of set 1, got %v", candidates)
+	}
+	if foundDissimilar {
+		t.Errorf("LSH: Expected disjoint set 3 not to be a candidate of set 1, got %v", candidates)
+	}
+}
+
+func TestRecall(t *testing.T) {
+	testCases := []struct {
+		approximate []int
+		exact       []int
+		expected    float64
+	}{
+		{[]int{1, 2, 3}, []int{1, 2, 3}, 1.0},
+		{[]int{1, 5}, []int{1, 2, 3, 4}, 0.25},
+		{[]int{}, []int{1, 2}, 0.0},
+		{[]int{1}, []int{}, 1.0},
+	}
+	for _, testCase := range testCases {
+		if recall := algorithms.Recall(testCase.approximate, testCase.exact); recall != testCase.expected {
+			t.Errorf("Recall(%v, %v) = %v; want %v", testCase.approximate, testCase.exact, recall, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	model "recommender/models"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+func TestMutationLog(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "mutations.log")
+	if mutations, err := util.ReadMutationLog(filePath); err != nil || len(mutations) != 0 {
+		t.Fatalf("ReadMutationLog: Expected no mutations of a missing log, got %v %v", mutations, err)
+	}
+	expected := []model.Mutation{
+		{Op: model.RateMovie, UserID: 1, MovieID: 2, Rating: 4.5, Time: 1700000000},
+		{Op: model.TagMovie, UserID: 1, MovieID: 2, Tags: []string{"dark", "film noir"}, Time: 1700000001},
+		{Op: model.UnrateMovie, UserID: 1, MovieID: 2, Time: 1700000002},
+	}
+	mutationLog, err := util.OpenMutationLog(filePath)
+	if err != nil {
+		t.Fatalf("OpenMutationLog: %v", err)
+	}
+	for _, mutation := range expected[:2] {
+		if err := mutationLog.Append(mutation); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	mutationLog.Close()
+	// A crash while appending leaves a truncated line, which is ignored and then overwritten
+	file, _ := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0644)
+	file.WriteString(`{"op":"rate","userId":3`)
+	file.Close()
+	if mutations, err := util.ReadMutationLog(filePath); err != nil || !reflect.DeepEqual(mutations, expected[:2]) {
+		t.Errorf("ReadMutationLog: Expected %v, got %v %v", expected[:2], mutations, err)
+	}
+	mutationLog, err = util.OpenMutationLog(filePath)
+	if err != nil {
+		t.Fatalf("OpenMutationLog: %v", err)
+	}
+	mutationLog.Append(expected[2])
+	mutationLog.Close()
+	if mutations, err := util.ReadMutationLog(filePath); err != nil || !reflect.DeepEqual(mutations, expected) {
+		t.Errorf("ReadMutationLog: Expected %v, got %v %v", expected, mutations, err)
+	}
+	// Complete lines that can't be decoded are errors
+	os.WriteFile(filePath, []byte("{\"op\":\"rate\"}\nnot json\n"), 0644)
+	if _, err := util.ReadMutationLog(filePath); err == nil {
+		t.Errorf("ReadMutationLog: Expected an error for a corrupted line")
+	}
+}
+
+func TestApplyMutation(t *testing.T) {
+	shared := []string{"dark", "noir"}
+	users := util.NewUserTable(map[int]model.User{1: {MovieRatings: map[int]float32{1: 4.0}}})
+	movies := util.NewMovieTable(map[int]model.Movie{1: {UserRatings: map[int]float32{1: 4.0}}})
+	movieTags := util.NewTagTable(map[int]model.MovieTags{1: {UserTags: map[int]model.UserTags{1: {Tags: shared[:1]}, 2: {Tags: shared[1:]}}}})
+	// Views of the tables must not see the mutations
+	usersView, moviesView, movieTagsView := users.Limit(-1), movies.Limit(-1), movieTags.Limit(-1)
+
+	util.ApplyMutation(model.Mutation{Op: model.RateMovie, UserID: 2, MovieID: 1, Rating: 3.5, Time: 1700000000}, &users, &movies, &movieTags)
+	if rating, _ := users.Rating(2, 1); rating != 3.5 || !reflect.DeepEqual(movies.Ratings(1), map[int]float32{1: 4.0, 2: 3.5}) || movies.Time(1, 2) != 1700000000 {
+		t.Errorf("ApplyMutation: Rating of user 2 was not added, got %v %v", users.ToUsers(), movies.ToMovies())
+	}
+	util.ApplyMutation(model.Mutation{Op: model.UnrateMovie, UserID: 1, MovieID: 1}, &users, &movies, &movieTags)
+	if users.Has(1) || movies.Count(1) != 1 {
+		t.Errorf("ApplyMutation: Expected user 1 to be removed with its only rating, got %v %v", users.ToUsers(), movies.ToMovies())
+	}
+	util.ApplyMutation(model.Mutation{Op: model.UnrateMovie, UserID: 2, MovieID: 1}, &users, &movies, &movieTags)
+	if users.Len() != 0 || movies.Len() != 0 || movies.TotalRatings() != 0 {
+		t.Errorf("ApplyMutation: Expected no users and movies left, got %v %v", users.ToUsers(), movies.ToMovies())
+	}
+	util.ApplyMutation(model.Mutation{Op: model.TagMovie, UserID: 1, MovieID: 1, Tags: []string{"hero"}}, &users, &movies, &movieTags)
+	if tags, _ := movieTags.Get(1); !reflect.DeepEqual(tags.UserTags[1].Tags, []string{"dark", "hero"}) || !reflect.DeepEqual(tags.UserTags[2].Tags, []string{"noir"}) {
+		t.Errorf("ApplyMutation: Unexpected tags %v", tags.UserTags)
+	}
+	if usersView.Len() != 1 || moviesView.Count(1) != 1 || !reflect.DeepEqual(movieTagsView.Occurrences(1), map[string]int{"dark": 1, "noir": 1}) {
+		t.Errorf("ApplyMutation: Expected the views to keep the original rows, got %v %v %v", usersView.ToUsers(), moviesView.ToMovies(), movieTagsView.ToMovieTags())
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	users := util.NewUserTable(map[int]model.User{
+		1: {MovieRatings: map[int]float32{1: 4.0, 2: 3.0}},
+		2: {MovieRatings: map[int]float32{1: 5.0}},
+	})
+	movies := util.NewMovieTable(map[int]model.Movie{
+		1: {UserRatings: map[int]float32{1: 4.0, 2: 5.0}},
+		2: {UserRatings: map[int]float32{1: 3.0}},
+	})
+	movieTags := util.NewTagTable(map[int]model.MovieTags{1: {UserTags: map[int]model.UserTags{1: {Tags: []string{"dark"}}}}})
+	movieTitles := util.NewTitleTable(map[int]model.MovieTitle{1: {Title: "Heat"}, 2: {Title: "Up"}})
+	// Raters indexed in place from the movies, like the Web-Server does
+	index := util.BuildDatasetIndex(&util.RatingTable{}, &movieTags, &movieTitles, 2)
+	index.IndexRaters(&movies)
+	mutations := []model.Mutation{
+		{Op: model.RateMovie, UserID: 3, MovieID: 2, Rating: 2.5},
+		{Op: model.UnrateMovie, UserID: 1, MovieID: 2},
+		{Op: model.UnrateMovie, UserID: 2, MovieID: 1},
+		{Op: model.TagMovie, UserID: 2, MovieID: 2, Tags: []string{"dark", "heist"}},
+	}
+	for _, mutation := range mutations {
+		util.ApplyMutation(mutation, &users, &movies, &movieTags)
+		if mutation.Op == model.TagMovie {
+			index.UpdateMovieTags(mutation.MovieID, &movieTags)
+		} else {
+			index.UpdateRating(mutation.UserID, mutation.MovieID, &users)
+		}
+	}
+	expected := util.BuildDatasetIndex(&users, &movieTags, &movieTitles, 2)
+	if !reflect.DeepEqual(index.MovieRaters, expected.MovieRaters) {
+		t.Errorf("UpdateRating: Expected raters %v, got %v", expected.MovieRaters, index.MovieRaters)
+	}
+	if !reflect.DeepEqual(index.MovieTags, expected.MovieTags) || !reflect.DeepEqual(index.TagPostings, expected.TagPostings) {
+		t.Errorf("UpdateMovieTags: Expected postings %v, got %v", expected.TagPostings, index.TagPostings)
+	}
+}
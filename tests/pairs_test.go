@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"math"
+	"recommender/config"
+	model "recommender/models"
+	"recommender/recommenders"
+	util "recommender/utils"
+	"testing"
+)
+
+var pairUsers = util.NewUserTable(map[int]model.User{
+	1: {MovieRatings: map[int]float32{1: 5, 2: 3}},
+	2: {MovieRatings: map[int]float32{1: 4, 2: 3, 3: 4}},
+	3: {MovieRatings: map[int]float32{1: 1, 3: 2}},
+	4: {MovieRatings: map[int]float32{4: 5}},
+})
+
+func TestPredictRating(t *testing.T) {
+	movies := util.RatingTable{}
+	// The profile rated the same movies as user 1, whose neighbours 2 and 3 rated movie 3
+	cfg := config.Config{Algorithm: "user", Similarity: "jaccard", K: 10, NumThreads: 2, Profile: map[int]float32{1: 5, 2: 3}}
+	prediction := recommenders.PredictRating(&cfg, 3, &pairUsers, &movies, nil, nil)
+	expected := []model.PredictionNeighbor{{ID: 2, Similarity: 2.0 / 3, Rating: 4, Overlap: 2}, {ID: 3, Similarity: 1.0 / 3, Rating: 2, Overlap: 1}}
+	if !prediction.Predicted || math.Abs(prediction.Rating-10.0/3) > 1e-9 || prediction.Actual != 0 || len(prediction.Neighbors) != len(expected) {
+		t.Fatalf("PredictRating: Expected a forecast of 3.333333 by users 2 and 3, got %+v", prediction)
+	}
+	for i, neighbor := range prediction.Neighbors {
+		if neighbor.ID != expected[i].ID || math.Abs(neighbor.Similarity-expected[i].Similarity) > 1e-9 || neighbor.Rating != expected[i].Rating || neighbor.Overlap != expected[i].Overlap {
+			t.Errorf("PredictRating: Expected neighbour %+v, got %+v", expected[i], neighbor)
+		}
+	}
+	// Same forecast as the recommendation of the movie
+	movieTitles := util.NewTitleTable(map[int]model.MovieTitle{3: {Title: "Heat (1995)"}, 4: {Title: "Alien (1979)"}})
+	recommendCfg := cfg
+	recommendCfg.Recommendations = -1
+	forecasts := recommenders.RecommendBasedOnUser(&recommendCfg, &pairUsers, &movieTitles, nil)
+	if len(forecasts) != 1 || forecasts[0].MovieID != 3 || math.Abs(float64(forecasts[0].Rating)-prediction.Rating) > 1e-6 {
+		t.Errorf("PredictRating: Expected the forecast of the recommendations %v", forecasts)
+	}
+	// Nobody who rated movie 4 is a neighbour of user 1
+	cfg.Profile, cfg.Input = nil, 1
+	if prediction := recommenders.PredictRating(&cfg, 4, &pairUsers, &movies, nil, nil); prediction.Predicted || prediction.Rating != 0 || len(prediction.Neighbors) != 0 {
+		t.Errorf("PredictRating: Expected no forecast of movie 4, got %+v", prediction)
+	}
+}
+
+func TestComparePair(t *testing.T) {
+	movies := util.RatingTable{}
+	cfg := config.Config{Input: 1, InputType: "user", Similarity: "jaccard", K: 10, NumThreads: 2}
+	pair := recommenders.ComparePair(&cfg, 2, &pairUsers, &movies, nil)
+	if pair.Ratings != 2 || pair.OtherRatings != 3 || pair.Overlap != 2 || pair.Neighbors != 2 || pair.Rank != 1 {
+		t.Errorf("ComparePair: Expected user 2 to be the first of the 2 neighbours of user 1, got %+v", pair)
+	}
+	if pair := recommenders.ComparePair(&cfg, 3, &pairUsers, &movies, nil); pair.Neighbors != 2 || pair.Rank != 2 {
+		t.Errorf("ComparePair: Expected user 3 to be the second of the 2 neighbours of user 1, got %+v", pair)
+	}
+	// Ties are ranked by ID, like the neighbours of the recommendations
+	cfg.Similarity = "dice"
+	tiedUsers := util.NewUserTable(map[int]model.User{1: {MovieRatings: map[int]float32{1: 5}}, 2: {MovieRatings: map[int]float32{1: 4}}, 3: {MovieRatings: map[int]float32{1: 3}}})
+	if pair := recommenders.ComparePair(&cfg, 3, &tiedUsers, &movies, nil); pair.Neighbors != 2 || pair.Rank != 2 {
+		t.Errorf("ComparePair: Expected user 3 to follow user 2 of the same similarity, got %+v", pair)
+	}
+	cfg.Similarity = "jaccard"
+	// The norm of user 2 is restricted to the movies rated by user 1
+	if expected := 29 / math.Sqrt(34*25); math.Abs(pair.Metrics["cosine"]-expected) > 1e-6 || math.Abs(pair.Metrics["jaccard"]-2.0/3) > 1e-9 {
+		t.Errorf("ComparePair: Expected cosine %f and jaccard 0.666667, got %v", expected, pair.Metrics)
+	}
+	// Implicit ratings are all 1.0, and user 4 shares no movie with user 1
+	cfg.Implicit, cfg.Similarity = true, ""
+	pair = recommenders.ComparePair(&cfg, 2, &pairUsers, &movies, nil)
+	if math.Abs(pair.Metrics["cosine"]-1) > 1e-6 || pair.Neighbors != 0 || pair.Rank != 0 {
+		t.Errorf("ComparePair: Expected the implicit cosine 1.0 without a rank, got %+v", pair)
+	}
+	if pair := recommenders.ComparePair(&cfg, 4, &pairUsers, &movies, nil); pair.Overlap != 0 || pair.Metrics["jaccard"] != 0 {
+		t.Errorf("ComparePair: Expected users 1 and 4 to share nothing, got %+v", pair)
+	}
+	// Only the ratings >= 4 are positive interactions, and user 2 is the only neighbour sharing one with user 1
+	cfg.Threshold, cfg.Similarity = 4, "jaccard"
+	pair = recommenders.ComparePair(&cfg, 2, &pairUsers, &movies, nil)
+	if pair.Ratings != 1 || pair.OtherRatings != 2 || pair.Overlap != 1 || pair.Metrics["jaccard"] != 0.5 || pair.Neighbors != 1 || pair.Rank != 1 {
+		t.Errorf("ComparePair: Expected user 2 to be the only implicit neighbour of user 1, got %+v", pair)
+	}
+	if _, exists := pair.Metrics["pearson"]; exists {
+		t.Errorf("ComparePair: Expected no pearson correlation of implicit feedback, got %v", pair.Metrics)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	directory := t.TempDir()
+	filePath := filepath.Join(directory, "profile.csv")
+	// Columns may be in any order, other columns are ignored and the last rating of a movie wins
+	os.WriteFile(filePath, []byte("\ufefftitle,Rating,movieId\n\"Heat (1995)\",4.5,6\nToy Story (1995),3,1\n,5,1\n"), 0644)
+	profile, err := util.LoadProfile(filePath)
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if expected := map[int]float32{6: 4.5, 1: 5}; !reflect.DeepEqual(profile, expected) {
+		t.Errorf("LoadProfile: Expected %v, got %v", expected, profile)
+	}
+
+	for name, content := range map[string]string{
+		"columns": "movie,rating\n1,4\n",
+		"movieId": "movieId,rating\none,4\n",
+		"rating":  "movieId,rating\n1,\n",
+		"short":   "movieId,title,rating\n1,Toy Story\n",
+	} {
+		invalidPath := filepath.Join(directory, name)
+		os.WriteFile(invalidPath, []byte(content), 0644)
+		if _, err := util.LoadProfile(invalidPath); err == nil {
+			t.Errorf("LoadProfile: Expected an error for the %s of %q", name, content)
+		}
+	}
+	if _, err := util.LoadProfile(filepath.Join(directory, "missing.csv")); err == nil {
+		t.Errorf("LoadProfile: Expected an error for a missing file")
+	}
+}
+
+func TestToImplicitRatings(t *testing.T) {
+	ratings := map[int]float32{1: 5, 2: 3.5, 3: 1}
+	if implicit := util.ToImplicitRatings(ratings, 3.5); !reflect.DeepEqual(implicit, map[int]float32{1: 1, 2: 1}) {
+		t.Errorf("ToImplicitRatings: Expected movies 1 and 2, got %v", implicit)
+	}
+	if implicit := util.ToImplicitRatings(ratings, 0); len(implicit) != 3 || ratings[1] != 5 {
+		t.Errorf("ToImplicitRatings: Expected every movie without modifying the ratings, got %v", implicit)
+	}
+}
+
+func TestLoadIDList(t *testing.T) {
+	directory := t.TempDir()
+	for name, content := range map[string]string{
+		"list":    "# Watchlist\n6\n 1 \n\n# Seen\n260\n",
+		"profile": "title,movieId,rating\n\"Heat (1995)\",6,4.5\nToy Story (1995),1,3\nStar Wars (1977),260,5\n",
+	} {
+		filePath := filepath.Join(directory, name)
+		os.WriteFile(filePath, []byte(content), 0644)
+		ids, err := util.LoadIDList(filePath)
+		if expected := []int{6, 1, 260}; err != nil || !reflect.DeepEqual(ids, expected) {
+			t.Errorf("LoadIDList: Expected %v from the %s, got %v %v", expected, name, ids, err)
+		}
+	}
+	for name, content := range map[string]string{
+		"first":   "one\n2\n",
+		"line":    "1\ntwo\n",
+		"movieId": "movieId,rating\n1,4\n,5\n",
+	} {
+		invalidPath := filepath.Join(directory, name)
+		os.WriteFile(invalidPath, []byte(content), 0644)
+		if _, err := util.LoadIDList(invalidPath); err == nil {
+			t.Errorf("LoadIDList: Expected an error for the %s of %q", name, content)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"math"
+	"recommender/algorithms"
+	model "recommender/models"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+func TestDetectRatingScale(t *testing.T) {
+	cases := []struct {
+		ratings  []float32
+		expected model.RatingScale
+		ok       bool
+	}{
+		{[]float32{0.5, 4.0, 5.0, 3.5}, model.RatingScale{Min: 0.5, Step: 0.5, Levels: 10}, true},
+		{[]float32{1, 10, 7, 3}, model.RatingScale{Min: 1, Step: 1, Levels: 10}, true},
+		{[]float32{0, 1, 1, 0}, model.RatingScale{Min: 0, Step: 1, Levels: 2}, true},
+		{[]float32{4, 4}, model.RatingScale{Min: 4, Step: 1, Levels: 1}, true},
+		{[]float32{1, 1.5, 2.2}, model.RatingScale{Min: 1, Step: 0.1, Levels: 13}, true},
+		// Too many levels
+		{[]float32{0, 0.001, 5}, model.RatingScale{}, false},
+		{[]float32{1, 1.3333, 2}, model.RatingScale{}, false},
+	}
+	for _, c := range cases {
+		scale, ok := algorithms.DetectRatingScale(c.ratings)
+		if ok != c.ok || scale.Min != c.expected.Min || scale.Levels != c.expected.Levels || math.Abs(scale.Step-c.expected.Step) > 1e-6 {
+			t.Errorf("DetectRatingScale(%v): Expected %v %v, got %v %v", c.ratings, c.expected, c.ok, scale, ok)
+		}
+		if !ok {
+			continue
+		}
+		for _, rating := range c.ratings {
+			if decoded := scale.Decode(scale.Encode(rating)); decoded != rating {
+				t.Errorf("RatingScale %v: Expected %f to round trip, got %f", scale, rating, decoded)
+			}
+		}
+	}
+}
+
+func TestQuantizedVector(t *testing.T) {
+	scale := model.RatingScale{Min: 0.5, Step: 0.5, Levels: 10}
+	ratings1 := map[int]float32{1: 4.0, 2: 3.5, 5: 0.5, 9: 5.0}
+	ratings2 := map[int]float32{2: 1.5, 5: 4.5, 9: 3.0, 11: 2.0}
+	float1, float2 := algorithms.NewSparseVector(ratings1), algorithms.NewSparseVector(ratings2)
+	quantized1, quantized2 := algorithms.NewQuantizedVector(ratings1, scale), algorithms.NewQuantizedVector(ratings2, scale)
+
+	if !quantized1.Quantized() || float1.Quantized() || quantized1.Values != nil {
+		t.Errorf("NewQuantizedVector: Expected steps instead of values")
+	}
+	if quantized1.Sum() != float1.Sum() || quantized1.SquaredNorm() != float1.SquaredNorm() || quantized1.Value(3) != 5.0 {
+		t.Errorf("NewQuantizedVector: Expected the statistics of the float vector")
+	}
+	if algorithms.CosineSimilaritySparse(quantized1, quantized2) != algorithms.CosineSimilaritySparse(float1, float2) {
+		t.Errorf("CosineSimilaritySparse: Expected the same similarity for quantized vectors")
+	}
+	if algorithms.PearsonSimilaritySparse(quantized1, float2) != algorithms.PearsonSimilaritySparse(float1, float2) {
+		t.Errorf("PearsonSimilaritySparse: Expected the same similarity for mixed vectors")
+	}
+}
+
+func TestQuantizeRatings(t *testing.T) {
+	users := map[int]model.User{1: {MovieRatings: map[int]float32{3: 4.5, 1: 2.0}}, 2: {MovieRatings: map[int]float32{}}}
+	movies := map[int]model.Movie{
+		1: {UserRatings: map[int]float32{1: 2.0}, RatingTimes: map[int]int64{1: 1700000000}},
+		3: {UserRatings: map[int]float32{1: 4.5}, RatingTimes: map[int]int64{}},
+	}
+	userTable, movieTable := util.NewUserTable(users), util.NewMovieTable(movies)
+	scale, ok := util.DetectRatingScale(&userTable, &movieTable)
+	if !ok || scale != (model.RatingScale{Min: 2.0, Step: 2.5, Levels: 2}) {
+		t.Fatalf("DetectRatingScale: Expected {2 2.5 2}, got %v %v", scale, ok)
+	}
+
+	quantizedUsers := util.QuantizeUsers(users, scale)
+	if entity := quantizedUsers.Entities[1]; !reflect.DeepEqual(entity.Keys, []int32{1, 3}) || !reflect.DeepEqual(entity.Steps, []uint8{0, 1}) {
+		t.Errorf("QuantizeUsers: Expected sorted keys [1 3] with steps [0 1], got %v", entity)
+	}
+	// Tables keep the steps of the ratings, which their vectors decode
+	quantizedTable := util.NewQuantizedTable(quantizedUsers)
+	if vector := quantizedTable.Vector(1); !vector.Quantized() || vector.Value(1) != 4.5 || vector.Sum() != 6.5 {
+		t.Errorf("NewQuantizedTable: Expected the quantized ratings of user 1, got %v", quantizedTable.Ratings(1))
+	}
+	if decoded := quantizedTable.ToUsers(); !reflect.DeepEqual(decoded, users) {
+		t.Errorf("NewQuantizedTable: Expected %v, got %v", users, decoded)
+	}
+	movieTable = util.NewQuantizedTable(util.QuantizeMovies(movies, scale))
+	if decoded := movieTable.ToMovies(); !reflect.DeepEqual(decoded, movies) {
+		t.Errorf("NewQuantizedTable: Expected %v, got %v", movies, decoded)
+	}
+	if limited := util.NewQuantizedTable(quantizedUsers, 1); limited.Len() != 1 || limited.TotalRatings() != 2 {
+		t.Errorf("NewQuantizedTable: Expected the ratings of user 1 only, got %v", limited.ToUsers())
+	}
+	// Changed rows stay quantized
+	movieTable.SetRating(3, 2, 2.0, 1700000000)
+	if vector := movieTable.Vector(3); !vector.Quantized() || vector.Sum() != 6.5 || movieTable.Time(3, 2) != 1700000000 {
+		t.Errorf("SetRating: Expected the quantized ratings of movie 3, got %v", movieTable.Ratings(3))
+	}
+}
@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"math"
+	"recommender/algorithms"
+	"testing"
+)
+
+func TestTransitionProbability(t *testing.T) {
+	tolerance := 0.000001
+	if result := algorithms.TransitionProbability(4, 1); math.Abs(result-0.25) > tolerance {
+		t.Errorf("Transition probability: Expected 0.25, got %f", result)
+	}
+	if result := algorithms.TransitionProbability(4, 0.5); math.Abs(result-0.5) > tolerance {
+		t.Errorf("Transition probability: Expected 0.5, got %f", result)
+	}
+	if result := algorithms.TransitionProbability(0, 1); result != 0 {
+		t.Errorf("Transition probability: Expected 0 for isolated node, got %f", result)
+	}
+}
+
+func TestPopularityPenalty(t *testing.T) {
+	result := algorithms.PopularityPenalty(0.6, 9, 0.5)
+
+	tolerance := 0.000001
+	if diff := math.Abs(result - 0.2); diff > tolerance {
+		t.Errorf("Popularity penalty: Expected 0.2, got %f", result)
+	}
+}
@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"math"
+	"recommender/config"
+	model "recommender/models"
+	"recommender/recommenders"
+	"testing"
+)
+
+// Similar movies of every seed, as ranked by a single-seed recommender
+var seedSimilarities = map[int][]model.SimilarMovie{
+	1: {{MovieID: 10, Similarity: 0.8}, {MovieID: 2, Similarity: 0.6}, {MovieID: 11, Similarity: 0.2}},
+	2: {{MovieID: 11, Similarity: 0.9}, {MovieID: 1, Similarity: 0.6}, {MovieID: 12, Similarity: 0.4}},
+	3: {{MovieID: 12, Similarity: 0.5}, {MovieID: 13, Similarity: 0.3}},
+}
+
+func TestRecommendSimilarToSeeds(t *testing.T) {
+	recommend := func(cfg *config.Config) []model.SimilarMovie {
+		if cfg.Recommendations != -1 || len(cfg.Seeds) != 0 {
+			t.Errorf("Expected every similar movie of seed %d alone, got %d of %v", cfg.Input, cfg.Recommendations, cfg.Seeds)
+		}
+		return seedSimilarities[cfg.Input]
+	}
+	tests := []struct {
+		aggregation   string
+		negativeSeeds []int
+		weights       map[int]float64
+		expected      []model.SimilarMovie
+	}{
+		{"mean", nil, nil, []model.SimilarMovie{{MovieID: 11, Similarity: 0.55}, {MovieID: 10, Similarity: 0.4}, {MovieID: 12, Similarity: 0.2}}},
+		{"mean", nil, map[int]float64{1: 3}, []model.SimilarMovie{{MovieID: 10, Similarity: 0.6}, {MovieID: 11, Similarity: 0.375}, {MovieID: 12, Similarity: 0.1}}},
+		{"max", nil, nil, []model.SimilarMovie{{MovieID: 11, Similarity: 0.9}, {MovieID: 10, Similarity: 0.8}, {MovieID: 12, Similarity: 0.4}}},
+		{"sum", nil, map[int]float64{2: 0.5}, []model.SimilarMovie{{MovieID: 10, Similarity: 0.8}, {MovieID: 11, Similarity: 0.65}, {MovieID: 12, Similarity: 0.2}}},
+		{"rrf", nil, nil, []model.SimilarMovie{{MovieID: 11, Similarity: 1.0/61 + 1.0/63}, {MovieID: 10, Similarity: 1.0 / 61}, {MovieID: 12, Similarity: 1.0 / 63}}},
+		// Movies similar to the negative seed 3 rank lower, and 13 isn't similar to any seed
+		{"sum", []int{3}, nil, []model.SimilarMovie{{MovieID: 11, Similarity: 1.1}, {MovieID: 10, Similarity: 0.8}, {MovieID: 12, Similarity: -0.1}}},
+		{"max", []int{3}, map[int]float64{3: 2}, []model.SimilarMovie{{MovieID: 11, Similarity: 0.9}, {MovieID: 10, Similarity: 0.8}, {MovieID: 12, Similarity: -0.6}}},
+	}
+	for _, test := range tests {
+		cfg := config.Config{Recommendations: 5, Seeds: []int{1, 2}, NegativeSeeds: test.negativeSeeds, SeedWeights: test.weights, Aggregation: test.aggregation}
+		similarMovies := recommenders.RecommendSimilarToSeeds(&cfg, recommend)
+		if len(similarMovies) != len(test.expected) {
+			t.Errorf("%s %v: Expected %v, got %v", test.aggregation, test.weights, test.expected, similarMovies)
+			continue
+		}
+		for i, movie := range similarMovies {
+			if movie.MovieID != test.expected[i].MovieID || math.Abs(movie.Similarity-test.expected[i].Similarity) > 1e-9 {
+				t.Errorf("%s %v: Expected %v, got %v", test.aggregation, test.weights, test.expected, similarMovies)
+				break
+			}
+		}
+	}
+}
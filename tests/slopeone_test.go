@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"math"
+	"recommender/algorithms"
+	"testing"
+)
+
+func TestWeightedSlopeOne(t *testing.T) {
+	// Example from the original paper: https://arxiv.org/abs/cs/0702144
+	users := map[int]map[int]float32{
+		1: {1: 5.0, 2: 3.0, 3: 2.0},
+		2: {1: 3.0, 2: 4.0},
+		3: {2: 2.0, 3: 5.0},
+	}
+	movieRatings := map[int]map[int]float32{
+		2: {1: 3.0, 2: 4.0, 3: 2.0},
+		3: {1: 2.0, 3: 5.0},
+	}
+	userRatings := func(userID int) algorithms.SparseVector[int, float32] {
+		return algorithms.NewSparseVector(users[userID])
+	}
+	rows := map[int]algorithms.SlopeOneRow{
+		2: algorithms.SlopeOneDeviations(2, algorithms.NewSparseVector(movieRatings[2]), userRatings),
+		3: algorithms.SlopeOneDeviations(3, algorithms.NewSparseVector(movieRatings[3]), userRatings),
+	}
+
+	result, exists := algorithms.WeightedSlopeOne(users[3], rows, 1)
+
+	tolerance := 0.000001
+	if !exists {
+		t.Errorf("Weighted Slope One: Expected a prediction for item 1")
+		return
+	}
+	if diff := math.Abs(result - 4.333333); diff > tolerance {
+		t.Errorf("Weighted Slope One: Expected 4.333333, got %f", result)
+	}
+}
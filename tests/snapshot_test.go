@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"recommender/algorithms"
+	model "recommender/models"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	users := map[int]model.User{
+		1: {MovieRatings: map[int]float32{3: 4.5, 1: 2.0}},
+		7: {MovieRatings: map[int]float32{}},
+		2: {MovieRatings: map[int]float32{1: 5.0}},
+	}
+	movies := map[int]model.Movie{
+		1: {UserRatings: map[int]float32{1: 2.0, 2: 5.0}, RatingTimes: map[int]int64{1: 1700000000, 2: 1700000100}},
+		3: {UserRatings: map[int]float32{1: 4.5}, RatingTimes: map[int]int64{1: 1600000000}},
+	}
+	movieTitles := map[int]model.MovieTitle{1: {Title: "Toy Story (1995)", Genres: []string{"Animation", "Children"}}, 3: {Title: "Heat (1995)"}, 5: {Title: ""}}
+	movieTags := map[int]model.MovieTags{
+		1: {UserTags: map[int]model.UserTags{2: {Tags: []string{"pixar", "toys"}}, 1: {Tags: []string{"pixar"}}}},
+		3: {UserTags: map[int]model.UserTags{}},
+	}
+	filePath := filepath.Join(t.TempDir(), "snapshot.bin")
+	if err := util.WriteSnapshot(filePath, users, movies, movieTitles, movieTags); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+	snapshot, err := util.OpenSnapshot(filePath)
+	if err != nil {
+		t.Fatalf("OpenSnapshot: %v", err)
+	}
+	defer snapshot.Close()
+	// Replacing the file, like preprocess does, leaves the mapped snapshot intact
+	err = util.ReplaceFile(filePath, func(tempPath string) error {
+		return util.WriteSnapshot(tempPath, map[int]model.User{9: {MovieRatings: map[int]float32{9: 1}}}, nil, nil, nil)
+	})
+	if err != nil {
+		t.Fatalf("ReplaceFile: %v", err)
+	}
+
+	if loaded := snapshot.Users(-1); !reflect.DeepEqual(loaded.ToUsers(), users) {
+		t.Errorf("Snapshot: Expected users %v, got %v", users, loaded)
+	}
+	if loaded := snapshot.Movies(-1); !reflect.DeepEqual(loaded.ToMovies(), movies) {
+		t.Errorf("Snapshot: Expected movies %v, got %v", movies, loaded)
+	}
+	if loaded := snapshot.MovieTitles(-1); !reflect.DeepEqual(loaded.ToMovieTitles(), movieTitles) {
+		t.Errorf("Snapshot: Expected titles %v, got %v", movieTitles, loaded)
+	}
+	if loaded := snapshot.MovieTags(-1); !reflect.DeepEqual(loaded.ToMovieTags(), movieTags) {
+		t.Errorf("Snapshot: Expected tags %v, got %v", movieTags, loaded)
+	}
+	// Records are limited to the lowest IDs, like the gob files
+	if loaded := snapshot.Users(2); loaded.Len() != 2 || loaded.Has(7) {
+		t.Errorf("Snapshot: Expected users 1 and 2, got %v", loaded.IDs())
+	}
+
+	// Rating vectors are read from the mapped file and match the ones built from the maps
+	loadedUsers, loadedMovies := snapshot.Users(-1), snapshot.Movies(-1)
+	for _, userID := range loadedUsers.IDs() {
+		vector := loadedUsers.Vector(userID)
+		expected := algorithms.NewSparseVector(users[userID].MovieRatings)
+		if !reflect.DeepEqual(vector.Keys, expected.Keys) || vector.Len() != expected.Len() || vector.SquaredNorm() != expected.SquaredNorm() {
+			t.Errorf("Snapshot: Expected the ratings of user %d to be %v, got %v", userID, expected.Keys, vector.Keys)
+		}
+		for i := range vector.Keys {
+			if vector.Value(i) != expected.Value(i) {
+				t.Errorf("Snapshot: Expected rating %f, got %f", expected.Value(i), vector.Value(i))
+			}
+		}
+	}
+	if vector := loadedMovies.Vector(1); !reflect.DeepEqual(vector.Keys, []int{1, 2}) || vector.Sum() != 7.0 {
+		t.Errorf("Snapshot: Expected the ratings of movie 1, got %v", vector)
+	}
+	if loadedMovies.Time(1, 2) != 1700000100 || loadedMovies.Time(3, 2) != 0 {
+		t.Errorf("Snapshot: Expected the rating times of movie 1, got %v", loadedMovies.Times(1))
+	}
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	directory := t.TempDir()
+	filePath := filepath.Join(directory, "snapshot.bin")
+	if err := util.WriteSnapshot(filePath, map[int]model.User{1: {MovieRatings: map[int]float32{1: 1}}}, nil, nil, nil); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+	content, _ := os.ReadFile(filePath)
+
+	// Truncated snapshots and other files must be rejected instead of being read out of bounds
+	for name, corrupted := range map[string][]byte{
+		"truncated": content[:len(content)-16],
+		"gob":       []byte("not a snapshot at all, eg. an older gob file"),
+		"empty":     {},
+	} {
+		corruptedPath := filepath.Join(directory, name)
+		os.WriteFile(corruptedPath, corrupted, 0644)
+		if _, err := util.OpenSnapshot(corruptedPath); err == nil {
+			t.Errorf("OpenSnapshot: Expected an error for a %s file", name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"math"
+	"math/rand"
+	"recommender/algorithms"
+	model "recommender/models"
+	util "recommender/utils"
+	"testing"
+)
+
+func TestSortedSetSimilarities(t *testing.T) {
+	set1 := []int{1, 2, 3, 7, 10, 20, 100}
+	set2 := []int{2, 3, 4, 17, 20}
+
+	if size := algorithms.IntersectionSize(set1, set2); size != 3 {
+		t.Errorf("IntersectionSize: Expected 3, got %d", size)
+	}
+	if size := algorithms.UnionSize(set1, set2); size != 9 {
+		t.Errorf("UnionSize: Expected 9, got %d", size)
+	}
+	if !algorithms.HasIntersection(set1, set2) || algorithms.HasIntersection(set1, []int{5, 6}) {
+		t.Errorf("HasIntersection: Expected true for common elements only")
+	}
+	tolerance := 0.000001
+	if result := algorithms.JaccardSimilaritySorted(set1, set2); math.Abs(result-algorithms.JaccardSimilarity(set1, set2)) > tolerance {
+		t.Errorf("Sorted Jaccard similarity: Expected %f, got %f", algorithms.JaccardSimilarity(set1, set2), result)
+	}
+	if result := algorithms.DiceSimilaritySorted(set1, set2); math.Abs(result-algorithms.DiceSimilarity(set1, set2)) > tolerance {
+		t.Errorf("Sorted Dice similarity: Expected %f, got %f", algorithms.DiceSimilarity(set1, set2), result)
+	}
+}
+
+func TestMultisetSimilarities(t *testing.T) {
+	// Tags of two movies, some of them given by several users
+	tags1 := []string{"dark", "noir", "dark", "crime", "dark"}
+	tags2 := []string{"crime", "dark", "crime", "heist"}
+	movieTags1 := model.MovieTags{UserTags: map[int]model.UserTags{1: {Tags: tags1[:2]}, 2: {Tags: tags1[2:]}}}
+	movieTags2 := model.MovieTags{UserTags: map[int]model.UserTags{1: {Tags: tags2}}}
+	vector1 := algorithms.NewSparseVector(util.CountTagOccurrences(movieTags1))
+	vector2 := algorithms.NewSparseVector(util.CountTagOccurrences(movieTags2))
+
+	if size := algorithms.MultisetIntersectionSize(vector1, vector2); size != 3 {
+		t.Errorf("MultisetIntersectionSize: Expected 3, got %f", size)
+	}
+	tolerance := 0.000001
+	if result := algorithms.MultisetJaccardSimilarity(vector1, vector2); math.Abs(result-algorithms.JaccardSimilarity(tags1, tags2)) > tolerance {
+		t.Errorf("Multiset Jaccard similarity: Expected %f, got %f", algorithms.JaccardSimilarity(tags1, tags2), result)
+	}
+	if result := algorithms.MultisetDiceSimilarity(vector1, vector2); math.Abs(result-algorithms.DiceSimilarity(tags1, tags2)) > tolerance {
+		t.Errorf("Multiset Dice similarity: Expected %f, got %f", algorithms.DiceSimilarity(tags1, tags2), result)
+	}
+	if result := algorithms.MultisetJaccardSimilarity(vector2, vector1); math.Abs(result-algorithms.JaccardSimilarity(tags2, tags1)) > tolerance {
+		t.Errorf("Multiset Jaccard similarity: Expected %f, got %f", algorithms.JaccardSimilarity(tags2, tags1), result)
+	}
+}
+
+func TestSparseVectorSimilarities(t *testing.T) {
+	user1 := model.User{MovieRatings: map[int]float32{1: 4.0, 3: 5.0, 2: 3.5, 8: 1.0}}
+	user2 := model.User{MovieRatings: map[int]float32{4: 2.5, 2: 4.5, 3: 3.0, 5: 2.5, 8: 2.0}}
+	vector1, vector2 := algorithms.NewSparseVector(user1.MovieRatings), algorithms.NewSparseVector(user2.MovieRatings)
+	// The dense vectors are aligned to the movies of user1, like the sparse similarities
+	movies1 := []int{1, 2, 3, 8}
+	movies2 := []int{2, 3, 4, 5, 8}
+	denseA, denseB := util.GetMovieRatingVectors(user1, user2, movies1, movies2)
+
+	tolerance := 0.000001
+	expectedCosine := algorithms.CosineSimilarity(denseA, denseB, algorithms.DotProductFloat32)
+	if result := algorithms.CosineSimilaritySparse(vector1, vector2); math.Abs(result-expectedCosine) > tolerance {
+		t.Errorf("Sparse cosine similarity: Expected %f, got %f", expectedCosine, result)
+	}
+	expectedPearson := algorithms.PearsonSimilarity(denseA, denseB)
+	if result := algorithms.PearsonSimilaritySparse(vector1, vector2); math.Abs(result-expectedPearson) > tolerance {
+		t.Errorf("Sparse Pearson similarity: Expected %f, got %f", expectedPearson, result)
+	}
+	if result := algorithms.DotProduct(vector1, vector2); math.Abs(result-32.75) > tolerance {
+		t.Errorf("Sparse dot product: Expected 32.750000, got %f", result)
+	}
+}
+
+// Returns two rating maps of $size movies each out of 4*$size, which is the typical sparsity of user profiles
+func randomRatings(size int) (map[int]float32, map[int]float32) {
+	random := rand.New(rand.NewSource(42))
+	ratings1, ratings2 := make(map[int]float32, size), make(map[int]float32, size)
+	for len(ratings1) < size {
+		ratings1[random.Intn(4*size)] = float32(random.Intn(10)+1) / 2
+	}
+	for len(ratings2) < size {
+		ratings2[random.Intn(4*size)] = float32(random.Intn(10)+1) / 2
+	}
+	return ratings1, ratings2
+}
+
+func BenchmarkJaccardSimilarity(b *testing.B) {
+	ratings1, ratings2 := randomRatings(500)
+	movies1, movies2 := make([]int, 0, len(ratings1)), make([]int, 0, len(ratings2))
+	for movieID := range ratings1 {
+		movies1 = append(movies1, movieID)
+	}
+	for movieID := range ratings2 {
+		movies2 = append(movies2, movieID)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Pre-filter and similarity, as the recommenders used to do for every pair
+		if len(algorithms.Intersection(movies1, movies2)) > 0 {
+			algorithms.JaccardSimilarity(movies1, movies2)
+		}
+	}
+}
+
+func BenchmarkJaccardSimilaritySorted(b *testing.B) {
+	ratings1, ratings2 := randomRatings(500)
+	vector1, vector2 := algorithms.NewSparseVector(ratings1), algorithms.NewSparseVector(ratings2)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if algorithms.HasIntersection(vector1.Keys, vector2.Keys) {
+			algorithms.JaccardSimilaritySorted(vector1.Keys, vector2.Keys)
+		}
+	}
+}
+
+func BenchmarkPearsonSimilarity(b *testing.B) {
+	ratings1, ratings2 := randomRatings(500)
+	user1, user2 := model.User{MovieRatings: ratings1}, model.User{MovieRatings: ratings2}
+	movies1, movies2 := make([]int, 0, len(ratings1)), make([]int, 0, len(ratings2))
+	for movieID := range ratings1 {
+		movies1 = append(movies1, movieID)
+	}
+	for movieID := range ratings2 {
+		movies2 = append(movies2, movieID)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vectorA, vectorB := util.GetMovieRatingVectors(user1, user2, movies1, movies2)
+		algorithms.PearsonSimilarity(vectorA, vectorB)
+	}
+}
+
+func BenchmarkPearsonSimilaritySparse(b *testing.B) {
+	ratings1, ratings2 := randomRatings(500)
+	vector1, vector2 := algorithms.NewSparseVector(ratings1), algorithms.NewSparseVector(ratings2)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		algorithms.PearsonSimilaritySparse(vector1, vector2)
+	}
+}
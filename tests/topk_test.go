@@ -0,0 +1,55 @@
+package tests
+
+import (
+	model "recommender/models"
+	util "recommender/utils"
+	"reflect"
+	"testing"
+)
+
+// Orders ratings in descending order and by ID for equal ratings
+func higherRating(a model.Rating, b model.Rating) bool {
+	if a.Rating == b.Rating {
+		return a.MovieID < b.MovieID
+	}
+	return a.Rating > b.Rating
+}
+
+func TestTopK(t *testing.T) {
+	topK := util.NewTopK(3, higherRating)
+	for _, rating := range []model.Rating{
+		{MovieID: 5, Rating: 3.0}, {MovieID: 1, Rating: 4.5}, {MovieID: 7, Rating: 4.0},
+		{MovieID: 2, Rating: 4.0}, {MovieID: 9, Rating: 1.0}, {MovieID: 3, Rating: 5.0},
+	} {
+		topK.Push(rating)
+	}
+	expected := []model.Rating{{MovieID: 3, Rating: 5.0}, {MovieID: 1, Rating: 4.5}, {MovieID: 2, Rating: 4.0}}
+	if result := topK.Sorted(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("TopK: Expected %v, got %v", expected, result)
+	}
+
+	unbounded := util.NewTopK(-1, higherRating)
+	unbounded.Push(model.Rating{MovieID: 1, Rating: 1.0})
+	unbounded.Push(model.Rating{MovieID: 2, Rating: 2.0})
+	if unbounded.Len() != 2 {
+		t.Errorf("TopK: Expected an unbounded heap to keep 2 items, got %d", unbounded.Len())
+	}
+}
+
+func TestMergeTopK(t *testing.T) {
+	lists := [][]model.Rating{
+		{{MovieID: 4, Rating: 5.0}, {MovieID: 6, Rating: 3.0}},
+		{},
+		{{MovieID: 1, Rating: 4.0}, {MovieID: 8, Rating: 4.0}, {MovieID: 2, Rating: 2.0}},
+		{{MovieID: 3, Rating: 4.0}},
+	}
+	expected := []model.Rating{
+		{MovieID: 4, Rating: 5.0}, {MovieID: 1, Rating: 4.0}, {MovieID: 3, Rating: 4.0}, {MovieID: 8, Rating: 4.0},
+	}
+	if result := util.MergeTopK(4, higherRating, lists...); !reflect.DeepEqual(result, expected) {
+		t.Errorf("MergeTopK: Expected %v, got %v", expected, result)
+	}
+	if result := util.MergeTopK(-1, higherRating, lists...); len(result) != 6 {
+		t.Errorf("MergeTopK: Expected all 6 items without a bound, got %d", len(result))
+	}
+}
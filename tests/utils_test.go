@@ -1,10 +1,12 @@
 package tests
 
 import (
+	"recommender/algorithms"
 	model "recommender/models"
 	util "recommender/utils"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGenerateChunkFromSet(t *testing.T) {
@@ -193,3 +195,197 @@ func TestGetTfIdfVectors(t *testing.T) {
 		t.Errorf("Expected: VectorA=%v, VectorB=%v", expectedVectorA, expectedVectorB)
 	}
 }
+
+func TestDatasetIndex(t *testing.T) {
+	users := map[int]model.User{
+		1: {MovieRatings: map[int]float32{3: 4.0, 1: 2.0}},
+		2: {MovieRatings: map[int]float32{2: 5.0}},
+	}
+	movieTags := util.NewTagTable(map[int]model.MovieTags{
+		1: {UserTags: map[int]model.UserTags{1: {Tags: []string{"pixar", "toys"}}, 2: {Tags: []string{"pixar"}}}},
+	})
+	movieTitles := util.NewTitleTable(map[int]model.MovieTitle{1: {Title: "The Lord of the Rings (2001)"}})
+	userTable := util.NewUserTable(users)
+
+	index := util.BuildDatasetIndex(&userTable, &movieTags, &movieTitles, 2)
+
+	userVector := userTable.Vector(1)
+	if !reflect.DeepEqual(userVector.Keys, []int{1, 3}) || !reflect.DeepEqual(userVector.Values, []float32{2.0, 4.0}) {
+		t.Errorf("RatingTable: Expected user 1 ratings sorted by movieID, got %v %v", userVector.Keys, userVector.Values)
+	}
+	if userVector.Mean() != 3.0 || userVector.SquaredNorm() != 20.0 {
+		t.Errorf("RatingTable: Expected mean 3 and squared norm 20, got %f and %f", userVector.Mean(), userVector.SquaredNorm())
+	}
+	if raters := index.MovieRaters[1]; !reflect.DeepEqual(raters, []int{1}) {
+		t.Errorf("DatasetIndex: Expected raters [1] of movie 1, got %v", raters)
+	}
+	tagVector := index.MovieTags[1]
+	if !reflect.DeepEqual(tagVector.Keys, []string{"pixar", "toys"}) || !reflect.DeepEqual(tagVector.Values, []int{2, 1}) {
+		t.Errorf("DatasetIndex: Expected tag occurrences sorted by tag, got %v %v", tagVector.Keys, tagVector.Values)
+	}
+	expectedTokens := []string{"2001", "lord", "of", "rings", "the"}
+	if tokens := index.TitleTokens[1]; !reflect.DeepEqual(tokens, expectedTokens) {
+		t.Errorf("DatasetIndex: Expected title tokens %v, got %v", expectedTokens, tokens)
+	}
+
+	// Re-indexing after a reload must drop stale entities and pick up changed ones
+	reloadedUsers := util.NewUserTable(map[int]model.User{1: {MovieRatings: map[int]float32{7: 1.0}}})
+	index.IndexUsers(&reloadedUsers)
+	if !reflect.DeepEqual(index.MovieRaters, map[int][]int{7: {1}}) {
+		t.Errorf("DatasetIndex: Expected the raters of the reloaded users only, got %v", index.MovieRaters)
+	}
+	// Raters indexed in place from the movies match the ones gathered from the users
+	movieTable := util.NewMovieTable(map[int]model.Movie{7: {UserRatings: map[int]float32{1: 1.0}}})
+	index.IndexRaters(&movieTable)
+	if !reflect.DeepEqual(index.MovieRaters, map[int][]int{7: {1}}) {
+		t.Errorf("IndexRaters: Expected the raters of the movies, got %v", index.MovieRaters)
+	}
+}
+
+func TestDatasetIndexPostings(t *testing.T) {
+	movieTags := util.NewTagTable(map[int]model.MovieTags{
+		1: {UserTags: map[int]model.UserTags{1: {Tags: []string{"pixar", "toys"}}}},
+		2: {UserTags: map[int]model.UserTags{1: {Tags: []string{"war"}}}},
+		3: {UserTags: map[int]model.UserTags{2: {Tags: []string{"toys", "war"}}}},
+	})
+	titles := map[int]model.MovieTitle{
+		1: {Title: "Toy Story (1995)"},
+		2: {Title: "Toy Story 2 (1999)"},
+		3: {Title: "Heat (1995)"},
+	}
+	movieTitles := util.NewTitleTable(titles)
+	index := util.BuildDatasetIndex(&util.RatingTable{}, &movieTags, &movieTitles, 2)
+
+	if candidates := index.TagCandidates([]string{"toys", "war"}); !reflect.DeepEqual(candidates, []int{1, 2, 3}) {
+		t.Errorf("TagCandidates: Expected [1 2 3], got %v", candidates)
+	}
+	if candidates := index.TagCandidates([]string{"pixar", "unknown"}); !reflect.DeepEqual(candidates, []int{1}) {
+		t.Errorf("TagCandidates: Expected [1], got %v", candidates)
+	}
+	if candidates := index.TitleCandidates([]string{"1995", "story"}); !reflect.DeepEqual(candidates, []int{1, 2, 3}) {
+		t.Errorf("TitleCandidates: Expected [1 2 3], got %v", candidates)
+	}
+
+	// The cached IDF table must match the one computed over all titles
+	totalTitles := make([]string, 0, len(titles))
+	for _, movie := range titles {
+		totalTitles = append(totalTitles, movie.Title)
+	}
+	if idf := index.IDF(&movieTitles); !reflect.DeepEqual(idf, algorithms.IDF(totalTitles)) {
+		t.Errorf("IDF: Expected %v, got %v", algorithms.IDF(totalTitles), idf)
+	}
+
+	// Without an index the candidates are unknown and the IDF is computed
+	var noIndex *util.DatasetIndex
+	if candidates := noIndex.TitleCandidates([]string{"story"}); candidates != nil {
+		t.Errorf("TitleCandidates: Expected nil without an index, got %v", candidates)
+	}
+	if idf := noIndex.IDF(&movieTitles); !reflect.DeepEqual(idf, algorithms.IDF(totalTitles)) {
+		t.Errorf("IDF: Expected %v without an index, got %v", algorithms.IDF(totalTitles), idf)
+	}
+}
+
+func TestDatasetIndexUserOverlaps(t *testing.T) {
+	users := map[int]model.User{
+		1: {MovieRatings: map[int]float32{1: 4.0, 2: 3.0, 3: 5.0}},
+		2: {MovieRatings: map[int]float32{2: 2.0, 3: 1.0}},
+		3: {MovieRatings: map[int]float32{4: 5.0}},
+	}
+	userTable := util.NewUserTable(users)
+	index := util.BuildDatasetIndex(&userTable, &util.TagTable{}, &util.TitleTable{}, 2)
+
+	if raters := index.MovieRaters[3]; !reflect.DeepEqual(raters, []int{1, 2}) {
+		t.Errorf("DatasetIndex: Expected raters [1 2] of movie 3, got %v", raters)
+	}
+	// User 3 shares no movie with user 1 and must not be a candidate
+	expected := map[int]int{1: 3, 2: 2}
+	if overlaps := index.UserOverlaps(userTable.Vector(1).Keys); !reflect.DeepEqual(overlaps, expected) {
+		t.Errorf("UserOverlaps: Expected %v, got %v", expected, overlaps)
+	}
+	// The counts give the same similarities as the merge-join over the sorted sets
+	set1, set2 := userTable.Vector(1).Keys, userTable.Vector(2).Keys
+	if algorithms.JaccardSimilarityCounts(2, len(set1), len(set2)) != algorithms.JaccardSimilaritySorted(set1, set2) {
+		t.Errorf("JaccardSimilarityCounts: Expected the same similarity as JaccardSimilaritySorted")
+	}
+	if algorithms.DiceSimilarityCounts(2, len(set1), len(set2)) != algorithms.DiceSimilaritySorted(set1, set2) {
+		t.Errorf("DiceSimilarityCounts: Expected the same similarity as DiceSimilaritySorted")
+	}
+	var noIndex *util.DatasetIndex
+	if overlaps := noIndex.UserOverlaps(set1); overlaps != nil {
+		t.Errorf("UserOverlaps: Expected nil without an index, got %v", overlaps)
+	}
+}
+
+func TestDatasetIndexImplicit(t *testing.T) {
+	users := util.NewUserTable(map[int]model.User{
+		1: {MovieRatings: map[int]float32{1: 4.0, 2: 2.0}},
+		2: {MovieRatings: map[int]float32{2: 1.5}},
+	})
+	index := util.BuildDatasetIndex(&users, &util.TagTable{}, &util.TitleTable{}, 2)
+
+	implicitUsers, implicitIndex := index.ImplicitUsers(3.0, &users)
+	if vector := implicitUsers.Vector(1); !reflect.DeepEqual(vector.Keys, []int{1}) || vector.Value(0) != 1.0 || implicitUsers.Has(2) {
+		t.Errorf("ImplicitUsers: Expected the positive interaction of user 1 only, got %v", implicitUsers.ToUsers())
+	}
+	if !reflect.DeepEqual(implicitIndex.MovieRaters, map[int][]int{1: {1}}) {
+		t.Errorf("ImplicitUsers: Expected the raters of the positive interactions, got %v", implicitIndex.MovieRaters)
+	}
+	// Built once per threshold
+	if again, _ := index.ImplicitUsers(3.0, &users); again != implicitUsers {
+		t.Errorf("ImplicitUsers: Expected the implicit users of threshold 3 to be reused")
+	}
+	if allUsers, _ := index.ImplicitUsers(0, &users); allUsers.TotalRatings() != 3 {
+		t.Errorf("ImplicitUsers: Expected every rating to be positive, got %v", allUsers.ToUsers())
+	}
+	// Changed ratings drop them
+	users.SetRating(2, 3, 5.0, 0)
+	index.UpdateRating(2, 3, &users)
+	if updated, updatedIndex := index.ImplicitUsers(3.0, &users); !updated.Has(2) || !reflect.DeepEqual(updatedIndex.MovieRaters[3], []int{2}) {
+		t.Errorf("ImplicitUsers: Expected the new rating of user 2, got %v", updated.ToUsers())
+	}
+	movies := util.NewMovieTable(map[int]model.Movie{1: {UserRatings: map[int]float32{1: 4.0, 2: 2.0}}})
+	if implicitMovies := index.ImplicitMovies(3.0, &movies); implicitMovies != index.ImplicitMovies(3.0, &movies) || implicitMovies.TotalRatings() != 1 {
+		t.Errorf("ImplicitMovies: Expected the reused positive interaction of user 1, got %v", implicitMovies.ToMovies())
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := util.NewLRUCache[int, int](2)
+	if value := cache.Get(1, func() int { return 10 }); value != 10 {
+		t.Fatalf("LRUCache: Expected 10, got %d", value)
+	}
+	// Key 2 is being built while key 1 is served
+	building, release := make(chan bool), make(chan bool)
+	builds := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			builds <- cache.Get(2, func() int {
+				building <- true
+				<-release
+				return 20
+			})
+		}()
+	}
+	<-building
+	served := make(chan int)
+	go func() { served <- cache.Get(1, func() int { return -1 }) }()
+	select {
+	case value := <-served:
+		if value != 10 {
+			t.Errorf("LRUCache: Expected the cached 10, got %d", value)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("LRUCache: Cached key 1 was blocked by the build of key 2")
+	}
+	close(release)
+	// Both lookups of key 2 get the value of its single build
+	if first, second := <-builds, <-builds; first != 20 || second != 20 {
+		t.Errorf("LRUCache: Expected 20 twice, got %d and %d", first, second)
+	}
+	// The least recently used key is evicted
+	cache.Get(1, func() int { return -1 })
+	cache.Get(3, func() int { return 30 })
+	if value := cache.Get(2, func() int { return 21 }); value != 21 {
+		t.Errorf("LRUCache: Expected key 2 to be evicted, got %d", value)
+	}
+}
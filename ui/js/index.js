@@ -12,7 +12,17 @@ document.getElementById('recommendationForm').addEventListener('submit', functio
     const similarity = document.getElementById('similarity').value;
     const algorithm = document.getElementById('algorithm').value;
     const input = parseInt(document.getElementById('input').value);
+    const inputType = document.getElementById('inputType').value;
+    const seeds = document.getElementById('seeds').value.trim();
     const maxRecords = parseInt(document.getElementById('maxRecords').value);
+    const implicit = document.getElementById('implicit').checked;
+    const threshold = parseFloat(document.getElementById('threshold').value);
+    const approximate = document.getElementById('approximate').checked;
+    const minRatings = parseInt(document.getElementById('minRatings').value);
+    const minYear = parseInt(document.getElementById('minYear').value);
+    const maxYear = parseInt(document.getElementById('maxYear').value);
+    // Lists of the result filters, sent only when they are set
+    const filterLists = ['allow', 'deny', 'includeTags', 'excludeTags', 'includeGenres', 'excludeGenres'];
     // Contruct the http request query
     const queryParams = {
         similarity,
@@ -20,9 +30,39 @@ document.getElementById('recommendationForm').addEventListener('submit', functio
         recommendations,
         input,
     };
+    if (algorithm === 'p3alpha' || algorithm === 'rp3beta') {
+        queryParams.inputType = inputType;
+    }
+    if (algorithm === 'assoc' && seeds !== '') {
+        queryParams.seeds = seeds;
+    }
     if (!isNaN(maxRecords) && maxRecords > 0) {
         queryParams.maxRecords = maxRecords;
     }
+    if (implicit) {
+        queryParams.implicit = implicit;
+    }
+    if (!isNaN(threshold) && threshold > 0) {
+        queryParams.threshold = threshold;
+    }
+    if (approximate) {
+        queryParams.approximate = approximate;
+    }
+    if (!isNaN(minRatings) && minRatings > 0) {
+        queryParams.minRatings = minRatings;
+    }
+    if (!isNaN(minYear) && minYear > 0) {
+        queryParams.minYear = minYear;
+    }
+    if (!isNaN(maxYear) && maxYear > 0) {
+        queryParams.maxYear = maxYear;
+    }
+    for (const filter of filterLists) {
+        const value = document.getElementById(filter).value.trim();
+        if (value !== '') {
+            queryParams[filter] = value;
+        }
+    }
     const queryString = Object.keys(queryParams)
         .filter(key => queryParams[key] !== undefined && queryParams[key] !== null)
         .map(key => encodeURIComponent(key) + '=' + encodeURIComponent(queryParams[key]))
@@ -32,7 +72,9 @@ document.getElementById('recommendationForm').addEventListener('submit', functio
         method: 'GET'
     })
         .then(response => {
-            if (response.status !== 200) {
+            // Error responses of the server are JSON as well and carry their reason in the message
+            const contentType = response.headers.get('Content-Type') || '';
+            if (response.status !== 200 && !contentType.includes('application/json')) {
                 throw new Error('Network response error.');
             }
             return response.json();
@@ -45,8 +87,16 @@ document.getElementById('recommendationForm').addEventListener('submit', functio
                 // If message is not empty, something went wrong with the query
                 document.getElementById('recommendationResults').innerText = responseData.message;
             } else {
-                const inputIsUserID = queryParams.algorithm === 'user' || queryParams.algorithm === "item";
-                if (!inputIsUserID) {
+                // The server falls back to a non-personalized algorithm when the input is not found
+                const algorithm = responseData.fallback !== '' ? responseData.fallback : queryParams.algorithm;
+                const nonPersonalized = algorithm === 'popular' || algorithm === 'top-rated' || algorithm === 'trending';
+                const randomWalk = algorithm === 'p3alpha' || algorithm === 'rp3beta';
+                const inputIsUserID = nonPersonalized || algorithm === 'user' || algorithm === "item" || algorithm === "bpr" || algorithm === "slopeone" ||
+                    (randomWalk && queryParams.inputType === 'user');
+                const resultIsScore = algorithm === 'bpr' || algorithm === 'popular' || algorithm === 'trending' || randomWalk || queryParams.implicit;
+                if (responseData.fallback !== '') {
+                    document.getElementById('metaInfo').innerText = `Input ${ queryParams.input } was not found. Showing ${ responseData.fallback } movies instead.`;
+                } else if (!inputIsUserID) {
                     document.getElementById('metaInfo').innerText = `Results for movie ${ responseData.metaInfo }`;
                 }
                 // Create a table of results inside the div with id="recommendationResults"
@@ -63,10 +113,20 @@ document.getElementById('recommendationForm').addEventListener('submit', functio
                 thCol2.innerText = 'Movie Title';
                 const thCol3 = document.createElement('th');
                 thCol3.scope = 'col';
-                thCol3.innerText = inputIsUserID ? 'Forecasted rating' : 'Similarity';
+                thCol3.innerText = resultIsScore ? 'Score' : algorithm === 'top-rated' ? 'Weighted rating' : algorithm === 'assoc' ? 'Confidence' : inputIsUserID ? 'Forecasted rating' : 'Similarity';
                 trHead.appendChild(thCol1);
                 trHead.appendChild(thCol2);
                 trHead.appendChild(thCol3);
+                // Association rules come with extra metrics
+                const ruleColumns = ['Support', 'Lift', 'Because of'];
+                if (algorithm === 'assoc') {
+                    ruleColumns.forEach(column => {
+                        const th = document.createElement('th');
+                        th.scope = 'col';
+                        th.innerText = column;
+                        trHead.appendChild(th);
+                    });
+                }
                 thead.appendChild(trHead);
                 table.appendChild(thead);
                 // Fill the table body dynamically with the responseData
@@ -83,6 +143,13 @@ document.getElementById('recommendationForm').addEventListener('submit', functio
                     tr.appendChild(tdCol1);
                     tr.appendChild(tdCol2);
                     tr.appendChild(tdCol3);
+                    if (algorithm === 'assoc') {
+                        [item.support, item.lift, item.antecedent.join(', ')].forEach(value => {
+                            const td = document.createElement('td');
+                            td.innerText = value;
+                            tr.appendChild(td);
+                        });
+                    }
                     tbody.appendChild(tr);
                 });
                 table.appendChild(tbody);
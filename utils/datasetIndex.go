@@ -0,0 +1,359 @@
+package util
+
+import (
+	"math"
+	"recommender/algorithms"
+	"recommender/helpers"
+	model "recommender/models"
+	"slices"
+	"sync"
+)
+
+/*
+Per-entity statistics that the recommenders would otherwise rebuild for every pair of entities, on top of the
+tables of the dataset, which already hold the sorted ratings of every user and movie.
+The index must be rebuilt (or the matching part re-indexed) whenever the data is (re)loaded, and updated
+(see UpdateRating and UpdateMovieTags) whenever single records change.
+  - MovieRaters:   sorted IDs of the users who rated every movie
+  - MovieTags:     tag occurrences of every movie sorted by tag
+  - TagPostings:   sorted IDs of the movies with every tag
+  - TitleTokens:   sorted set of title tokens of every movie
+  - TitlePostings: sorted IDs of the movies with every title token
+  - TitleIDF:      IDF of every title token over all titles
+
+The implicit feedback of the ratings is built on demand, once per threshold (see ImplicitUsers and ImplicitMovies).
+*/
+type DatasetIndex struct {
+	MovieRaters   map[int][]int
+	MovieTags     map[int]algorithms.SparseVector[string, int]
+	TagPostings   map[string][]int
+	TitleTokens   map[int][]string
+	TitlePostings map[string][]int
+	TitleIDF      map[string]float64
+	numThreads    int
+	// Implicit feedback of the users and movies, keyed by threshold
+	implicitUsers  *LRUCache[float64, *implicitUsers]
+	implicitMovies *LRUCache[float64, *RatingTable]
+}
+
+// Max number of thresholds whose implicit feedback is kept at once
+const maxImplicitThresholds = 4
+
+// Implicit feedback of every user, with the index of its raters to find the users who share movies
+type implicitUsers struct {
+	users RatingTable
+	index *DatasetIndex
+}
+
+// Builds the index of every table in parallel. Empty tables, eg. ones not loaded by the CLI, produce empty parts.
+func BuildDatasetIndex(users *RatingTable, movieTags *TagTable, movieTitles *TitleTable, numThreads int) *DatasetIndex {
+	index := &DatasetIndex{numThreads: numThreads}
+	index.resetImplicit()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() { defer wg.Done(); index.IndexUsers(users) }()
+	go func() { defer wg.Done(); index.IndexMovieTags(movieTags) }()
+	go func() { defer wg.Done(); index.IndexMovieTitles(movieTitles) }()
+	wg.Wait()
+	return index
+}
+
+// Returns a shallow copy of the index, whose parts can be re-indexed without affecting the original one
+func (index *DatasetIndex) Clone() *DatasetIndex {
+	clone := *index
+	clone.resetImplicit()
+	return &clone
+}
+
+// Indexes the raters of every movie rated by $users
+func (index *DatasetIndex) IndexUsers(users *RatingTable) {
+	index.resetImplicit()
+	index.MovieRaters = make(map[int][]int)
+	for _, userID := range users.IDs() {
+		for _, movieID := range users.Vector(userID).Keys {
+			index.MovieRaters[movieID] = append(index.MovieRaters[movieID], userID)
+		}
+	}
+}
+
+/*
+Indexes the raters of every movie as the keys of its ratings in $movies, which are used in place instead of being
+copied. $movies must hold every rating of the users, eg. when neither of them is limited.
+*/
+func (index *DatasetIndex) IndexRaters(movies *RatingTable) {
+	index.resetImplicit()
+	index.MovieRaters = make(map[int][]int, movies.Len())
+	for _, movieID := range movies.IDs() {
+		index.MovieRaters[movieID] = movies.Vector(movieID).Keys
+	}
+}
+
+func (index *DatasetIndex) IndexMovieTags(movieTags *TagTable) {
+	index.MovieTags = indexEntities(movieTags.IDs(), index.numThreads, func(movieID int) algorithms.SparseVector[string, int] {
+		return algorithms.NewSparseVector(movieTags.Occurrences(movieID))
+	})
+	index.TagPostings = buildPostings(index.MovieTags, func(vector algorithms.SparseVector[string, int]) []string {
+		return vector.Keys
+	})
+}
+
+func (index *DatasetIndex) IndexMovieTitles(movieTitles *TitleTable) {
+	index.TitleTokens = indexEntities(movieTitles.IDs(), index.numThreads, func(movieID int) []string {
+		return titleTokens(movieTitles.Title(movieID))
+	})
+	index.TitlePostings = buildPostings(index.TitleTokens, func(tokens []string) []string {
+		return tokens
+	})
+	// Same as algorithms.IDF over all titles, since the postings hold the titles containing each token once
+	index.TitleIDF = make(map[string]float64, len(index.TitlePostings))
+	for token, movieIDs := range index.TitlePostings {
+		index.TitleIDF[token] = math.Log10(float64(len(index.TitleTokens)) / float64(len(movieIDs)))
+	}
+}
+
+/*
+Re-indexes the raters of $movieID after the rating of $userID to it changed in $users, eg. through the Web-Server.
+Postings are replaced rather than modified, since they may point into a table.
+*/
+func (index *DatasetIndex) UpdateRating(userID int, movieID int, users *RatingTable) {
+	index.resetImplicit()
+	if index.MovieRaters == nil {
+		return
+	}
+	_, rated := users.Rating(userID, movieID)
+	raters := updatePosting(index.MovieRaters[movieID], userID, rated)
+	updateEntry(index.MovieRaters, movieID, len(raters) > 0, func() []int { return raters })
+}
+
+// Re-indexes the tags of $movieID after they changed, eg. through the Web-Server
+func (index *DatasetIndex) UpdateMovieTags(movieID int, movieTags *TagTable) {
+	if index.MovieTags == nil {
+		return
+	}
+	previous := index.MovieTags[movieID]
+	updateEntry(index.MovieTags, movieID, movieTags.Has(movieID), func() algorithms.SparseVector[string, int] {
+		return algorithms.NewSparseVector(movieTags.Occurrences(movieID))
+	})
+	current := index.MovieTags[movieID]
+	for _, tag := range previous.Keys {
+		if _, found := slices.BinarySearch(current.Keys, tag); !found {
+			if movieIDs := updatePosting(index.TagPostings[tag], movieID, false); len(movieIDs) > 0 {
+				index.TagPostings[tag] = movieIDs
+			} else {
+				delete(index.TagPostings, tag)
+			}
+		}
+	}
+	for _, tag := range current.Keys {
+		index.TagPostings[tag] = updatePosting(index.TagPostings[tag], movieID, true)
+	}
+}
+
+// Returns the sorted tag occurrences of $movieID, from the index if it covers the movie or computed from $movieTags otherwise
+func (index *DatasetIndex) TagVector(movieID int, movieTags *TagTable) algorithms.SparseVector[string, int] {
+	if index != nil {
+		if vector, exists := index.MovieTags[movieID]; exists {
+			return vector
+		}
+	}
+	return algorithms.NewSparseVector(movieTags.Occurrences(movieID))
+}
+
+// Returns the sorted title tokens of $movieID, from the index if it covers the movie or computed from $movieTitles otherwise
+func (index *DatasetIndex) Tokens(movieID int, movieTitles *TitleTable) []string {
+	if index != nil {
+		if tokens, exists := index.TitleTokens[movieID]; exists {
+			return tokens
+		}
+	}
+	return titleTokens(movieTitles.Title(movieID))
+}
+
+// Returns the sorted IDs of the indexed movies sharing at least one of $tags, or nil if the index doesn't cover tags
+func (index *DatasetIndex) TagCandidates(tags []string) []int {
+	if index == nil || index.TagPostings == nil {
+		return nil
+	}
+	return mergePostings(index.TagPostings, tags)
+}
+
+// Returns the sorted IDs of the indexed movies sharing at least one of $tokens, or nil if the index doesn't cover titles
+func (index *DatasetIndex) TitleCandidates(tokens []string) []int {
+	if index == nil || index.TitlePostings == nil {
+		return nil
+	}
+	return mergePostings(index.TitlePostings, tokens)
+}
+
+/*
+Returns the {userID:count} number of $movieIDs rated by every indexed user who rated at least one of them,
+by traversing the raters of $movieIDs only, or nil if the index doesn't cover users.
+*/
+func (index *DatasetIndex) UserOverlaps(movieIDs []int) map[int]int {
+	if index == nil || index.MovieRaters == nil {
+		return nil
+	}
+	overlaps := make(map[int]int)
+	for _, movieID := range movieIDs {
+		for _, userID := range index.MovieRaters[movieID] {
+			overlaps[userID]++
+		}
+	}
+	return overlaps
+}
+
+// Returns the cached IDF table if $movieTitles are the indexed titles, or computes it over $movieTitles otherwise, eg. for subsets
+func (index *DatasetIndex) IDF(movieTitles *TitleTable) map[string]float64 {
+	if index != nil && index.TitleIDF != nil && len(index.TitleTokens) == movieTitles.Len() {
+		return index.TitleIDF
+	}
+	totalTitles := make([]string, 0, movieTitles.Len())
+	for _, movieID := range movieTitles.IDs() {
+		totalTitles = append(totalTitles, movieTitles.Title(movieID))
+	}
+	return algorithms.IDF(totalTitles)
+}
+
+/*
+Returns the implicit feedback of $users (see RatingTable.ToImplicit) for $threshold, along with the index of its raters
+to pass to UserOverlaps. Both are built once per threshold, and the index must hold the raters of $users.
+*/
+func (index *DatasetIndex) ImplicitUsers(threshold float64, users *RatingTable) (*RatingTable, *DatasetIndex) {
+	build := func() *implicitUsers {
+		implicit := &implicitUsers{users: users.ToImplicit(threshold)}
+		if index != nil && threshold <= 0 {
+			// Every rating is a positive interaction, so the raters stay the same
+			implicit.index = &DatasetIndex{MovieRaters: index.MovieRaters}
+		} else if index != nil && index.MovieRaters != nil {
+			implicit.index = &DatasetIndex{}
+			implicit.index.IndexUsers(&implicit.users)
+		}
+		return implicit
+	}
+	var implicit *implicitUsers
+	if index == nil || index.implicitUsers == nil {
+		implicit = build()
+	} else {
+		implicit = index.implicitUsers.Get(threshold, build)
+	}
+	return &implicit.users, implicit.index
+}
+
+// Returns the implicit feedback of $movies (see RatingTable.ToImplicit) for $threshold, built once per threshold
+func (index *DatasetIndex) ImplicitMovies(threshold float64, movies *RatingTable) *RatingTable {
+	build := func() *RatingTable {
+		implicitMovies := movies.ToImplicit(threshold)
+		return &implicitMovies
+	}
+	if index == nil || index.implicitMovies == nil {
+		return build()
+	}
+	return index.implicitMovies.Get(threshold, build)
+}
+
+// Drops the implicit feedback built so far, eg. after the ratings changed
+func (index *DatasetIndex) resetImplicit() {
+	index.implicitUsers = NewLRUCache[float64, *implicitUsers](maxImplicitThresholds)
+	index.implicitMovies = NewLRUCache[float64, *RatingTable](maxImplicitThresholds)
+}
+
+// Returns the sorted set of tokens of $title
+func titleTokens(title string) []string {
+	tokens := helpers.ExtractTokensFromStr(title)
+	slices.Sort(tokens)
+	return slices.Compact(tokens)
+}
+
+// Returns the number of times every tag was given to a movie
+func CountTagOccurrences(movieTags model.MovieTags) map[string]int {
+	tagCounts := make(map[string]int)
+	for _, userTags := range movieTags.UserTags {
+		for _, tag := range userTags.Tags {
+			tagCounts[tag]++
+		}
+	}
+	return tagCounts
+}
+
+// Returns the {term:IDs} postings of every term of the $indexed entities, with IDs sorted in ascending order
+func buildPostings[K comparable, V any](indexed map[int]V, terms func(V) []K) map[K][]int {
+	ids := make([]int, 0, len(indexed))
+	for id := range indexed {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	postings := make(map[K][]int)
+	for _, id := range ids {
+		for _, term := range terms(indexed[id]) {
+			postings[term] = append(postings[term], id)
+		}
+	}
+	return postings
+}
+
+// Sets $indexed[$id] to the result of $build if the entity $exists, or removes it otherwise
+func updateEntry[V any](indexed map[int]V, id int, exists bool, build func() V) {
+	if exists {
+		indexed[id] = build()
+	} else {
+		delete(indexed, id)
+	}
+}
+
+// Returns a copy of the sorted $ids with $id added if it's $present or removed otherwise, or $ids if they already match
+func updatePosting(ids []int, id int, present bool) []int {
+	position, found := slices.BinarySearch(ids, id)
+	if found == present {
+		return ids
+	}
+	if present {
+		return slices.Insert(slices.Clip(ids), position, id)
+	}
+	return slices.Delete(slices.Clone(ids), position, position+1)
+}
+
+// Returns the sorted union of the postings of $terms
+func mergePostings(postings map[string][]int, terms []string) []int {
+	seen := make(map[int]bool)
+	ids := make([]int, 0)
+	for _, term := range terms {
+		for _, id := range postings[term] {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Applies $build to every one of $ids in parallel and returns the results keyed by ID
+func indexEntities[V any](ids []int, numThreads int, build func(int) V) map[int]V {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	// Divide entities into chunks to split the workload to multiple routines
+	if numThreads > len(ids) {
+		numThreads = len(ids)
+	}
+	indexed := make(map[int]V, len(ids))
+	for _, idChunk := range GenerateChunkFromSet(ids, numThreads) {
+		wg.Add(1)
+		go func(ids []int) {
+			defer wg.Done()
+			localIndexed := make(map[int]V, len(ids))
+			for _, id := range ids {
+				localIndexed[id] = build(id)
+			}
+			// Merge all local results while protecting concurrent writing to shared map
+			mu.Lock()
+			for id, value := range localIndexed {
+				indexed[id] = value
+			}
+			mu.Unlock()
+		}(idChunk)
+	}
+	// Wait for all routines to finish
+	wg.Wait()
+	return indexed
+}
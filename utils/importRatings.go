@@ -0,0 +1,109 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sources of the rating exports the importer reads
+const (
+	Letterboxd = "letterboxd"
+	IMDb       = "imdb"
+)
+
+// Rating of an export file, on the 0.5-5 scale of MovieLens
+type ImportedRating struct {
+	// Row of the rating in the export file, counting the header as row 1
+	Row    int
+	Titles []string
+	// Release year, or 0 if the row has none
+	Year   int
+	Rating float32
+}
+
+/*
+Reads the ratings of a Letterboxd ratings.csv or of an IMDb ratings export, telling them apart by their header:
+  - Letterboxd: Name, Year and Rating columns, with ratings from 0.5 to 5 stars
+  - IMDb: Title, Original Title (optional), Year and Your Rating columns, with ratings from 1 to 10, halved to 0.5-5
+
+Rows without a rating, eg. of a watched.csv, are skipped.
+*/
+func ReadRatingsExport(filePath string) (string, []ImportedRating, error) {
+	file, reader, header, err := openCSVFile(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	source, titleColumns, ratingColumn, scale := Letterboxd, []int{getColumnIndex(header, "Name")}, getColumnIndex(header, "Rating"), float32(1)
+	if getColumnIndex(header, "Your Rating") != -1 {
+		source, titleColumns, ratingColumn, scale = IMDb, []int{getColumnIndex(header, "Title")}, getColumnIndex(header, "Your Rating"), 0.5
+		if originalTitleColumn := getColumnIndex(header, "Original Title"); originalTitleColumn != -1 {
+			titleColumns = append(titleColumns, originalTitleColumn)
+		}
+	}
+	yearColumn := getColumnIndex(header, "Year")
+	if titleColumns[0] == -1 || ratingColumn == -1 || yearColumn == -1 {
+		return "", nil, errors.New("unknown export format, expected a Letterboxd ratings.csv or an IMDb ratings export")
+	}
+	reader.FieldsPerRecord = -1
+	ratings := make([]ImportedRating, 0)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return source, ratings, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		field := func(column int) string {
+			if column < len(record) {
+				return strings.TrimSpace(record[column])
+			}
+			return ""
+		}
+		if field(ratingColumn) == "" {
+			continue
+		}
+		rating, err := strconv.ParseFloat(field(ratingColumn), 32)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid rating '%s' in row %d", field(ratingColumn), row)
+		}
+		importedRating := ImportedRating{Row: row, Rating: float32(rating) * scale}
+		if field(yearColumn) != "" {
+			if importedRating.Year, err = strconv.Atoi(field(yearColumn)); err != nil {
+				return "", nil, fmt.Errorf("invalid year '%s' in row %d", field(yearColumn), row)
+			}
+		}
+		for _, column := range titleColumns {
+			if title := field(column); title != "" {
+				importedRating.Titles = append(importedRating.Titles, title)
+			}
+		}
+		ratings = append(ratings, importedRating)
+	}
+}
+
+/*
+Matches $ratings to the movies of $matcher by any of their titles and their year. Returns the {movieID:rating} profile
+of the matched ones, where a repeated movie keeps its last rating, and the unmatched ones.
+*/
+func MatchImportedRatings(ratings []ImportedRating, matcher *TitleMatcher) (map[int]float32, []ImportedRating) {
+	profile := make(map[int]float32)
+	unmatched := make([]ImportedRating, 0)
+	for _, rating := range ratings {
+		matched := false
+		for _, title := range rating.Titles {
+			if movieID, found := matcher.Match(title, rating.Year); found {
+				profile[movieID], matched = rating.Rating, true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, rating)
+		}
+	}
+	return profile, unmatched
+}
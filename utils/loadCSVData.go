@@ -115,9 +115,14 @@ func loadMovieTitles(filePath string, maxRows int) map[int]model.MovieTitle {
 			log.Fatal(errors.New("Invalid movieID"))
 			return nil
 		}
-		movieTitles[movieID] = model.MovieTitle{
+		movieTitle := model.MovieTitle{
 			Title: strings.Trim(record[1], "\""),
 		}
+		// Genres are separated by '|', and movies without any have a placeholder
+		if len(record) > 2 && record[2] != "" && record[2] != "(no genres listed)" {
+			movieTitle.Genres = strings.Split(record[2], "|")
+		}
+		movieTitles[movieID] = movieTitle
 	}
 	return movieTitles
 }
@@ -160,9 +165,19 @@ func loadMovies(filePath string, maxRows int) map[int]model.Movie {
 		if !exists {
 			movie = model.Movie{
 				UserRatings: make(map[int]float32),
+				RatingTimes: make(map[int]int64),
 			}
 		}
 		movie.UserRatings[userID] = float32(rating)
+		// Timestamp column is optional
+		if len(record) > 3 {
+			timestamp, err := strconv.ParseInt(record[3], 10, 64)
+			if err != nil {
+				log.Fatal(errors.New("Invalid timestamp"))
+				return nil
+			}
+			movie.RatingTimes[userID] = timestamp
+		}
 		movies[movieID] = movie
 	}
 	return movies
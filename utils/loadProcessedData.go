@@ -16,6 +16,9 @@ var DataTypes = map[string]reflect.Type{
 	"MovieTitle":   reflect.TypeOf(map[int]model.MovieTitle{}),
 	"MovieRatings": reflect.TypeOf(map[int]model.Movie{}),
 	"MovieTags":    reflect.TypeOf(map[int]model.MovieTags{}),
+	"Rules":        reflect.TypeOf([]model.AssociationRule{}),
+	"Neighbors":    reflect.TypeOf(model.MovieNeighbors{}),
+	"Quantized":    reflect.TypeOf(model.QuantizedRatings{}),
 }
 
 /*
@@ -24,6 +27,9 @@ Valid datatypes for $dataField:
   - MovieTitles map:  map[int]model.MovieTitle{}}
   - MovieRatings map: map[int]model.Movie{}}
   - MovieTags map:    map[int]model.MovieTags{}}
+  - Rules slice:      []model.AssociationRule{} (maxRecords is ignored)
+  - Neighbors map:    model.MovieNeighbors{}
+  - Quantized:        model.QuantizedRatings{} (maxRecords is ignored, see LoadQuantizedRatings)
 */
 func LoadData(dataField interface{}, filePath string, maxRecords ...int) {
 	rowsToRead := -1
@@ -43,6 +49,12 @@ func LoadData(dataField interface{}, filePath string, maxRecords ...int) {
 				data = loadProcessedData(filePath, rowsToRead, decodeMovie)
 			case "MovieTags":
 				data = loadProcessedData(filePath, rowsToRead, decodeMovieTags)
+			case "Rules":
+				data = loadProcessedData(filePath, -1, decodeRules)
+			case "Neighbors":
+				data = loadProcessedData(filePath, rowsToRead, decodeNeighbors)
+			case "Quantized":
+				data = loadProcessedData(filePath, -1, decodeQuantized)
 			default:
 				log.Fatalf("Unsupported data type: %v", fieldType)
 				return
@@ -62,7 +74,11 @@ func loadProcessedData(filePath string, maxRecords int, decodeFunc func(*gob.Dec
 	}
 	defer file.Close()
 	decoder := gob.NewDecoder(file)
-	data := decodeFunc(decoder)
+	return limitRecords(decodeFunc(decoder), maxRecords)
+}
+
+// Keeps the $maxRecords records of the $data map with the lowest IDs, or all of them if $maxRecords is -1
+func limitRecords(data interface{}, maxRecords int) interface{} {
 	if maxRecords != -1 {
 		dataValue := reflect.ValueOf(data)
 		limitedData := reflect.MakeMap(reflect.TypeOf(dataValue.Interface()))
@@ -119,3 +135,30 @@ func decodeMovieTags(decoder *gob.Decoder) interface{} {
 	}
 	return data
 }
+
+func decodeRules(decoder *gob.Decoder) interface{} {
+	var data []model.AssociationRule
+	if err := decoder.Decode(&data); err != nil {
+		log.Fatal(errors.New(fmt.Sprintf("Failed to decode association rule data")))
+		return nil
+	}
+	return data
+}
+
+func decodeNeighbors(decoder *gob.Decoder) interface{} {
+	var data model.MovieNeighbors
+	if err := decoder.Decode(&data); err != nil {
+		log.Fatal(errors.New(fmt.Sprintf("Failed to decode neighbor data")))
+		return nil
+	}
+	return data
+}
+
+func decodeQuantized(decoder *gob.Decoder) interface{} {
+	var data model.QuantizedRatings
+	if err := decoder.Decode(&data); err != nil {
+		log.Fatal(errors.New(fmt.Sprintf("Failed to decode quantized rating data")))
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,91 @@
+package util
+
+import "sync"
+
+/*
+Cache of the values derived from the dataset, keyed by what they were derived from, eg. a view of the dataset or a
+threshold. Only the most recently used values are kept, up to its capacity, so that requests asking for different
+keys reuse their own values instead of replacing each other's. Safe for concurrent use.
+*/
+type LRUCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[K]*lruCacheEntry[V]
+	// Number of lookups so far, to find the least recently used entry
+	clock uint64
+}
+
+type lruCacheEntry[V any] struct {
+	value    V
+	lastUsed uint64
+	// Builds the value once, outside the lock of the cache
+	once  sync.Once
+	built bool
+}
+
+// Returns an empty cache of at most $capacity values
+func NewLRUCache[K comparable, V any](capacity int) *LRUCache[K, V] {
+	return &LRUCache[K, V]{capacity: capacity}
+}
+
+/*
+Returns the cached value of $key, or the one returned by $create which is then cached. $create runs outside the lock,
+so that other keys are served meanwhile, and only once per key: concurrent lookups of $key wait for its value.
+*/
+func (cache *LRUCache[K, V]) Get(key K, create func() V) V {
+	entry := cache.entry(key)
+	entry.once.Do(func() {
+		defer func() {
+			// A failed build isn't cached, so that the next lookup builds the value again
+			if !entry.built {
+				cache.remove(key, entry)
+			}
+		}()
+		entry.value = create()
+		entry.built = true
+	})
+	return entry.value
+}
+
+// Returns the entry of $key, adding it in place of the least recently used entry if needed
+func (cache *LRUCache[K, V]) entry(key K) *lruCacheEntry[V] {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.clock++
+	if entry, exists := cache.entries[key]; exists {
+		entry.lastUsed = cache.clock
+		return entry
+	}
+	if cache.entries == nil {
+		cache.entries = make(map[K]*lruCacheEntry[V], cache.capacity)
+	}
+	if len(cache.entries) >= cache.capacity {
+		var oldestKey K
+		oldest := cache.clock
+		for key, entry := range cache.entries {
+			if entry.lastUsed < oldest {
+				oldestKey, oldest = key, entry.lastUsed
+			}
+		}
+		delete(cache.entries, oldestKey)
+	}
+	entry := &lruCacheEntry[V]{lastUsed: cache.clock}
+	cache.entries[key] = entry
+	return entry
+}
+
+// Removes the $entry of $key, unless it was replaced since
+func (cache *LRUCache[K, V]) remove(key K, entry *lruCacheEntry[V]) {
+	cache.mu.Lock()
+	if cache.entries[key] == entry {
+		delete(cache.entries, key)
+	}
+	cache.mu.Unlock()
+}
+
+// Drops every cached value. Values being built are still returned to the lookups waiting for them.
+func (cache *LRUCache[K, V]) Reset() {
+	cache.mu.Lock()
+	cache.entries = nil
+	cache.mu.Unlock()
+}
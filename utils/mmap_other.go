@@ -0,0 +1,14 @@
+//go:build !unix
+
+package util
+
+import "os"
+
+// Reads the whole file at $filePath, since mmap isn't available, and returns its bytes with a no-op unmap function
+func mapFile(filePath string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
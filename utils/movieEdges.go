@@ -0,0 +1,91 @@
+package util
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	model "recommender/models"
+	"strconv"
+)
+
+/*
+Writes $edges to $filePath in the requested $format:
+  - csv: a "movieA,movieB,similarity" header followed by one edge per line
+  - bin: one little-endian record per edge, made of int32 movieA, int32 movieB and float64 similarity
+*/
+func WriteMovieEdges(edges []model.MovieEdge, filePath string, format string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	switch format {
+	case "csv":
+		err = WriteMovieEdgesCSV(writer, edges)
+	case "bin":
+		err = WriteMovieEdgesBinary(writer, edges)
+	default:
+		err = fmt.Errorf("unknown edge format '%s'", format)
+	}
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func WriteMovieEdgesCSV(writer io.Writer, edges []model.MovieEdge) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write([]string{"movieA", "movieB", "similarity"}); err != nil {
+		return err
+	}
+	for _, edge := range edges {
+		record := []string{strconv.Itoa(edge.MovieA), strconv.Itoa(edge.MovieB), strconv.FormatFloat(edge.Similarity, 'g', -1, 64)}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// Size in bytes of a binary edge record
+const edgeRecordSize = 16
+
+func WriteMovieEdgesBinary(writer io.Writer, edges []model.MovieEdge) error {
+	record := make([]byte, edgeRecordSize)
+	for _, edge := range edges {
+		binary.LittleEndian.PutUint32(record[0:4], uint32(int32(edge.MovieA)))
+		binary.LittleEndian.PutUint32(record[4:8], uint32(int32(edge.MovieB)))
+		binary.LittleEndian.PutUint64(record[8:16], math.Float64bits(edge.Similarity))
+		if _, err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the edges written by WriteMovieEdgesBinary until the end of $reader
+func ReadMovieEdgesBinary(reader io.Reader) ([]model.MovieEdge, error) {
+	edges := make([]model.MovieEdge, 0)
+	record := make([]byte, edgeRecordSize)
+	for {
+		_, err := io.ReadFull(reader, record)
+		if errors.Is(err, io.EOF) {
+			return edges, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, model.MovieEdge{
+			MovieA:     int(int32(binary.LittleEndian.Uint32(record[0:4]))),
+			MovieB:     int(int32(binary.LittleEndian.Uint32(record[4:8]))),
+			Similarity: math.Float64frombits(binary.LittleEndian.Uint64(record[8:16])),
+		})
+	}
+}
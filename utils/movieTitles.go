@@ -0,0 +1,112 @@
+package util
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Year (or range of years of a series) at the end of a MovieLens title, eg. "Heat (1995)"
+	titleYearPattern = regexp.MustCompile(`^(.*?)\s*\((\d{4})(?:[-–]\d{4})?\)\s*$`)
+	// Alternative title at the end of a MovieLens title, eg. "Amelie (Fabuleux destin d'Amélie Poulain, Le)"
+	titleAliasPattern = regexp.MustCompile(`^(.+?)\s*\(([^()]+)\)$`)
+	// Article moved to the end of a MovieLens title for sorting, eg. "Matrix, The"
+	trailingArticlePattern = regexp.MustCompile(`^(.+), (The|A|An|La|Le|Les|L'|Il|Lo|Gli|El|Los|Las|Das|Der|Die|Den|Det|De|Het|O|Os|As|Un|Une|Una|Uno|Ein|Eine)$`)
+	// Latin letters with diacritics and ligatures, replaced by their plain spelling
+	diacriticsReplacer = strings.NewReplacer(
+		"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae", "ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+		"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n", "ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+		"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+	)
+)
+
+// Splits a MovieLens title into its name and release year, or 0 if it has none
+func SplitTitleYear(title string) (string, int) {
+	match := titleYearPattern.FindStringSubmatch(title)
+	if match == nil {
+		return strings.TrimSpace(title), 0
+	}
+	year, _ := strconv.Atoi(match[2])
+	return match[1], year
+}
+
+/*
+Returns the normalized names a movie may be known by: the one of its MovieLens title and its alternative titles, with
+their trailing article moved back to the front, eg. "Cité des enfants perdus, La" is also known as "la cite des enfants perdus"
+*/
+func TitleNames(title string) []string {
+	name, _ := SplitTitleYear(title)
+	names := make([]string, 0, 1)
+	for {
+		match := titleAliasPattern.FindStringSubmatch(name)
+		if match == nil {
+			break
+		}
+		names = append(names, NormalizeTitle(moveArticleToFront(strings.TrimPrefix(match[2], "a.k.a. "))))
+		name = match[1]
+	}
+	return append(names, NormalizeTitle(moveArticleToFront(name)))
+}
+
+// Lowercases $title, strips its diacritics and replaces its punctuation with spaces, so that titles from other sources compare equal
+func NormalizeTitle(title string) string {
+	title = diacriticsReplacer.Replace(strings.ToLower(title))
+	title = strings.ReplaceAll(title, "&", " and ")
+	return strings.Join(strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func moveArticleToFront(name string) string {
+	match := trailingArticlePattern.FindStringSubmatch(name)
+	if match == nil {
+		return name
+	}
+	return match[2] + " " + match[1]
+}
+
+// Looks up movies by normalized name and release year
+type TitleMatcher struct {
+	// Movie IDs of every "name|year" key, in ascending order
+	movieIDs map[string][]int
+}
+
+// Indexes every name of $movieTitles
+func NewTitleMatcher(movieTitles *TitleTable) *TitleMatcher {
+	matcher := &TitleMatcher{movieIDs: make(map[string][]int)}
+	for _, movieID := range movieTitles.IDs() {
+		title := movieTitles.Title(movieID)
+		_, year := SplitTitleYear(title)
+		for _, name := range TitleNames(title) {
+			key := titleKey(name, year)
+			if !slices.Contains(matcher.movieIDs[key], movieID) {
+				matcher.movieIDs[key] = append(matcher.movieIDs[key], movieID)
+			}
+		}
+	}
+	for _, movieIDs := range matcher.movieIDs {
+		slices.Sort(movieIDs)
+	}
+	return matcher
+}
+
+/*
+Returns the ID of the movie named $title released in $year, also trying the years before and after it, since sources
+often disagree by one year about festival and international releases. Movies sharing a name and year resolve to the lowest ID.
+*/
+func (matcher *TitleMatcher) Match(title string, year int) (int, bool) {
+	name := NormalizeTitle(title)
+	for _, candidateYear := range []int{year, year - 1, year + 1} {
+		if movieIDs := matcher.movieIDs[titleKey(name, candidateYear)]; len(movieIDs) > 0 {
+			return movieIDs[0], true
+		}
+	}
+	return 0, false
+}
+
+func titleKey(name string, year int) string {
+	return name + "|" + strconv.Itoa(year)
+}
@@ -0,0 +1,109 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	model "recommender/models"
+	"sync"
+)
+
+/*
+Append-only log of the mutations of a dataset, with one JSON encoded mutation per line. Every append is synced
+to disk before it returns, so that an acknowledged mutation survives a crash.
+*/
+type MutationLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+/*
+Opens the log at $filePath for appending, creating it if it doesn't exist. A truncated last line, eg. of a crash
+while appending, is cut off so that the next mutation starts on its own line.
+*/
+func OpenMutationLog(filePath string) (*MutationLog, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		if err := os.Truncate(filePath, int64(bytes.LastIndexByte(content, '\n')+1)); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &MutationLog{file: file}, nil
+}
+
+// Appends $mutation to the log and syncs it to disk
+func (mutationLog *MutationLog) Append(mutation model.Mutation) error {
+	line, err := json.Marshal(mutation)
+	if err != nil {
+		return err
+	}
+	mutationLog.mu.Lock()
+	defer mutationLog.mu.Unlock()
+	if _, err := mutationLog.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return mutationLog.file.Sync()
+}
+
+func (mutationLog *MutationLog) Close() error {
+	return mutationLog.file.Close()
+}
+
+// Reads the mutations of the log at $filePath in order. A missing log has none, and a truncated last line is ignored
+func ReadMutationLog(filePath string) ([]model.Mutation, error) {
+	mutations := make([]model.Mutation, 0)
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return mutations, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// Only complete lines were acknowledged
+			return mutations, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var mutation model.Mutation
+		if err := json.Unmarshal(line, &mutation); err != nil {
+			return nil, fmt.Errorf("line %d of %s: %w", lineNumber, filePath, err)
+		}
+		mutations = append(mutations, mutation)
+	}
+}
+
+/*
+Applies $mutation to the datasets, materializing only the rows it changes:
+  - rate: sets the rating of the user to the movie in both $users and $movies, along with its time in $movies
+  - unrate: removes the rating from both of them, along with the user or movie if it has no ratings left
+  - tag: adds the tags of the user to the movie in $movieTags
+*/
+func ApplyMutation(mutation model.Mutation, users *RatingTable, movies *RatingTable, movieTags *TagTable) {
+	switch mutation.Op {
+	case model.RateMovie:
+		users.SetRating(mutation.UserID, mutation.MovieID, mutation.Rating, 0)
+		movies.SetRating(mutation.MovieID, mutation.UserID, mutation.Rating, mutation.Time)
+	case model.UnrateMovie:
+		users.RemoveRating(mutation.UserID, mutation.MovieID)
+		movies.RemoveRating(mutation.MovieID, mutation.UserID)
+	case model.TagMovie:
+		movieTags.AddTags(mutation.MovieID, mutation.UserID, mutation.Tags)
+	}
+}
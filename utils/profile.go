@@ -0,0 +1,138 @@
+package util
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+Loads the {movieID:rating} ratings of an anonymous user from a CSV file with movieId and rating columns,
+eg. a subset of the MovieLens ratings.csv. Other columns are ignored, and a repeated movie keeps its last rating.
+*/
+func LoadProfile(filePath string) (map[int]float32, error) {
+	file, reader, header, err := openCSVFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	movieIDColumn, ratingColumn := getColumnIndex(header, "movieId"), getColumnIndex(header, "rating")
+	if movieIDColumn == -1 || ratingColumn == -1 {
+		return nil, errors.New("profile must have 'movieId' and 'rating' columns")
+	}
+	// Rows may have fewer columns than the header, eg. without an optional title
+	reader.FieldsPerRecord = -1
+	profile := make(map[int]float32)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return profile, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= max(movieIDColumn, ratingColumn) {
+			return nil, fmt.Errorf("row %d of the profile has %d columns", row, len(record))
+		}
+		movieID, err := strconv.Atoi(record[movieIDColumn])
+		if err != nil {
+			return nil, fmt.Errorf("invalid movieId '%s' in row %d of the profile", record[movieIDColumn], row)
+		}
+		rating, err := strconv.ParseFloat(record[ratingColumn], 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rating '%s' in row %d of the profile", record[ratingColumn], row)
+		}
+		profile[movieID] = float32(rating)
+	}
+}
+
+// Writes $profile to $filePath in the format of LoadProfile, sorted by movieId and with the title of every movie for reference
+func WriteProfile(filePath string, profile map[int]float32, movieTitles *TitleTable) error {
+	movieIDs := make([]int, 0, len(profile))
+	for movieID := range profile {
+		movieIDs = append(movieIDs, movieID)
+	}
+	slices.Sort(movieIDs)
+	return ReplaceFile(filePath, func(tempPath string) error {
+		file, err := os.Create(tempPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer := csv.NewWriter(file)
+		writer.Write([]string{"movieId", "rating", "title"})
+		for _, movieID := range movieIDs {
+			writer.Write([]string{strconv.Itoa(movieID), strconv.FormatFloat(float64(profile[movieID]), 'g', -1, 32), movieTitles.Title(movieID)})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		return file.Sync()
+	})
+}
+
+// Writes $ids to $filePath in the format of LoadIDList, one ID per line after a '#' line with the $comment
+func WriteIDList(filePath string, ids []int, comment string) error {
+	return ReplaceFile(filePath, func(tempPath string) error {
+		content := []byte("# " + comment + "\n")
+		for _, id := range ids {
+			content = strconv.AppendInt(content, int64(id), 10)
+			content = append(content, '\n')
+		}
+		return os.WriteFile(tempPath, content, 0644)
+	})
+}
+
+/*
+Loads a list of movie IDs from a text file with one ID per line, where lines starting with '#' are ignored, or from a
+CSV file with a movieId column, eg. a profile or a MovieLens movies.csv
+*/
+func LoadIDList(filePath string) ([]int, error) {
+	file, reader, header, err := openCSVFile(filePath)
+	if err == io.EOF {
+		return []int{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	ids := make([]int, 0)
+	movieIDColumn := getColumnIndex(header, "movieId")
+	if movieIDColumn == -1 {
+		// Plain list, whose first line is an ID too
+		movieIDColumn = 0
+		if firstLine := strings.TrimSpace(header[0]); !strings.HasPrefix(firstLine, "#") {
+			id, err := strconv.Atoi(firstLine)
+			if err != nil {
+				return nil, fmt.Errorf("invalid movie ID '%s' in line 1", firstLine)
+			}
+			ids = append(ids, id)
+		}
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) <= movieIDColumn {
+			return nil, fmt.Errorf("line %d has %d columns", line, len(record))
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(record[movieIDColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid movie ID '%s' in line %d", record[movieIDColumn], line)
+		}
+		ids = append(ids, id)
+	}
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"recommender/algorithms"
+	model "recommender/models"
+	"slices"
+)
+
+// Returns the rating scale of every rating of $users and $movies, or false if the ratings don't fit in 256 steps
+func DetectRatingScale(users *RatingTable, movies *RatingTable) (model.RatingScale, bool) {
+	// Only the distinct ratings matter, so there is no need to collect all of them
+	seen := make(map[float32]bool)
+	for _, table := range []*RatingTable{users, movies} {
+		for _, id := range table.IDs() {
+			vector := table.Vector(id)
+			for i := range vector.Keys {
+				seen[float32(vector.Value(i))] = true
+			}
+		}
+	}
+	ratings := make([]float32, 0, len(seen))
+	for rating := range seen {
+		ratings = append(ratings, rating)
+	}
+	return algorithms.DetectRatingScale(ratings)
+}
+
+// Loads the users or movies of a quantized snapshot written by preprocess -quantize into $table and returns its rating scale
+func LoadQuantizedRatings(table *RatingTable, filePath string, maxRecords ...int) model.RatingScale {
+	var quantized model.QuantizedRatings
+	LoadData(&quantized, filePath)
+	*table = NewQuantizedTable(quantized, maxRecords...)
+	return quantized.Scale
+}
+
+// Returns the table of the $maxRecords entities of $quantized with the lowest IDs (all of them by default), keeping their ratings as steps
+func NewQuantizedTable(quantized model.QuantizedRatings, maxRecords ...int) RatingTable {
+	ids := sortedIDs(quantized.Entities)
+	ids = ids[:recordCount(len(ids), recordLimit(maxRecords))]
+	nnz, hasTimes := 0, false
+	for _, id := range ids {
+		nnz += len(quantized.Entities[id].Keys)
+		hasTimes = hasTimes || len(quantized.Entities[id].Times) > 0
+	}
+	section := ratingSection{ids: ids, offsets: make([]int, 0, len(ids)+1), keys: make([]int, 0, nnz), steps: make([]uint8, 0, nnz), scale: quantized.Scale}
+	if hasTimes {
+		section.times = make([]int64, nnz)
+	}
+	for _, id := range ids {
+		entity := quantized.Entities[id]
+		section.offsets = append(section.offsets, len(section.keys))
+		if len(entity.Times) > 0 {
+			copy(section.times[len(section.keys):], entity.Times)
+		}
+		for _, key := range entity.Keys {
+			section.keys = append(section.keys, int(key))
+		}
+		section.steps = append(section.steps, entity.Steps...)
+	}
+	section.offsets = append(section.offsets, len(section.keys))
+	return newRatingTable(section)
+}
+
+func QuantizeUsers(users map[int]model.User, scale model.RatingScale) model.QuantizedRatings {
+	quantized := model.QuantizedRatings{Scale: scale, Entities: make(map[int]model.QuantizedEntity, len(users))}
+	for userID, user := range users {
+		quantized.Entities[userID] = quantizeEntity(user.MovieRatings, nil, scale)
+	}
+	return quantized
+}
+
+func QuantizeMovies(movies map[int]model.Movie, scale model.RatingScale) model.QuantizedRatings {
+	quantized := model.QuantizedRatings{Scale: scale, Entities: make(map[int]model.QuantizedEntity, len(movies))}
+	for movieID, movie := range movies {
+		quantized.Entities[movieID] = quantizeEntity(movie.UserRatings, movie.RatingTimes, scale)
+	}
+	return quantized
+}
+
+// Returns a copy of the table storing every rating as its step in $scale, which must fit the ratings (see DetectRatingScale)
+func (table *RatingTable) Quantize(scale model.RatingScale) RatingTable {
+	nnz := table.TotalRatings()
+	section := ratingSection{ids: table.ids, offsets: make([]int, 0, len(table.ids)+1), keys: make([]int, 0, nnz), steps: make([]uint8, 0, nnz), scale: scale}
+	if table.columns.times != nil {
+		section.times = make([]int64, 0, nnz)
+	}
+	for _, id := range table.ids {
+		section.offsets = append(section.offsets, len(section.keys))
+		vector := table.Vector(id)
+		section.keys = append(section.keys, vector.Keys...)
+		for i := range vector.Keys {
+			section.steps = append(section.steps, scale.Encode(float32(vector.Value(i))))
+		}
+		if section.times != nil {
+			if times := table.Times(id); times != nil {
+				section.times = append(section.times, times...)
+			} else {
+				section.times = append(section.times, make([]int64, vector.Len())...)
+			}
+		}
+	}
+	section.offsets = append(section.offsets, len(section.keys))
+	return newRatingTable(section)
+}
+
+// Returns the sorted keys of $ratings with their steps in $scale and their $times, if any
+func quantizeEntity(ratings map[int]float32, times map[int]int64, scale model.RatingScale) model.QuantizedEntity {
+	keys := make([]int, 0, len(ratings))
+	for key := range ratings {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	entity := model.QuantizedEntity{Keys: make([]int32, len(keys)), Steps: make([]uint8, len(keys))}
+	if len(times) > 0 {
+		entity.Times = make([]int64, len(keys))
+	}
+	for i, key := range keys {
+		entity.Keys[i] = int32(key)
+		entity.Steps[i] = scale.Encode(ratings[key])
+		if entity.Times != nil {
+			entity.Times[i] = times[key]
+		}
+	}
+	return entity
+}
+
+// Returns the optional max number of records, or -1 for all of them
+func recordLimit(maxRecords []int) int {
+	if len(maxRecords) > 0 {
+		return maxRecords[0]
+	}
+	return -1
+}
@@ -0,0 +1,284 @@
+package util
+
+import (
+	"recommender/algorithms"
+	model "recommender/models"
+	"slices"
+)
+
+/*
+Read-only table of the ratings of every user (keyed by movieID) or of every movie (keyed by userID). Rows are served
+from columns sorted by ID (see ratingSection), eg. in place from a Snapshot, and only the rows changed through
+SetRating and RemoveRating are materialized as maps. Ratings of quantized tables stay uint8 steps of their scale,
+which their vectors decode on access. The zero value is an empty table.
+*/
+type RatingTable struct {
+	tableRows[ratingRow]
+	columns ratingSection
+	// Sum and squared norm of the ratings of every row of the columns, so that their vectors don't recompute them
+	stats []algorithms.VectorStats
+}
+
+// Scale of implicit feedback, whose only rating is 1.0
+var implicitScale = model.RatingScale{Min: 1, Step: 1, Levels: 1}
+
+// Changed row of a RatingTable, with its ratings sorted by key and the times of its keys (0 if none)
+type ratingRow struct {
+	ratings     map[int]float32
+	times       map[int]int64
+	vector      algorithms.SparseVector[int, float32]
+	sortedTimes []int64
+}
+
+// Returns the table of the ratings of $users
+func NewUserTable(users map[int]model.User) RatingTable {
+	return newRatingTable(newRatingSection(sortedIDs(users), func(id int) (map[int]float32, map[int]int64) {
+		return users[id].MovieRatings, nil
+	}, false))
+}
+
+// Returns the table of the ratings of $movies, along with their times
+func NewMovieTable(movies map[int]model.Movie) RatingTable {
+	hasTimes := false
+	for _, movie := range movies {
+		hasTimes = hasTimes || len(movie.RatingTimes) > 0
+	}
+	return newRatingTable(newRatingSection(sortedIDs(movies), func(id int) (map[int]float32, map[int]int64) {
+		return movies[id].UserRatings, movies[id].RatingTimes
+	}, hasTimes))
+}
+
+func newRatingTable(columns ratingSection) RatingTable {
+	table := RatingTable{tableRows: newTableRows[ratingRow](columns.ids), columns: columns, stats: make([]algorithms.VectorStats, len(columns.ids))}
+	for position := range columns.ids {
+		start, end := columns.offsets[position], columns.offsets[position+1]
+		if columns.steps != nil {
+			table.stats[position] = algorithms.NewSortedQuantizedVector(columns.keys[start:end], columns.steps[start:end], columns.scale).Stats()
+		} else {
+			table.stats[position] = algorithms.NewSortedSparseVector(columns.keys[start:end], columns.ratings[start:end]).Stats()
+		}
+	}
+	return table
+}
+
+// Returns the columns of the ratings and optional times of the entities of the sorted $ids
+func newRatingSection(ids []int, entity func(int) (map[int]float32, map[int]int64), hasTimes bool) ratingSection {
+	nnz := 0
+	for _, id := range ids {
+		ratings, _ := entity(id)
+		nnz += len(ratings)
+	}
+	section := ratingSection{ids: ids, offsets: make([]int, 0, len(ids)+1), keys: make([]int, 0, nnz), ratings: make([]float32, 0, nnz)}
+	if hasTimes {
+		section.times = make([]int64, 0, nnz)
+	}
+	for _, id := range ids {
+		section.offsets = append(section.offsets, len(section.keys))
+		ratings, times := entity(id)
+		for _, key := range sortedIDs(ratings) {
+			section.keys = append(section.keys, key)
+			section.ratings = append(section.ratings, ratings[key])
+			if hasTimes {
+				section.times = append(section.times, times[key])
+			}
+		}
+	}
+	section.offsets = append(section.offsets, len(section.keys))
+	return section
+}
+
+// Returns the ratings of the row $id sorted by key, which point into the table and must not be modified. Empty if there is no row $id.
+func (table *RatingTable) Vector(id int) algorithms.SparseVector[int, float32] {
+	position, changed, ok := table.find(id)
+	if !ok {
+		return algorithms.SparseVector[int, float32]{}
+	}
+	if changed != nil {
+		return changed.vector
+	}
+	start, end := table.columns.offsets[position], table.columns.offsets[position+1]
+	if table.columns.steps != nil {
+		return algorithms.NewQuantizedVectorWithStats(table.columns.keys[start:end:end], table.columns.steps[start:end:end], table.columns.scale, table.stats[position])
+	}
+	return algorithms.NewSparseVectorWithStats(table.columns.keys[start:end:end], table.columns.ratings[start:end:end], table.stats[position])
+}
+
+// Returns the Unix times of the ratings of the row $id in the order of its Vector, 0 for ratings without a time, or nil if the table has no times
+func (table *RatingTable) Times(id int) []int64 {
+	position, changed, ok := table.find(id)
+	if !ok {
+		return nil
+	}
+	if changed != nil {
+		return changed.sortedTimes
+	}
+	if table.columns.times == nil {
+		return nil
+	}
+	start, end := table.columns.offsets[position], table.columns.offsets[position+1]
+	return table.columns.times[start:end:end]
+}
+
+// Returns the rating of the row $id to $key, or false if there is none
+func (table *RatingTable) Rating(id int, key int) (float32, bool) {
+	vector := table.Vector(id)
+	if i, found := slices.BinarySearch(vector.Keys, key); found {
+		return float32(vector.Value(i)), true
+	}
+	return 0, false
+}
+
+// Returns the Unix time of the rating of the row $id to $key, or 0 if there is none
+func (table *RatingTable) Time(id int, key int) int64 {
+	if i, found := slices.BinarySearch(table.Vector(id).Keys, key); found {
+		if times := table.Times(id); times != nil {
+			return times[i]
+		}
+	}
+	return 0
+}
+
+// Returns the number of ratings of the row $id
+func (table *RatingTable) Count(id int) int {
+	return table.Vector(id).Len()
+}
+
+// Returns a {key:rating} copy of the ratings of the row $id
+func (table *RatingTable) Ratings(id int) map[int]float32 {
+	vector := table.Vector(id)
+	ratings := make(map[int]float32, vector.Len())
+	for i, key := range vector.Keys {
+		ratings[key] = float32(vector.Value(i))
+	}
+	return ratings
+}
+
+// Returns the number of ratings of every row
+func (table *RatingTable) TotalRatings() int {
+	if len(table.ids) == 0 {
+		return 0
+	}
+	if table.storedPrefix() {
+		return table.columns.offsets[len(table.ids)]
+	}
+	total := 0
+	for _, id := range table.ids {
+		total += table.Count(id)
+	}
+	return total
+}
+
+// Returns a view of the $maxRecords rows with the lowest IDs, or of every row if $maxRecords is -1
+func (table *RatingTable) Limit(maxRecords int) RatingTable {
+	view := *table
+	view.tableRows = table.tableRows.limit(maxRecords)
+	return view
+}
+
+// Returns a view of the rows among $ids
+func (table *RatingTable) Subset(ids []int) RatingTable {
+	view := *table
+	view.tableRows = table.tableRows.restrict(ids)
+	return view
+}
+
+/*
+Returns a table where every positive interaction is stored as a 1.0 rating and every other rating is dropped, along
+with the rows left without ratings. Ratings are stored as the single step of implicitScale.
+*/
+func (table *RatingTable) ToImplicit(threshold float64) RatingTable {
+	section := ratingSection{ids: make([]int, 0, table.Len()), offsets: make([]int, 0, table.Len()+1), keys: make([]int, 0), steps: make([]uint8, 0), scale: implicitScale}
+	for _, id := range table.ids {
+		vector := table.Vector(id)
+		start := len(section.keys)
+		for i, key := range vector.Keys {
+			if IsPositiveInteraction(float32(vector.Value(i)), threshold) {
+				section.keys = append(section.keys, key)
+				section.steps = append(section.steps, 0)
+			}
+		}
+		if len(section.keys) > start {
+			section.ids = append(section.ids, id)
+			section.offsets = append(section.offsets, start)
+		}
+	}
+	section.offsets = append(section.offsets, len(section.keys))
+	return newRatingTable(section)
+}
+
+// Sets the rating of the row $id to $key, along with its Unix $time unless it's 0, adding the row if needed
+func (table *RatingTable) SetRating(id int, key int, rating float32, time int64) {
+	row := table.changedRow(id)
+	row.ratings[key] = rating
+	if time != 0 {
+		row.times[key] = time
+	}
+	table.setRow(id, row)
+}
+
+// Removes the rating of the row $id to $key, along with the row if it has no ratings left
+func (table *RatingTable) RemoveRating(id int, key int) {
+	if !table.Has(id) {
+		return
+	}
+	row := table.changedRow(id)
+	delete(row.ratings, key)
+	delete(row.times, key)
+	table.setRow(id, row)
+}
+
+// Returns a copy of the row $id to be changed, materialized from the columns if it didn't change yet
+func (table *RatingTable) changedRow(id int) *ratingRow {
+	row := &ratingRow{ratings: table.Ratings(id), times: make(map[int]int64)}
+	for i, time := range table.Times(id) {
+		if time != 0 {
+			row.times[table.Vector(id).Keys[i]] = time
+		}
+	}
+	return row
+}
+
+// Replaces the row $id with $row, or removes it if $row has no ratings
+func (table *RatingTable) setRow(id int, row *ratingRow) {
+	if len(row.ratings) == 0 {
+		table.set(id, nil)
+		return
+	}
+	if table.columns.steps != nil {
+		row.vector = algorithms.NewQuantizedVector(row.ratings, table.columns.scale)
+	} else {
+		row.vector = algorithms.NewSparseVector(row.ratings)
+	}
+	if len(row.times) > 0 || table.columns.times != nil {
+		row.sortedTimes = make([]int64, len(row.vector.Keys))
+		for i, key := range row.vector.Keys {
+			row.sortedTimes[i] = row.times[key]
+		}
+	}
+	table.set(id, row)
+}
+
+// Returns the rows as users, eg. to write them to a gob file
+func (table *RatingTable) ToUsers() map[int]model.User {
+	users := make(map[int]model.User, table.Len())
+	for _, id := range table.ids {
+		users[id] = model.User{MovieRatings: table.Ratings(id)}
+	}
+	return users
+}
+
+// Returns the rows as movies with the times of their ratings, eg. to write them to a gob file
+func (table *RatingTable) ToMovies() map[int]model.Movie {
+	movies := make(map[int]model.Movie, table.Len())
+	for _, id := range table.ids {
+		movie := model.Movie{UserRatings: table.Ratings(id), RatingTimes: make(map[int]int64)}
+		keys := table.Vector(id).Keys
+		for i, time := range table.Times(id) {
+			if time != 0 {
+				movie.RatingTimes[keys[i]] = time
+			}
+		}
+		movies[id] = movie
+	}
+	return movies
+}
@@ -0,0 +1,479 @@
+package util
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	model "recommender/models"
+	"slices"
+	"strings"
+	"unsafe"
+)
+
+/*
+Read-only snapshot of the users, movies, titles, tags and genres of a dataset, written by preprocess to be mapped
+in memory (see OpenSnapshot) instead of decoded. Every number is little-endian and 8 bytes wide except
+ratings (float32), and every array starts at a multiple of 8 bytes:
+  - header:  "MRSNAP02", then the offset and length of the users, movies, titles, tags and genres sections
+  - ratings: n, nnz, hasTimes, ids[n], offsets[n+1], keys[nnz], times[nnz] (if hasTimes), ratings[nnz]
+    where the keys (movieIDs of a user or userIDs of a movie) of ids[i] are keys[offsets[i]:offsets[i+1]]
+  - titles:  n, size, ids[n], offsets[n+1], bytes[size] where the title of ids[i] is bytes[offsets[i]:offsets[i+1]]
+  - tags:    n, users, tags, size, ids[n], userOffsets[n+1], userIDs[users], tagOffsets[users+1], stringOffsets[tags+1], bytes[size]
+  - genres:  same as titles, with the same ids and the genres of ids[i] joined by '|'
+*/
+type Snapshot struct {
+	users  ratingSection
+	movies ratingSection
+	titles stringSection
+	tags   tagSection
+	genres stringSection
+	unmap  func() error
+}
+
+// Sorted entity IDs with the sorted keys, ratings and optional times of every entity
+type ratingSection struct {
+	ids     []int
+	offsets []int
+	keys    []int
+	times   []int64
+	ratings []float32
+	// Ratings of quantized sections, as steps of their scale, in place of the float ratings
+	steps []uint8
+	scale model.RatingScale
+}
+
+type stringSection struct {
+	ids     []int
+	offsets []int
+	bytes   []byte
+}
+
+// Returns the string of the entity at position $i
+func (section stringSection) value(i int) string {
+	return string(section.bytes[section.offsets[i]:section.offsets[i+1]])
+}
+
+type tagSection struct {
+	ids           []int
+	userOffsets   []int
+	userIDs       []int
+	tagOffsets    []int
+	stringOffsets []int
+	bytes         []byte
+}
+
+// Returns the tag at position $t
+func (section tagSection) tag(t int) string {
+	return string(section.bytes[section.stringOffsets[t]:section.stringOffsets[t+1]])
+}
+
+const snapshotMagic = "MRSNAP02"
+
+// Number of sections of a snapshot: users, movies, titles, tags, genres
+const snapshotSections = 5
+
+// Whether int is 64-bit little-endian, so that snapshot arrays can be used in place instead of being copied
+var nativeSnapshotLayout = unsafe.Sizeof(int(0)) == 8 && binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+/*
+Maps the snapshot at $filePath in memory, read-only and shared with every other process mapping it.
+The tables returned by the snapshot, eg. Users, point into the mapping, which is
+never unmapped while the snapshot is in use. Platforms without mmap read the whole file instead.
+*/
+func OpenSnapshot(filePath string) (*Snapshot, error) {
+	data, unmap, err := mapFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := parseSnapshot(data)
+	if err != nil {
+		unmap()
+		return nil, fmt.Errorf("invalid snapshot %s: %w", filePath, err)
+	}
+	snapshot.unmap = unmap
+	return snapshot, nil
+}
+
+// Unmaps the snapshot. Nothing returned by the snapshot may be used afterwards
+func (snapshot *Snapshot) Close() error {
+	return snapshot.unmap()
+}
+
+// Returns the table of the users with the $maxRecords lowest IDs, or all of them if $maxRecords is -1, served from the snapshot
+func (snapshot *Snapshot) Users(maxRecords int) RatingTable {
+	table := newRatingTable(snapshot.users)
+	return table.Limit(maxRecords)
+}
+
+// Returns the table of the movies with the $maxRecords lowest IDs, or all of them if $maxRecords is -1, served from the snapshot
+func (snapshot *Snapshot) Movies(maxRecords int) RatingTable {
+	table := newRatingTable(snapshot.movies)
+	return table.Limit(maxRecords)
+}
+
+// Returns the table of the titles of the movies with the $maxRecords lowest IDs, or all of them if $maxRecords is -1, served from the snapshot
+func (snapshot *Snapshot) MovieTitles(maxRecords int) TitleTable {
+	table := newTitleTable(snapshot.titles, snapshot.genres)
+	return table.Limit(maxRecords)
+}
+
+// Returns the table of the tags of the movies with the $maxRecords lowest IDs, or all of them if $maxRecords is -1, served from the snapshot
+func (snapshot *Snapshot) MovieTags(maxRecords int) TagTable {
+	table := newTagTable(snapshot.tags)
+	return table.Limit(maxRecords)
+}
+
+// Returns the number of records to read out of $total, ie. $maxRecords unless it is -1 or greater than $total
+func recordCount(total int, maxRecords int) int {
+	if maxRecords == -1 || maxRecords > total {
+		return total
+	}
+	return maxRecords
+}
+
+/*
+Writes the snapshot of the datasets to $filePath and syncs it to disk. An existing file is truncated, which crashes the
+processes that mapped it, so replace it with ReplaceFile instead.
+*/
+func WriteSnapshot(filePath string, users map[int]model.User, movies map[int]model.Movie, movieTitles map[int]model.MovieTitle, movieTags map[int]model.MovieTags) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := &snapshotWriter{writer: bufio.NewWriter(file)}
+	writer.write([]byte(snapshotMagic))
+	// Section table, filled in once the sections are written
+	writer.write(make([]byte, snapshotSections*2*8))
+	table := make([]uint64, 0, snapshotSections*2)
+	sections := []func(){
+		func() {
+			writer.writeRatings(sortedIDs(users), func(id int) (map[int]float32, map[int]int64) { return users[id].MovieRatings, nil }, false)
+		},
+		func() {
+			hasTimes := false
+			for _, movie := range movies {
+				hasTimes = hasTimes || len(movie.RatingTimes) > 0
+			}
+			writer.writeRatings(sortedIDs(movies), func(id int) (map[int]float32, map[int]int64) { return movies[id].UserRatings, movies[id].RatingTimes }, hasTimes)
+		},
+		func() {
+			writer.writeStrings(sortedIDs(movieTitles), func(id int) string { return movieTitles[id].Title })
+		},
+		func() { writer.writeTags(movieTags) },
+		func() {
+			writer.writeStrings(sortedIDs(movieTitles), func(id int) string { return strings.Join(movieTitles[id].Genres, "|") })
+		},
+	}
+	for _, writeSection := range sections {
+		start := writer.offset
+		writeSection()
+		writer.pad()
+		table = append(table, uint64(start), uint64(writer.offset-start))
+	}
+	if writer.err != nil {
+		return writer.err
+	}
+	if err := writer.writer.Flush(); err != nil {
+		return err
+	}
+	header := make([]byte, 0, len(table)*8)
+	for _, value := range table {
+		header = binary.LittleEndian.AppendUint64(header, value)
+	}
+	if _, err := file.WriteAt(header, int64(len(snapshotMagic))); err != nil {
+		return err
+	}
+	// The snapshot must be complete on disk before ReplaceFile renames it over the mapped one
+	return file.Sync()
+}
+
+// Buffered writer that keeps track of its offset and of the first error
+type snapshotWriter struct {
+	writer *bufio.Writer
+	offset int
+	err    error
+}
+
+func (writer *snapshotWriter) write(bytes []byte) {
+	if writer.err != nil {
+		return
+	}
+	_, writer.err = writer.writer.Write(bytes)
+	writer.offset += len(bytes)
+}
+
+func (writer *snapshotWriter) ints(values ...int) {
+	buffer := make([]byte, 0, 8)
+	for _, value := range values {
+		writer.write(binary.LittleEndian.AppendUint64(buffer[:0], uint64(value)))
+	}
+}
+
+// Pads the output with zeros up to the next multiple of 8 bytes
+func (writer *snapshotWriter) pad() {
+	writer.write(make([]byte, (8-writer.offset%8)%8))
+}
+
+func (writer *snapshotWriter) writeRatings(ids []int, entity func(int) (map[int]float32, map[int]int64), hasTimes bool) {
+	offsets, nnz := make([]int, 0, len(ids)+1), 0
+	for _, id := range ids {
+		offsets = append(offsets, nnz)
+		ratings, _ := entity(id)
+		nnz += len(ratings)
+	}
+	offsets = append(offsets, nnz)
+	timesFlag := 0
+	if hasTimes {
+		timesFlag = 1
+	}
+	writer.ints(len(ids), nnz, timesFlag)
+	writer.ints(ids...)
+	writer.ints(offsets...)
+	// Keys, times and ratings of every entity in the order of its sorted keys
+	sortedKeys := func(id int) []int {
+		ratings, _ := entity(id)
+		return sortedIDs(ratings)
+	}
+	for _, id := range ids {
+		writer.ints(sortedKeys(id)...)
+	}
+	if hasTimes {
+		for _, id := range ids {
+			_, times := entity(id)
+			for _, key := range sortedKeys(id) {
+				writer.ints(int(times[key]))
+			}
+		}
+	}
+	buffer := make([]byte, 0, 4)
+	for _, id := range ids {
+		ratings, _ := entity(id)
+		for _, key := range sortedKeys(id) {
+			writer.write(binary.LittleEndian.AppendUint32(buffer[:0], math.Float32bits(ratings[key])))
+		}
+	}
+}
+
+// Writes the $value string of every one of $ids
+func (writer *snapshotWriter) writeStrings(ids []int, value func(int) string) {
+	offsets, size := make([]int, 0, len(ids)+1), 0
+	for _, id := range ids {
+		offsets = append(offsets, size)
+		size += len(value(id))
+	}
+	offsets = append(offsets, size)
+	writer.ints(len(ids), size)
+	writer.ints(ids...)
+	writer.ints(offsets...)
+	for _, id := range ids {
+		writer.write([]byte(value(id)))
+	}
+}
+
+func (writer *snapshotWriter) writeTags(movieTags map[int]model.MovieTags) {
+	ids := sortedIDs(movieTags)
+	userOffsets, userIDs, tagOffsets, stringOffsets := []int{0}, []int{}, []int{0}, []int{0}
+	strings := make([]string, 0)
+	size := 0
+	for _, id := range ids {
+		tagUserIDs := sortedIDs(movieTags[id].UserTags)
+		userIDs = append(userIDs, tagUserIDs...)
+		userOffsets = append(userOffsets, len(userIDs))
+		for _, userID := range tagUserIDs {
+			for _, tag := range movieTags[id].UserTags[userID].Tags {
+				strings = append(strings, tag)
+				size += len(tag)
+				stringOffsets = append(stringOffsets, size)
+			}
+			tagOffsets = append(tagOffsets, len(strings))
+		}
+	}
+	writer.ints(len(ids), len(userIDs), len(strings), size)
+	writer.ints(ids...)
+	writer.ints(userOffsets...)
+	writer.ints(userIDs...)
+	writer.ints(tagOffsets...)
+	writer.ints(stringOffsets...)
+	for _, tag := range strings {
+		writer.write([]byte(tag))
+	}
+}
+
+// Returns the keys of $entities sorted in ascending order
+func sortedIDs[V any](entities map[int]V) []int {
+	ids := make([]int, 0, len(entities))
+	for id := range entities {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func parseSnapshot(data []byte) (*Snapshot, error) {
+	if len(data) < len(snapshotMagic)+snapshotSections*2*8 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("unknown format")
+	}
+	sections := make([]*snapshotReader, snapshotSections)
+	for i := range sections {
+		position := len(snapshotMagic) + i*2*8
+		offset := binary.LittleEndian.Uint64(data[position:])
+		length := binary.LittleEndian.Uint64(data[position+8:])
+		if offset%8 != 0 || offset > uint64(len(data)) || length > uint64(len(data))-offset {
+			return nil, errors.New("section out of bounds")
+		}
+		sections[i] = &snapshotReader{data: data[offset : offset+length]}
+	}
+	snapshot := &Snapshot{}
+	snapshot.users = sections[0].readRatings()
+	snapshot.movies = sections[1].readRatings()
+	snapshot.titles = sections[2].readStrings()
+	snapshot.tags = sections[3].readTags()
+	snapshot.genres = sections[4].readStrings()
+	for _, section := range sections {
+		if section.err != nil {
+			return nil, section.err
+		}
+	}
+	if !slices.Equal(snapshot.genres.ids, snapshot.titles.ids) {
+		return nil, errors.New("genres don't match the titles")
+	}
+	return snapshot, nil
+}
+
+// Reader of a snapshot section that keeps track of its offset and of the first error
+type snapshotReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// Returns the next $size bytes, or nil if the section is too short
+func (reader *snapshotReader) next(size int) []byte {
+	if reader.err != nil {
+		return nil
+	}
+	if size < 0 || size > len(reader.data)-reader.offset {
+		reader.err = errors.New("section too short")
+		return nil
+	}
+	bytes := reader.data[reader.offset : reader.offset+size]
+	reader.offset += size
+	return bytes
+}
+
+// Returns the next $count ints, in place if the layout is native
+func (reader *snapshotReader) ints(count int) []int {
+	return readWords[int](reader, count)
+}
+
+// Returns the next $count int64 values, in place if the layout is native
+func (reader *snapshotReader) int64s(count int) []int64 {
+	return readWords[int64](reader, count)
+}
+
+// Returns the next $count 8-byte integers, in place if the layout is native
+func readWords[T int | int64](reader *snapshotReader, count int) []T {
+	if count < 0 || count > len(reader.data)/8 {
+		reader.err = errors.New("invalid array length")
+		return nil
+	}
+	bytes := reader.next(count * 8)
+	if bytes == nil || count == 0 {
+		return []T{}
+	}
+	if nativeSnapshotLayout {
+		return unsafe.Slice((*T)(unsafe.Pointer(&bytes[0])), count)
+	}
+	values := make([]T, count)
+	for i := range values {
+		values[i] = T(binary.LittleEndian.Uint64(bytes[i*8:]))
+	}
+	return values
+}
+
+// Returns the next $count float32 values, in place if the layout is native
+func (reader *snapshotReader) float32s(count int) []float32 {
+	if count < 0 || count > len(reader.data)/4 {
+		reader.err = errors.New("invalid array length")
+		return nil
+	}
+	bytes := reader.next(count * 4)
+	if bytes == nil || count == 0 {
+		return []float32{}
+	}
+	if nativeSnapshotLayout {
+		return unsafe.Slice((*float32)(unsafe.Pointer(&bytes[0])), count)
+	}
+	values := make([]float32, count)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[i*4:]))
+	}
+	return values
+}
+
+func (reader *snapshotReader) readRatings() ratingSection {
+	header := reader.ints(3)
+	if reader.err != nil {
+		return ratingSection{}
+	}
+	n, nnz, hasTimes := header[0], header[1], header[2] == 1
+	section := ratingSection{ids: reader.ints(n), offsets: reader.ints(n + 1), keys: reader.ints(nnz)}
+	if hasTimes {
+		section.times = reader.int64s(nnz)
+	}
+	section.ratings = reader.float32s(nnz)
+	reader.checkOffsets(section.offsets, nnz)
+	return section
+}
+
+func (reader *snapshotReader) readStrings() stringSection {
+	header := reader.ints(2)
+	if reader.err != nil {
+		return stringSection{}
+	}
+	n, size := header[0], header[1]
+	section := stringSection{ids: reader.ints(n), offsets: reader.ints(n + 1), bytes: reader.next(size)}
+	reader.checkOffsets(section.offsets, size)
+	return section
+}
+
+func (reader *snapshotReader) readTags() tagSection {
+	header := reader.ints(4)
+	if reader.err != nil {
+		return tagSection{}
+	}
+	n, users, tags, size := header[0], header[1], header[2], header[3]
+	section := tagSection{
+		ids:           reader.ints(n),
+		userOffsets:   reader.ints(n + 1),
+		userIDs:       reader.ints(users),
+		tagOffsets:    reader.ints(users + 1),
+		stringOffsets: reader.ints(tags + 1),
+		bytes:         reader.next(size),
+	}
+	reader.checkOffsets(section.userOffsets, users)
+	reader.checkOffsets(section.tagOffsets, tags)
+	reader.checkOffsets(section.stringOffsets, size)
+	return section
+}
+
+// Checks that $offsets start at 0, never decrease and end at $total, so that they can't point out of their array
+func (reader *snapshotReader) checkOffsets(offsets []int, total int) {
+	if reader.err != nil {
+		return
+	}
+	if len(offsets) == 0 || offsets[0] != 0 || offsets[len(offsets)-1] != total {
+		reader.err = errors.New("invalid offsets")
+		return
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] < offsets[i-1] {
+			reader.err = errors.New("invalid offsets")
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package util
+
+import "slices"
+
+/*
+Rows of a read-only table whose rows are stored in columns sorted by ID, eg. in place in a Snapshot, except for the
+rows changed since, which are the only ones materialized as $R values. Views of a table (see limit and restrict)
+share its columns and changed rows, so the table must not change while its views are in use.
+*/
+type tableRows[R any] struct {
+	// Sorted IDs of the rows stored in the columns
+	stored []int
+	// Sorted IDs of every row of the table or view
+	ids []int
+	// Rows changed since the columns were written, keyed by ID, where nil is a removed row
+	changed map[int]*R
+}
+
+func newTableRows[R any](stored []int) tableRows[R] {
+	return tableRows[R]{stored: stored, ids: stored}
+}
+
+// Returns the number of rows
+func (rows *tableRows[R]) Len() int {
+	return len(rows.ids)
+}
+
+// Returns the sorted IDs of the rows, which must not be modified
+func (rows *tableRows[R]) IDs() []int {
+	return rows.ids
+}
+
+// Returns whether there is a row $id
+func (rows *tableRows[R]) Has(id int) bool {
+	_, found := slices.BinarySearch(rows.ids, id)
+	return found
+}
+
+// Returns the position of the row $id in the columns, or its changed row if it changed. $ok is false if there is no row $id.
+func (rows *tableRows[R]) find(id int) (position int, changed *R, ok bool) {
+	if !rows.Has(id) {
+		return -1, nil, false
+	}
+	if row, exists := rows.changed[id]; exists {
+		return -1, row, true
+	}
+	position, _ = slices.BinarySearch(rows.stored, id)
+	return position, nil, true
+}
+
+// Sets the changed row $id, or removes the row if $row is nil. The IDs are copied rather than modified, since views may share them.
+func (rows *tableRows[R]) set(id int, row *R) {
+	if rows.changed == nil {
+		rows.changed = make(map[int]*R)
+	}
+	rows.changed[id] = row
+	rows.ids = updatePosting(rows.ids, id, row != nil)
+}
+
+// Returns whether the rows are the ones of the columns up to the last one, ie. there is no changed row and at most a limit
+func (rows *tableRows[R]) storedPrefix() bool {
+	// A sorted subset of the stored IDs whose last ID is the n-th stored one is made of the n first ones
+	return len(rows.changed) == 0 && (len(rows.ids) == 0 || rows.ids[len(rows.ids)-1] == rows.stored[len(rows.ids)-1])
+}
+
+// Returns a view of the $maxRecords rows with the lowest IDs, or of every row if $maxRecords is -1
+func (rows tableRows[R]) limit(maxRecords int) tableRows[R] {
+	rows.ids = slices.Clip(rows.ids[:recordCount(len(rows.ids), maxRecords)])
+	return rows
+}
+
+// Returns a view of the rows among $ids
+func (rows tableRows[R]) restrict(ids []int) tableRows[R] {
+	selected := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if rows.Has(id) {
+			selected = append(selected, id)
+		}
+	}
+	slices.Sort(selected)
+	rows.ids = slices.Compact(selected)
+	return rows
+}
@@ -0,0 +1,126 @@
+package util
+
+import (
+	model "recommender/models"
+	"slices"
+)
+
+/*
+Read-only table of the tags every user gave to every movie, served from columns sorted by ID (see tagSection), eg.
+in place from a Snapshot. Only the movies changed through AddTags are materialized as maps. The zero value is an
+empty table.
+*/
+type TagTable struct {
+	tableRows[model.MovieTags]
+	columns tagSection
+}
+
+// Returns the table of $movieTags
+func NewTagTable(movieTags map[int]model.MovieTags) TagTable {
+	return newTagTable(newTagSection(movieTags))
+}
+
+func newTagTable(columns tagSection) TagTable {
+	return TagTable{tableRows: newTableRows[model.MovieTags](columns.ids), columns: columns}
+}
+
+// Returns the columns of $movieTags, with the users of every movie and their tags in ascending order of user ID
+func newTagSection(movieTags map[int]model.MovieTags) tagSection {
+	section := tagSection{ids: sortedIDs(movieTags), userOffsets: []int{0}, userIDs: []int{}, tagOffsets: []int{0}, stringOffsets: []int{0}}
+	for _, id := range section.ids {
+		for _, userID := range sortedIDs(movieTags[id].UserTags) {
+			for _, tag := range movieTags[id].UserTags[userID].Tags {
+				section.bytes = append(section.bytes, tag...)
+				section.stringOffsets = append(section.stringOffsets, len(section.bytes))
+			}
+			section.userIDs = append(section.userIDs, userID)
+			section.tagOffsets = append(section.tagOffsets, len(section.stringOffsets)-1)
+		}
+		section.userOffsets = append(section.userOffsets, len(section.userIDs))
+	}
+	return section
+}
+
+// Returns the tags of the movie $id, which must not be modified, or false if there are none
+func (table *TagTable) Get(id int) (model.MovieTags, bool) {
+	position, changed, ok := table.find(id)
+	if !ok {
+		return model.MovieTags{}, false
+	}
+	if changed != nil {
+		return *changed, true
+	}
+	section := table.columns
+	tags := model.MovieTags{UserTags: make(map[int]model.UserTags, section.userOffsets[position+1]-section.userOffsets[position])}
+	for u := section.userOffsets[position]; u < section.userOffsets[position+1]; u++ {
+		userTags := model.UserTags{Tags: make([]string, 0, section.tagOffsets[u+1]-section.tagOffsets[u])}
+		for t := section.tagOffsets[u]; t < section.tagOffsets[u+1]; t++ {
+			userTags.Tags = append(userTags.Tags, section.tag(t))
+		}
+		tags.UserTags[section.userIDs[u]] = userTags
+	}
+	return tags, true
+}
+
+// Returns the number of times every tag was given to the movie $id
+func (table *TagTable) Occurrences(id int) map[string]int {
+	position, changed, ok := table.find(id)
+	if !ok {
+		return map[string]int{}
+	}
+	if changed != nil {
+		return CountTagOccurrences(*changed)
+	}
+	section := table.columns
+	tagCounts := make(map[string]int)
+	for t := section.tagOffsets[section.userOffsets[position]]; t < section.tagOffsets[section.userOffsets[position+1]]; t++ {
+		tagCounts[section.tag(t)]++
+	}
+	return tagCounts
+}
+
+// Returns the number of tags of every movie, counting every time a tag was given
+func (table *TagTable) TotalTags() int {
+	if len(table.ids) == 0 {
+		return 0
+	}
+	if table.storedPrefix() {
+		return table.columns.tagOffsets[table.columns.userOffsets[len(table.ids)]]
+	}
+	total := 0
+	for _, id := range table.ids {
+		tags, _ := table.Get(id)
+		for _, userTags := range tags.UserTags {
+			total += len(userTags.Tags)
+		}
+	}
+	return total
+}
+
+// Returns a view of the $maxRecords movies with the lowest IDs, or of every movie if $maxRecords is -1
+func (table *TagTable) Limit(maxRecords int) TagTable {
+	view := *table
+	view.tableRows = table.tableRows.limit(maxRecords)
+	return view
+}
+
+// Adds $tags to the ones $userID gave to the movie $movieID, adding the movie if needed
+func (table *TagTable) AddTags(movieID int, userID int, tags []string) {
+	previous, _ := table.Get(movieID)
+	// Copy the tags of the movie, which may be shared with views of the table
+	changed := model.MovieTags{UserTags: make(map[int]model.UserTags, len(previous.UserTags)+1)}
+	for previousUserID, userTags := range previous.UserTags {
+		changed.UserTags[previousUserID] = userTags
+	}
+	changed.UserTags[userID] = model.UserTags{Tags: append(slices.Clip(previous.UserTags[userID].Tags), tags...)}
+	table.set(movieID, &changed)
+}
+
+// Returns the rows as movie tags, eg. to write them to a gob file
+func (table *TagTable) ToMovieTags() map[int]model.MovieTags {
+	movieTags := make(map[int]model.MovieTags, table.Len())
+	for _, id := range table.ids {
+		movieTags[id], _ = table.Get(id)
+	}
+	return movieTags
+}
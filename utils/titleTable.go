@@ -0,0 +1,88 @@
+package util
+
+import (
+	model "recommender/models"
+	"strings"
+)
+
+/*
+Read-only table of the titles and genres of every movie, served from columns sorted by ID (see stringSection), eg.
+in place from a Snapshot. Titles never change, so no row is materialized. The zero value is an empty table.
+*/
+type TitleTable struct {
+	tableRows[model.MovieTitle]
+	titles stringSection
+	// Genres of the movies of the titles, joined by '|'
+	genres stringSection
+}
+
+// Returns the table of $movieTitles
+func NewTitleTable(movieTitles map[int]model.MovieTitle) TitleTable {
+	ids := sortedIDs(movieTitles)
+	return newTitleTable(
+		newStringSection(ids, func(id int) string { return movieTitles[id].Title }),
+		newStringSection(ids, func(id int) string { return strings.Join(movieTitles[id].Genres, "|") }),
+	)
+}
+
+func newTitleTable(titles stringSection, genres stringSection) TitleTable {
+	return TitleTable{tableRows: newTableRows[model.MovieTitle](titles.ids), titles: titles, genres: genres}
+}
+
+// Returns the column of the $value string of every one of the sorted $ids
+func newStringSection(ids []int, value func(int) string) stringSection {
+	section := stringSection{ids: ids, offsets: make([]int, 0, len(ids)+1)}
+	var bytes strings.Builder
+	for _, id := range ids {
+		section.offsets = append(section.offsets, bytes.Len())
+		bytes.WriteString(value(id))
+	}
+	section.offsets = append(section.offsets, bytes.Len())
+	section.bytes = []byte(bytes.String())
+	return section
+}
+
+// Returns the title and genres of the movie $id, or false if there is none
+func (table *TitleTable) Get(id int) (model.MovieTitle, bool) {
+	position, _, ok := table.find(id)
+	if !ok {
+		return model.MovieTitle{}, false
+	}
+	movieTitle := model.MovieTitle{Title: table.titles.value(position)}
+	if genres := table.genres.value(position); genres != "" {
+		movieTitle.Genres = strings.Split(genres, "|")
+	}
+	return movieTitle, true
+}
+
+// Returns the title of the movie $id, or an empty string if there is none
+func (table *TitleTable) Title(id int) string {
+	position, _, ok := table.find(id)
+	if !ok {
+		return ""
+	}
+	return table.titles.value(position)
+}
+
+// Returns a view of the $maxRecords movies with the lowest IDs, or of every movie if $maxRecords is -1
+func (table *TitleTable) Limit(maxRecords int) TitleTable {
+	view := *table
+	view.tableRows = table.tableRows.limit(maxRecords)
+	return view
+}
+
+// Returns a view of the movies among $ids
+func (table *TitleTable) Subset(ids []int) TitleTable {
+	view := *table
+	view.tableRows = table.tableRows.restrict(ids)
+	return view
+}
+
+// Returns the rows as movie titles, eg. to write them to a snapshot
+func (table *TitleTable) ToMovieTitles() map[int]model.MovieTitle {
+	movieTitles := make(map[int]model.MovieTitle, table.Len())
+	for _, id := range table.ids {
+		movieTitles[id], _ = table.Get(id)
+	}
+	return movieTitles
+}
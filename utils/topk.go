@@ -0,0 +1,118 @@
+package util
+
+import "sort"
+
+/*
+Bounded heap that keeps the $k best items pushed into it, where $better(a, b) reports whether
+a ranks before b. $better must be a strict total order (eg. break score ties by ID) for results
+to be reproducible. A negative $k keeps every item.
+*/
+type TopK[T any] struct {
+	k      int
+	better func(a, b T) bool
+	// Min-heap on $better, so that the worst kept item is always at the root
+	items []T
+}
+
+func NewTopK[T any](k int, better func(a, b T) bool) *TopK[T] {
+	capacity := k
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &TopK[T]{k: k, better: better, items: make([]T, 0, capacity)}
+}
+
+func (topK *TopK[T]) Len() int {
+	return len(topK.items)
+}
+
+// Adds $item if fewer than k items are kept or if it ranks before the worst kept item, which is then dropped
+func (topK *TopK[T]) Push(item T) {
+	worse := func(a, b T) bool { return topK.better(b, a) }
+	if topK.k < 0 || len(topK.items) < topK.k {
+		topK.items = append(topK.items, item)
+		siftUp(topK.items, len(topK.items)-1, worse)
+		return
+	}
+	if topK.k == 0 || !topK.better(item, topK.items[0]) {
+		return
+	}
+	topK.items[0] = item
+	siftDown(topK.items, 0, worse)
+}
+
+// Returns the kept items from best to worst
+func (topK *TopK[T]) Sorted() []T {
+	sorted := make([]T, len(topK.items))
+	copy(sorted, topK.items)
+	sort.Slice(sorted, func(i, j int) bool {
+		return topK.better(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
+// Merges $lists, each sorted from best to worst, and returns their $k best items from best to worst
+func MergeTopK[T any](k int, better func(a, b T) bool, lists ...[]T) []T {
+	total := 0
+	for _, list := range lists {
+		total += len(list)
+	}
+	if k < 0 || k > total {
+		k = total
+	}
+	// Heap of the current head of every non-empty list, with the best head at the root
+	type cursor struct {
+		list     int
+		position int
+	}
+	head := func(c cursor) T { return lists[c.list][c.position] }
+	bestFirst := func(a, b cursor) bool { return better(head(a), head(b)) }
+	cursors := make([]cursor, 0, len(lists))
+	for i, list := range lists {
+		if len(list) > 0 {
+			cursors = append(cursors, cursor{list: i})
+			siftUp(cursors, len(cursors)-1, bestFirst)
+		}
+	}
+	merged := make([]T, 0, k)
+	for len(merged) < k && len(cursors) > 0 {
+		merged = append(merged, head(cursors[0]))
+		cursors[0].position++
+		if cursors[0].position == len(lists[cursors[0].list]) {
+			cursors[0] = cursors[len(cursors)-1]
+			cursors = cursors[:len(cursors)-1]
+		}
+		siftDown(cursors, 0, bestFirst)
+	}
+	return merged
+}
+
+// Restores the heap order of $items, whose root is the first item on $less, after $items[i] was appended
+func siftUp[T any](items []T, i int, less func(a, b T) bool) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !less(items[i], items[parent]) {
+			return
+		}
+		items[i], items[parent] = items[parent], items[i]
+		i = parent
+	}
+}
+
+// Restores the heap order of $items, whose root is the first item on $less, after $items[i] was replaced
+func siftDown[T any](items []T, i int, less func(a, b T) bool) {
+	for {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(items) && less(items[left], items[smallest]) {
+			smallest = left
+		}
+		if right < len(items) && less(items[right], items[smallest]) {
+			smallest = right
+		}
+		if smallest == i {
+			return
+		}
+		items[i], items[smallest] = items[smallest], items[i]
+		i = smallest
+	}
+}
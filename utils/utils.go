@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"recommender/algorithms"
 	model "recommender/models"
 	"runtime/pprof"
 	"strings"
@@ -140,6 +141,34 @@ func CreateBoolVectors[T comparable](set1 []T, set2 []T) (vectorA []bool, vector
 	return vectorA, vectorB
 }
 
+// Returns true if $rating counts as a positive interaction in implicit feedback mode.
+// A $threshold <= 0 means that any existing rating counts as a positive interaction.
+func IsPositiveInteraction(rating float32, threshold float64) bool {
+	return threshold <= 0 || float64(rating) >= threshold
+}
+
+// Returns the positive interactions of the sorted $vector as 1.0 ratings, stored like the ones of RatingTable.ToImplicit
+func ToImplicitVector(vector algorithms.SparseVector[int, float32], threshold float64) algorithms.SparseVector[int, float32] {
+	keys := make([]int, 0, vector.Len())
+	for i, key := range vector.Keys {
+		if IsPositiveInteraction(float32(vector.Value(i)), threshold) {
+			keys = append(keys, key)
+		}
+	}
+	return algorithms.NewSortedQuantizedVector(keys, make([]uint8, len(keys)), implicitScale)
+}
+
+// Returns a copy of $ratings where every positive interaction is stored as a 1.0 rating and every other rating is dropped
+func ToImplicitRatings(ratings map[int]float32, threshold float64) map[int]float32 {
+	implicitRatings := make(map[int]float32)
+	for movieID, rating := range ratings {
+		if IsPositiveInteraction(rating, threshold) {
+			implicitRatings[movieID] = 1.0
+		}
+	}
+	return implicitRatings
+}
+
 func StartProfiling(fileName string) {
 	profilingDir := os.Getenv("PWD") + "/profiling/"
 	err := os.MkdirAll(profilingDir, 0755)
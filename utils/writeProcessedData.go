@@ -0,0 +1,34 @@
+package util
+
+import (
+	"encoding/gob"
+	"os"
+)
+
+// Stores $data into $filePath using Go Binary format, replacing the file only once it is completely written
+func WriteGOB(data interface{}, filePath string) error {
+	return ReplaceFile(filePath, func(tempPath string) error {
+		file, err := os.Create(tempPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := gob.NewEncoder(file).Encode(data); err != nil {
+			return err
+		}
+		return file.Sync()
+	})
+}
+
+/*
+Lets $write create a temporary file next to $filePath, then renames it to $filePath. Readers of $filePath, eg. the
+ones that mapped a snapshot in memory, keep seeing the old file, and a failed write leaves it untouched.
+*/
+func ReplaceFile(filePath string, write func(tempPath string) error) error {
+	tempPath := filePath + ".tmp"
+	if err := write(tempPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, filePath)
+}